@@ -0,0 +1,97 @@
+package charts
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// supersample is how many times larger charts are drawn before they are scaled down, smoothing edges.
+const supersample = 2
+
+// canvas draws shapes in chart coordinates on a supersampled image.
+type canvas struct {
+	img *image.RGBA
+}
+
+func newCanvas(width, height int) *canvas {
+	img := image.NewRGBA(image.Rect(0, 0, width*supersample, height*supersample))
+	c := &canvas{img: img}
+	c.fillRect(0, 0, float64(width), float64(height), Background)
+	return c
+}
+
+// fillRect fills the rectangle with the top left corner at x, y.
+func (c *canvas) fillRect(x, y, w, h float64, col color.RGBA) {
+	r := image.Rect(scaled(x), scaled(y), scaled(x+w), scaled(y+h)).Intersect(c.img.Bounds())
+	for py := r.Min.Y; py < r.Max.Y; py++ {
+		for px := r.Min.X; px < r.Max.X; px++ {
+			c.img.SetRGBA(px, py, col)
+		}
+	}
+}
+
+// fillCircle fills a circle centered at cx, cy.
+func (c *canvas) fillCircle(cx, cy, radius float64, col color.RGBA) {
+	r := image.Rect(scaled(cx-radius), scaled(cy-radius), scaled(cx+radius)+1, scaled(cy+radius)+1).Intersect(c.img.Bounds())
+	rs := radius * supersample
+	for py := r.Min.Y; py < r.Max.Y; py++ {
+		for px := r.Min.X; px < r.Max.X; px++ {
+			dx, dy := float64(px)-cx*supersample, float64(py)-cy*supersample
+			if dx*dx+dy*dy <= rs*rs {
+				c.img.SetRGBA(px, py, col)
+			}
+		}
+	}
+}
+
+// line draws a segment of the given width with rounded ends.
+func (c *canvas) line(x0, y0, x1, y1, width float64, col color.RGBA) {
+	steps := int(math.Max(math.Abs(x1-x0), math.Abs(y1-y0))*supersample) + 1
+	for i := 0; i <= steps; i++ {
+		t := float64(i) / float64(steps)
+		c.fillCircle(x0+(x1-x0)*t, y0+(y1-y0)*t, width/2, col)
+	}
+}
+
+// text draws the text with its top left corner at x, y, each font pixel being size points wide.
+func (c *canvas) text(x, y float64, text string, size float64, col color.RGBA) {
+	for _, r := range text {
+		glyph, ok := glyphs[r]
+		if !ok {
+			glyph = glyphs['?']
+		}
+		for row, line := range glyph {
+			for column, pixel := range line {
+				if pixel == '#' {
+					c.fillRect(x+float64(column)*size, y+float64(row)*size, size, size, col)
+				}
+			}
+		}
+		x += textAdvance * size
+	}
+}
+
+// image returns the chart scaled down to its actual size.
+func (c *canvas) image() *image.RGBA {
+	b := c.img.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, b.Dx()/supersample, b.Dy()/supersample))
+	for y := 0; y < out.Rect.Dy(); y++ {
+		for x := 0; x < out.Rect.Dx(); x++ {
+			var r, g, bl, a int
+			for sy := 0; sy < supersample; sy++ {
+				for sx := 0; sx < supersample; sx++ {
+					p := c.img.RGBAAt(x*supersample+sx, y*supersample+sy)
+					r, g, bl, a = r+int(p.R), g+int(p.G), bl+int(p.B), a+int(p.A)
+				}
+			}
+			n := supersample * supersample
+			out.SetRGBA(x, y, color.RGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(bl / n), A: uint8(a / n)})
+		}
+	}
+	return out
+}
+
+func scaled(v float64) int {
+	return int(math.Round(v * supersample))
+}
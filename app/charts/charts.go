@@ -0,0 +1,246 @@
+// Package charts renders spending aggregates as PNG images in pure Go.
+package charts
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"strings"
+)
+
+// Point is a single value of a trend chart, labeled on the horizontal axis.
+type Point struct {
+	Label string // Digits only, e.g. a day of month
+	Value float64
+}
+
+// Palette colors chart series. Each color matches the square emoji of Markers with the same index,
+// so captions can explain charts in any language.
+var Palette = []color.RGBA{
+	{R: 221, G: 46, B: 68, A: 255},
+	{R: 244, G: 144, B: 12, A: 255},
+	{R: 253, G: 203, B: 88, A: 255},
+	{R: 120, G: 177, B: 89, A: 255},
+	{R: 85, G: 172, B: 238, A: 255},
+	{R: 170, G: 142, B: 214, A: 255},
+	{R: 193, G: 105, B: 79, A: 255},
+	{R: 49, G: 55, B: 61, A: 255},
+}
+
+// Markers are the square emoji matching Palette colors.
+var Markers = []string{"🟥", "🟧", "🟨", "🟩", "🟦", "🟪", "🟫", "⬛"}
+
+// Chart colors besides the palette.
+var (
+	Background = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	gridColor  = color.RGBA{R: 225, G: 229, B: 234, A: 255}
+	axisColor  = color.RGBA{R: 120, G: 128, B: 138, A: 255}
+	emptyColor = color.RGBA{R: 235, G: 238, B: 241, A: 255}
+	trendColor = Palette[4]
+)
+
+// Chart sizes in pixels.
+const (
+	pieSize     = 600
+	trendWidth  = 800
+	trendHeight = 480
+	labelSize   = 3 // font pixel size of axis labels
+)
+
+// Pie renders a donut chart of the values' shares, colored by Palette in order,
+// starting at the top and going clockwise. Values beyond the palette reuse its colors.
+func Pie(values []float64) ([]byte, error) {
+	c := newCanvas(pieSize, pieSize)
+	center, outer, inner := float64(pieSize)/2, float64(pieSize)/2-20, float64(pieSize)/5
+
+	var total float64
+	for _, v := range values {
+		total += math.Max(v, 0)
+	}
+
+	if total == 0 {
+		c.fillCircle(center, center, outer, emptyColor)
+		c.fillCircle(center, center, inner, Background)
+		return encode(c.image())
+	}
+
+	// boundaries of the slices as fractions of the full circle
+	bounds := make([]float64, len(values))
+	var sum float64
+	for i, v := range values {
+		sum += math.Max(v, 0)
+		bounds[i] = sum / total
+	}
+
+	b := c.img.Bounds()
+	cs, os, is := center*supersample, outer*supersample, inner*supersample
+	for py := b.Min.Y; py < b.Max.Y; py++ {
+		for px := b.Min.X; px < b.Max.X; px++ {
+			dx, dy := float64(px)-cs, float64(py)-cs
+			if d := dx*dx + dy*dy; d > os*os || d < is*is {
+				continue
+			}
+
+			// angle from the top, clockwise, as a fraction of the full circle
+			angle := math.Atan2(dx, -dy) / (2 * math.Pi)
+			if angle < 0 {
+				angle++
+			}
+			for i, bound := range bounds {
+				if angle <= bound {
+					c.img.SetRGBA(px, py, Palette[i%len(Palette)])
+					break
+				}
+			}
+		}
+	}
+
+	return encode(c.image())
+}
+
+// Bars renders the points as a bar chart.
+func Bars(points []Point) ([]byte, error) {
+	c, plot := trendCanvas(points)
+	if len(points) == 0 {
+		return encode(c.image())
+	}
+
+	slot := plot.w / float64(len(points))
+	for i, p := range points {
+		h := plot.height(p.Value)
+		c.fillRect(plot.x+float64(i)*slot+slot*0.15, plot.y+plot.h-h, slot*0.7, h, trendColor)
+	}
+
+	return encode(c.image())
+}
+
+// Line renders the points as a line chart.
+func Line(points []Point) ([]byte, error) {
+	c, plot := trendCanvas(points)
+	if len(points) == 0 {
+		return encode(c.image())
+	}
+
+	slot := plot.w / float64(len(points))
+	x := func(i int) float64 { return plot.x + float64(i)*slot + slot/2 }
+	y := func(i int) float64 { return plot.y + plot.h - plot.height(points[i].Value) }
+
+	for i := 1; i < len(points); i++ {
+		c.line(x(i-1), y(i-1), x(i), y(i), 4, trendColor)
+	}
+	for i := range points {
+		c.fillCircle(x(i), y(i), 6, trendColor)
+		c.fillCircle(x(i), y(i), 3, Background)
+	}
+
+	return encode(c.image())
+}
+
+// plotArea is the part of a trend chart inside the axes.
+type plotArea struct {
+	x, y, w, h float64
+	max        float64 // value at the top of the plot
+}
+
+// height returns the height of a value within the plot.
+func (p plotArea) height(v float64) float64 {
+	if p.max <= 0 || v <= 0 {
+		return 0
+	}
+	return p.h * v / p.max
+}
+
+// trendCanvas draws the grid, value labels and point labels shared by trend charts.
+func trendCanvas(points []Point) (*canvas, plotArea) {
+	c := newCanvas(trendWidth, trendHeight)
+
+	var maxValue float64
+	for _, p := range points {
+		maxValue = math.Max(maxValue, p.Value)
+	}
+
+	const gridLines = 4
+	step := niceStep(maxValue / gridLines)
+	plot := plotArea{x: 20, y: 20, w: trendWidth - 40, h: trendHeight - 60, max: step * gridLines}
+
+	// leave room for the widest value label on the left
+	var labelWidth float64
+	for i := 0; i <= gridLines; i++ {
+		labelWidth = math.Max(labelWidth, textWidth(compact(step*float64(i)), labelSize))
+	}
+	plot.x += labelWidth + 10
+	plot.w -= labelWidth + 10
+
+	for i := 0; i <= gridLines; i++ {
+		y := plot.y + plot.h - plot.h*float64(i)/gridLines
+		c.fillRect(plot.x, y-1, plot.w, 2, gridColor)
+
+		label := compact(step * float64(i))
+		c.text(plot.x-10-textWidth(label, labelSize), y-labelSize*2.5, label, labelSize, axisColor)
+	}
+	c.fillRect(plot.x, plot.y+plot.h-1, plot.w, 2, axisColor)
+
+	if len(points) == 0 {
+		return c, plot
+	}
+
+	// label every point while they fit, otherwise every n-th one
+	slot := plot.w / float64(len(points))
+	every := 1
+	for _, p := range points {
+		for textWidth(p.Label, labelSize)+8 > slot*float64(every) {
+			every++
+		}
+	}
+	for i, p := range points {
+		if i%every != 0 {
+			continue
+		}
+		x := plot.x + float64(i)*slot + slot/2 - textWidth(p.Label, labelSize)/2
+		c.text(x, plot.y+plot.h+12, p.Label, labelSize, axisColor)
+	}
+
+	return c, plot
+}
+
+// niceStep rounds a grid step up to 1, 2 or 5 times a power of ten.
+func niceStep(v float64) float64 {
+	if v <= 0 {
+		return 1
+	}
+
+	magnitude := math.Pow(10, math.Floor(math.Log10(v)))
+	for _, m := range []float64{1, 2, 5, 10} {
+		if v <= m*magnitude {
+			return m * magnitude
+		}
+	}
+	return 10 * magnitude
+}
+
+// compact formats a value for an axis label, e.g. 1500 as 1.5k.
+func compact(v float64) string {
+	format := func(v float64, suffix string) string {
+		return strings.TrimSuffix(strings.TrimSuffix(fmt.Sprintf("%.1f", v), "0"), ".") + suffix
+	}
+
+	switch {
+	case v >= 1e6:
+		return format(v/1e6, "M")
+	case v >= 1e3:
+		return format(v/1e3, "k")
+	default:
+		return format(v, "")
+	}
+}
+
+func encode(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode chart: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,34 @@
+package charts
+
+// textAdvance is the width of a glyph including the spacing after it, in font pixels.
+const textAdvance = 4
+
+// glyphs is a tiny 3x5 pixel font, enough for axis labels. Category names and other
+// text go into message captions, so charts don't need to render any language.
+var glyphs = map[rune][5]string{
+	'0': {"###", "#.#", "#.#", "#.#", "###"},
+	'1': {".#.", "##.", ".#.", ".#.", "###"},
+	'2': {"###", "..#", "###", "#..", "###"},
+	'3': {"###", "..#", "###", "..#", "###"},
+	'4': {"#.#", "#.#", "###", "..#", "..#"},
+	'5': {"###", "#..", "###", "..#", "###"},
+	'6': {"###", "#..", "###", "#.#", "###"},
+	'7': {"###", "..#", "..#", "..#", "..#"},
+	'8': {"###", "#.#", "###", "#.#", "###"},
+	'9': {"###", "#.#", "###", "..#", "###"},
+	'.': {"...", "...", "...", "...", ".#."},
+	'-': {"...", "...", "###", "...", "..."},
+	'k': {"#..", "#.#", "##.", "#.#", "#.#"},
+	'M': {"#.#", "###", "###", "#.#", "#.#"},
+	' ': {"...", "...", "...", "...", "..."},
+	'?': {"###", "..#", ".##", "...", ".#."},
+}
+
+// textWidth returns the width of the text drawn with the given font pixel size.
+func textWidth(text string, size float64) float64 {
+	n := len([]rune(text))
+	if n == 0 {
+		return 0
+	}
+	return (float64(n)*textAdvance - 1) * size
+}
@@ -0,0 +1,275 @@
+package events
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/nyanyamaga/finance-tracker-bot/app/i18n"
+	"github.com/nyanyamaga/finance-tracker-bot/app/storage"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Access modes, who may use the bot
+const (
+	AccessOpen      = "open"      // anyone who finds the bot
+	AccessAllowlist = "allowlist" // configured and approved users, others ask admins for access
+	AccessInvite    = "invite"    // configured and approved users and whoever has an invite link
+)
+
+// AccessModes lists the supported access modes.
+var AccessModes = []string{AccessOpen, AccessAllowlist, AccessInvite}
+
+// accessPayloadPrefix marks /start deep-link payloads carrying an access invite code.
+const accessPayloadPrefix = "access_"
+
+// accessInviteTTL is how long an access invite code stays valid.
+const accessInviteTTL = 7 * 24 * time.Hour
+
+// ErrUserNotFound is returned when a user to approve or revoke is named by an unknown username.
+var ErrUserNotFound = errors.New("user not found")
+
+// BotAccessManager decides who may use the bot. Admins always may, revoked users never may, and otherwise
+// it's up to the mode: everyone in the open mode, or users allowed by the configuration, approved by admins
+// or who came with an invite link.
+type BotAccessManager struct {
+	TbAPI            TbAPI
+	Access           AccessRepository
+	Admins           AdminManager
+	StateManager     StateManager
+	Mode             string
+	AllowedIDs       []int64
+	AllowedUsernames []string // Without @
+	BotUsername      string   // Used to build invite links
+}
+
+// Allow reports whether the update may be handled, answering users who may not why.
+// In the allowlist mode unknown users are recorded as pending and admins are asked to approve them.
+func (am *BotAccessManager) Allow(update tbapi.Update) bool {
+	from := update.SentFrom()
+	if from == nil || am.Admins.IsAdmin(from.ID) {
+		return true
+	}
+
+	access, err := am.Access.GetAccess(from.ID)
+	if err != nil {
+		log.Printf("[error] error checking access of user %d: %v", from.ID, err)
+		return am.Mode == AccessOpen
+	}
+	if access != nil && access.Status == storage.AccessRevoked {
+		am.refuse(update, "access.revoked")
+		return false
+	}
+	if am.Mode == AccessOpen || access != nil && access.Status == storage.AccessApproved || am.allowlisted(from) {
+		return true
+	}
+
+	if am.Mode == AccessInvite {
+		if code, ok := accessCode(update); ok {
+			err = am.Access.UseInvite(code, accessOf(from), time.Now())
+			if err == nil {
+				return true // the /start command greets the new user
+			}
+			if !errors.Is(err, storage.ErrInviteNotFound) {
+				log.Printf("[error] error redeeming access invite of user %d: %v", from.ID, err)
+			}
+		}
+		am.refuse(update, "access.invite_required")
+		return false
+	}
+
+	if !am.addressed(update) {
+		return false // group chatter of unknown users is no request for access
+	}
+	if access == nil {
+		am.request(from)
+	}
+	am.refuse(update, "access.pending")
+	return false
+}
+
+// List shows the users who asked for access or were approved or revoked, pending requests first.
+func (am *BotAccessManager) List(lang string) (string, error) {
+	users, err := am.Access.ListAccess()
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "*%s* · %s\n\n", i18n.Text(lang, "access.title"), i18n.Text(lang, "access.mode_"+am.Mode))
+	if len(users) == 0 {
+		sb.WriteString(i18n.Text(lang, "access.empty") + "\n")
+	}
+	for _, u := range users {
+		fmt.Fprintf(&sb, "%s %s\n", i18n.Text(lang, "access.status_"+u.Status), accessLabel(u))
+	}
+	sb.WriteString("\n" + i18n.Text(lang, "access.usage"))
+	return sb.String(), nil
+}
+
+// Approve lets the user named by an ID or a @username use the bot and lets them know.
+func (am *BotAccessManager) Approve(who string) (*storage.AccessInfo, error) {
+	access, err := am.setStatus(who, storage.AccessApproved)
+	if err != nil {
+		return nil, err
+	}
+
+	text := i18n.Text(am.language(*access), "access.approved")
+	if err = send(tbapi.NewMessage(access.UserID, text), am.TbAPI); err != nil {
+		log.Printf("[warn] error notifying user %d of approved access: %v", access.UserID, err)
+	}
+	return access, nil
+}
+
+// Revoke stops the user named by an ID or a @username from using the bot, even if the configuration allows them.
+func (am *BotAccessManager) Revoke(who string) (*storage.AccessInfo, error) {
+	return am.setStatus(who, storage.AccessRevoked)
+}
+
+// CreateInvite creates a one-time access invite and returns its link.
+func (am *BotAccessManager) CreateInvite(adminID int64) (string, error) {
+	code := make([]byte, 12)
+	if _, err := rand.Read(code); err != nil {
+		return "", fmt.Errorf("failed to generate access invite code: %w", err)
+	}
+
+	invite := storage.AccessInviteInfo{
+		Code:      base64.RawURLEncoding.EncodeToString(code),
+		CreatedBy: adminID,
+		ExpiresAt: time.Now().Add(accessInviteTTL),
+	}
+	if err := am.Access.CreateInvite(invite); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("https://t.me/%s?start=%s%s", am.BotUsername, accessPayloadPrefix, invite.Code), nil
+}
+
+// setStatus sets the access status of the user named by an ID or a @username.
+// Users named by an ID may be approved before they ever talk to the bot.
+func (am *BotAccessManager) setStatus(who, status string) (*storage.AccessInfo, error) {
+	who = strings.TrimSpace(who)
+	access := &storage.AccessInfo{}
+	if userID, err := strconv.ParseInt(who, 10, 64); err == nil {
+		found, err := am.Access.GetAccess(userID)
+		if err != nil {
+			return nil, err
+		}
+		access.UserID = userID
+		if found != nil {
+			access = found
+		}
+	} else {
+		found, err := am.Access.FindAccess(strings.TrimPrefix(who, "@"))
+		if err != nil {
+			return nil, err
+		}
+		if found == nil {
+			return nil, fmt.Errorf("no user %q: %w", who, ErrUserNotFound)
+		}
+		access = found
+	}
+
+	access.Status = status
+	if err := am.Access.SetStatus(*access); err != nil {
+		return nil, err
+	}
+	return access, nil
+}
+
+// allowlisted reports whether the configuration allows the user by ID or username.
+func (am *BotAccessManager) allowlisted(user *tbapi.User) bool {
+	for _, id := range am.AllowedIDs {
+		if id == user.ID {
+			return true
+		}
+	}
+	for _, username := range am.AllowedUsernames {
+		if user.UserName != "" && strings.EqualFold(username, user.UserName) {
+			return true
+		}
+	}
+	return false
+}
+
+// addressed reports whether the user turns to the bot: in a private chat, or with a command for it in a group.
+func (am *BotAccessManager) addressed(update tbapi.Update) bool {
+	switch {
+	case update.Message != nil && update.Message.Chat.IsPrivate():
+		return true
+	case update.Message != nil && update.Message.IsCommand():
+		_, botName, ok := strings.Cut(update.Message.CommandWithAt(), "@")
+		return !ok || strings.EqualFold(botName, am.BotUsername)
+	case update.CallbackQuery != nil:
+		return update.CallbackQuery.Message != nil && update.CallbackQuery.Message.Chat.IsPrivate()
+	}
+	return false
+}
+
+// request records a pending access request and asks admins to approve it.
+func (am *BotAccessManager) request(user *tbapi.User) {
+	access := accessOf(user)
+	added, err := am.Access.RequestAccess(access)
+	if err != nil {
+		log.Printf("[error] error recording access request of user %d: %v", user.ID, err)
+		return
+	}
+	if !added {
+		return
+	}
+
+	log.Printf("[info] user %d asked for access", user.ID)
+	am.Admins.Notify("access.requested", accessLabel(access), user.ID)
+}
+
+// language returns the language to talk to a user about their access in. Users asking for access haven't talked
+// to the bot otherwise, so the language Telegram reported then is preferred to the default one.
+func (am *BotAccessManager) language(access storage.AccessInfo) string {
+	if access.Language != "" {
+		return i18n.Normalize(access.Language)
+	}
+	return am.StateManager.Language(access.UserID)
+}
+
+// refuse tells the user why the bot doesn't answer. Group chatter and inline queries are ignored silently.
+func (am *BotAccessManager) refuse(update tbapi.Update, key string) {
+	lang := i18n.Normalize(update.SentFrom().LanguageCode)
+
+	switch {
+	case update.Message != nil && update.Message.Chat.IsPrivate():
+		if err := send(tbapi.NewMessage(update.Message.Chat.ID, i18n.Text(lang, key)), am.TbAPI); err != nil {
+			log.Printf("[warn] error sending access refusal: %v", err)
+		}
+	case update.CallbackQuery != nil:
+		if _, err := am.TbAPI.Request(tbapi.NewCallback(update.CallbackQuery.ID, i18n.Text(lang, key))); err != nil {
+			log.Printf("[warn] error answering refused callback query: %v", err)
+		}
+	}
+}
+
+// accessCode returns the access invite code of a /start deep link.
+func accessCode(update tbapi.Update) (string, bool) {
+	msg := update.Message
+	if msg == nil || !msg.IsCommand() || msg.Command() != "start" {
+		return "", false
+	}
+	code, ok := strings.CutPrefix(strings.TrimSpace(msg.CommandArguments()), accessPayloadPrefix)
+	return code, ok && code != ""
+}
+
+// accessOf returns the access details of a Telegram user.
+func accessOf(user *tbapi.User) storage.AccessInfo {
+	return storage.AccessInfo{UserID: user.ID, Username: user.UserName, Name: displayName(user), Language: user.LanguageCode}
+}
+
+// accessLabel names a user for admins: the display name, the username and the ID.
+func accessLabel(access storage.AccessInfo) string {
+	label := access.Name
+	if access.Username != "" {
+		label = strings.TrimSpace(label + " @" + access.Username)
+	}
+	return strings.TrimSpace(fmt.Sprintf("%s (%d)", label, access.UserID))
+}
@@ -0,0 +1,275 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/looplab/fsm"
+	"github.com/nyanyamaga/finance-tracker-bot/app/i18n"
+	"github.com/nyanyamaga/finance-tracker-bot/app/keyboards"
+	"github.com/nyanyamaga/finance-tracker-bot/app/storage"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// transferSeparators may stand between the accounts of a transfer, needed when account names have spaces.
+var transferSeparators = []string{"->", "→", ">"}
+
+// ErrInvalidAccount is returned when account details can't be parsed.
+var ErrInvalidAccount = errors.New("invalid account")
+
+// ErrInvalidTransfer is returned when a transfer can't be parsed or names accounts missing in the ledger.
+var ErrInvalidTransfer = errors.New("invalid transfer")
+
+type BotAccountManager struct {
+	Ledgers  LedgerManager
+	Accounts AccountsRepository
+}
+
+// List shows the accounts of the conversation's ledger with their current balances, empty when there are none.
+func (am *BotAccountManager) List(lang string, conv Conversation) (string, error) {
+	accounts, err := am.LedgerAccounts(conv)
+	if err != nil || len(accounts) == 0 {
+		return "", err
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "*%s*\n", i18n.Text(lang, "accounts.title"))
+	var total float64
+	for _, account := range accounts {
+		fmt.Fprintf(&sb, "%s: %.2f\n", keyboards.AccountLabel(account), account.Balance)
+		total += account.Balance
+	}
+	if len(accounts) > 1 {
+		fmt.Fprintf(&sb, "%s: *%.2f*\n", i18n.Text(lang, "accounts.total"), total)
+	}
+	return sb.String(), nil
+}
+
+// LedgerAccounts returns the accounts of the conversation's ledger with their current balances.
+func (am *BotAccountManager) LedgerAccounts(conv Conversation) ([]storage.AccountInfo, error) {
+	member, err := am.Ledgers.LedgerFor(conv)
+	if err != nil {
+		return nil, err
+	}
+	return am.Accounts.ListAccounts(member.LedgerID)
+}
+
+// DefaultAccount returns the account the user paid the latest spending from, or the first account of the ledger.
+// It returns 0 when the ledger has no accounts.
+func (am *BotAccountManager) DefaultAccount(conv Conversation, accounts []storage.AccountInfo) (int64, error) {
+	if len(accounts) == 0 {
+		return 0, nil
+	}
+
+	lastID, err := am.Accounts.LastUsedAccount(accounts[0].LedgerID, conv.UserID)
+	if err != nil {
+		return 0, err
+	}
+	for _, account := range accounts {
+		if account.ID == lastID {
+			return lastID, nil
+		}
+	}
+	return accounts[0].ID, nil
+}
+
+// AddAccount adds an account to the conversation's ledger from details like "Card card 1500",
+// the kind defaults to cash and the opening balance to 0. Adding an existing account updates it.
+func (am *BotAccountManager) AddAccount(conv Conversation, details string) (*storage.AccountInfo, error) {
+	member, err := am.Ledgers.LedgerFor(conv)
+	if err != nil {
+		return nil, err
+	}
+	if !canEdit(member) {
+		return nil, fmt.Errorf("user %d can't add accounts to ledger %d: %w", conv.UserID, member.LedgerID, ErrPermissionDenied)
+	}
+
+	account, err := parseAccount(details)
+	if err != nil {
+		return nil, err
+	}
+	account.LedgerID = member.LedgerID
+	account.CreatedBy = conv.UserID
+
+	accounts, err := am.Accounts.ListAccounts(member.LedgerID)
+	if err != nil {
+		return nil, err
+	}
+	if existing, ok := findAccount(accounts, account.Name); ok {
+		account.Name = existing.Name
+	}
+
+	if err = am.Accounts.AddAccount(account); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// Transfer moves money between accounts of the conversation's ledger from details like "200 Card Cash"
+// or "200 Main card > Cash". Transfers change account balances but aren't spendings.
+func (am *BotAccountManager) Transfer(conv Conversation, details string) (*storage.AccountTransferInfo, error) {
+	member, err := am.Ledgers.LedgerFor(conv)
+	if err != nil {
+		return nil, err
+	}
+	if !canEdit(member) {
+		return nil, fmt.Errorf("user %d can't transfer in ledger %d: %w", conv.UserID, member.LedgerID, ErrPermissionDenied)
+	}
+
+	amount, names, err := parseAmountInput(details)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", err, ErrInvalidTransfer)
+	}
+
+	accounts, err := am.Accounts.ListAccounts(member.LedgerID)
+	if err != nil {
+		return nil, err
+	}
+	from, to, ok := transferAccounts(accounts, names)
+	if !ok || from.ID == to.ID {
+		return nil, fmt.Errorf("no accounts to transfer between in %q: %w", names, ErrInvalidTransfer)
+	}
+
+	transfer := storage.AccountTransferInfo{
+		LedgerID:      member.LedgerID,
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+		Amount:        amount,
+		UserID:        conv.UserID,
+	}
+	if err = am.Accounts.AddTransfer(transfer); err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+// parseAccount parses account details: a name, optionally followed by a kind and an opening balance.
+func parseAccount(details string) (storage.AccountInfo, error) {
+	account := storage.AccountInfo{Kind: storage.AccountCash}
+
+	fields := strings.Fields(details)
+	if len(fields) > 1 {
+		if balance, err := strconv.ParseFloat(strings.ReplaceAll(fields[len(fields)-1], ",", "."), 64); err == nil {
+			account.OpeningBalance = balance
+			fields = fields[:len(fields)-1]
+		}
+	}
+	if len(fields) > 1 {
+		for _, kind := range storage.AccountKinds {
+			if strings.EqualFold(fields[len(fields)-1], kind) {
+				account.Kind = kind
+				fields = fields[:len(fields)-1]
+				break
+			}
+		}
+	}
+
+	account.Name = strings.Join(fields, " ")
+	if account.Name == "" {
+		return account, fmt.Errorf("no account name in %q: %w", details, ErrInvalidAccount)
+	}
+	return account, nil
+}
+
+// transferAccounts finds the source and the destination account named in the text,
+// split by a separator or at the first place both halves name accounts.
+func transferAccounts(accounts []storage.AccountInfo, text string) (from, to storage.AccountInfo, ok bool) {
+	for _, sep := range transferSeparators {
+		if fromName, toName, found := strings.Cut(text, sep); found {
+			from, fromOK := findAccount(accounts, fromName)
+			to, toOK := findAccount(accounts, toName)
+			return from, to, fromOK && toOK
+		}
+	}
+
+	fields := strings.Fields(text)
+	for i := 1; i < len(fields); i++ {
+		from, fromOK := findAccount(accounts, strings.Join(fields[:i], " "))
+		to, toOK := findAccount(accounts, strings.Join(fields[i:], " "))
+		if fromOK && toOK {
+			return from, to, true
+		}
+	}
+	return from, to, false
+}
+
+// findAccount looks up an account by its name, ignoring case.
+func findAccount(accounts []storage.AccountInfo, name string) (storage.AccountInfo, bool) {
+	name = strings.TrimSpace(name)
+	for _, account := range accounts {
+		if strings.EqualFold(account.Name, name) {
+			return account, true
+		}
+	}
+	return storage.AccountInfo{}, false
+}
+
+// promptAccountSelection asks which account the spending was paid from, the last used one checked.
+// Ledgers without accounts record the spending without one, ledgers with a single account skip the step.
+func (sm *BotStateManager) promptAccountSelection(ctx context.Context, conv Conversation) {
+	accounts, err := sm.Accounts.LedgerAccounts(conv)
+	if err != nil {
+		log.Printf("[warn] error fetching accounts: %v", err)
+		return
+	}
+
+	defaultID, err := sm.Accounts.DefaultAccount(conv, accounts)
+	if err != nil {
+		log.Printf("[warn] error fetching default account: %v", err)
+	}
+
+	if len(accounts) < 2 {
+		sm.skipStep(ctx, conv, "AccountSelected", fmt.Sprintf("%s%d", keyboards.AccountPrefix, defaultID))
+		return
+	}
+
+	keyboard := sm.TbKeyboards.GetAccountKeyboard(accounts, defaultID)
+	if err = sm.sendBotResponse(conv, sm.text(conv.UserID, "account.select"), &keyboard); err != nil {
+		log.Printf("[warn] error sending account selection prompt: %v", err)
+	}
+}
+
+// validateAccount cancels the account transition unless an account of the ledger was picked,
+// or no account at all when the ledger has none.
+func (sm *BotStateManager) validateAccount(e *fsm.Event, conv Conversation) {
+	accounts, err := sm.Accounts.LedgerAccounts(conv)
+	if err != nil {
+		e.Cancel(err)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(strings.TrimPrefix(sm.UserValues[conv], keyboards.AccountPrefix), 10, 64)
+	if err == nil && strings.HasPrefix(sm.UserValues[conv], keyboards.AccountPrefix) {
+		if accountID == 0 && len(accounts) == 0 {
+			return
+		}
+		for _, account := range accounts {
+			if account.ID == accountID {
+				return
+			}
+		}
+	}
+
+	e.Cancel(fmt.Errorf("invalid account %q", sm.UserValues[conv]))
+	defaultID, _ := sm.Accounts.DefaultAccount(conv, accounts)
+	keyboard := sm.TbKeyboards.GetAccountKeyboard(accounts, defaultID)
+	if err := sm.sendBotResponse(conv, sm.text(conv.UserID, "account.select"), &keyboard); err != nil {
+		log.Printf("[warn] error sending account selection prompt: %v", err)
+	}
+}
+
+// spendingAccount returns the account the spending is paid from: the picked one,
+// or the default account for quick entries, which skip the account step.
+func (sm *BotStateManager) spendingAccount(conv Conversation, stateData map[string]interface{}) (int64, error) {
+	if value, ok := stateData["AccountSelected"]; ok {
+		return strconv.ParseInt(strings.TrimPrefix(fmt.Sprint(value), keyboards.AccountPrefix), 10, 64)
+	}
+
+	accounts, err := sm.Accounts.LedgerAccounts(conv)
+	if err != nil {
+		return 0, err
+	}
+	return sm.Accounts.DefaultAccount(conv, accounts)
+}
@@ -0,0 +1,163 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/nyanyamaga/finance-tracker-bot/app/i18n"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Admin defaults
+const (
+	adminErrorWindow  = 24 * time.Hour
+	activityPrecision = time.Hour        // how often a user's last seen time is written while they keep using the bot
+	broadcastInterval = time.Second / 20 // stays below the limit of 30 messages per second Telegram allows bots
+)
+
+// ErrorLog passes log output through, remembering when errors were logged
+// so admins can see how many there were recently. Warnings are left out,
+// they're mostly about users, like messages the bot doesn't understand or blocked bots.
+type ErrorLog struct {
+	Out io.Writer
+
+	mu     sync.Mutex
+	logged []time.Time
+}
+
+// Write writes the log output and records a time for every line logged at the error level.
+func (l *ErrorLog) Write(p []byte) (int, error) {
+	if n := bytes.Count(p, []byte("[error]")); n > 0 {
+		now := time.Now()
+		l.mu.Lock()
+		l.logged = l.prune(now)
+		for i := 0; i < n; i++ {
+			l.logged = append(l.logged, now)
+		}
+		l.mu.Unlock()
+	}
+	return l.Out.Write(p)
+}
+
+// Count returns the number of errors logged within the error window before now.
+func (l *ErrorLog) Count(now time.Time) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.logged = l.prune(now)
+	return len(l.logged)
+}
+
+// prune drops the times older than the error window, the caller holds the lock.
+func (l *ErrorLog) prune(now time.Time) []time.Time {
+	i := 0
+	for i < len(l.logged) && now.Sub(l.logged[i]) > adminErrorWindow {
+		i++
+	}
+	return l.logged[i:]
+}
+
+// BotAdminManager serves the admins of the bot, configured by their user IDs.
+type BotAdminManager struct {
+	TbAPI        TbAPI
+	StateManager StateManager
+	Storage      StatsRepository
+	Errors       *ErrorLog
+	AdminIDs     []int64
+
+	lastSeen map[int64]time.Time // last seen times written, called from the listener only
+}
+
+// IsAdmin reports whether the user is an admin of the bot.
+func (am *BotAdminManager) IsAdmin(userID int64) bool {
+	for _, id := range am.AdminIDs {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// Seen records that the user used the bot, so admins see how many users are active.
+func (am *BotAdminManager) Seen(userID int64) {
+	now := time.Now()
+	if now.Sub(am.lastSeen[userID]) < activityPrecision {
+		return
+	}
+
+	if err := am.Storage.Seen(userID, now); err != nil {
+		log.Printf("[error] error recording activity of user %d: %v", userID, err)
+		return
+	}
+	if am.lastSeen == nil {
+		am.lastSeen = make(map[int64]time.Time)
+	}
+	am.lastSeen[userID] = now
+}
+
+// Notify sends a catalog message to every admin in their language.
+func (am *BotAdminManager) Notify(key string, args ...interface{}) {
+	for _, adminID := range am.AdminIDs {
+		text := i18n.Text(am.StateManager.Language(adminID), key, args...)
+		if err := send(tbapi.NewMessage(adminID, text), am.TbAPI); err != nil {
+			log.Printf("[warn] error notifying admin %d: %v", adminID, err)
+		}
+	}
+}
+
+// Stats shows how the bot is used: users, active users, records, the database size and recent errors.
+func (am *BotAdminManager) Stats(lang string) (string, error) {
+	now := time.Now()
+	usage, err := am.Storage.Usage(now)
+	if err != nil {
+		return "", err
+	}
+
+	errorCount := 0
+	if am.Errors != nil {
+		errorCount = am.Errors.Count(now)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "🛠 *%s*\n\n", i18n.Text(lang, "admin.title"))
+	fmt.Fprintf(&sb, "%s\n", i18n.Text(lang, "admin.users", usage.Users))
+	fmt.Fprintf(&sb, "%s\n", i18n.Text(lang, "admin.active", usage.ActiveDay, usage.ActiveWeek, usage.ActiveMonth))
+	fmt.Fprintf(&sb, "%s\n", i18n.Text(lang, "admin.records", usage.Ledgers, usage.Categories, usage.Spendings))
+	fmt.Fprintf(&sb, "%s\n", i18n.Text(lang, "admin.database", float64(usage.DatabaseSize)/(1<<20)))
+	fmt.Fprintf(&sb, "%s", i18n.Text(lang, "admin.errors", errorCount))
+	return sb.String(), nil
+}
+
+// Broadcast sends the text to every user of the bot, pacing the messages to stay within Telegram limits.
+// It returns how many users got the message and how many couldn't be reached, e.g. because they blocked the bot.
+func (am *BotAdminManager) Broadcast(ctx context.Context, text string) (sent, failed int, err error) {
+	userIDs, err := am.Storage.ListUserIDs()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	ticker := time.NewTicker(broadcastInterval)
+	defer ticker.Stop()
+
+	for _, userID := range userIDs {
+		select {
+		case <-ctx.Done():
+			return sent, failed, ctx.Err()
+		case <-ticker.C:
+		}
+
+		if err := send(tbapi.NewMessage(userID, text), am.TbAPI); err != nil {
+			log.Printf("[info] broadcast didn't reach user %d: %v", userID, err)
+			failed++
+			continue
+		}
+		sent++
+	}
+
+	log.Printf("[info] broadcast sent to %d users, %d failed", sent, failed)
+	return sent, failed, nil
+}
@@ -0,0 +1,128 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/nyanyamaga/finance-tracker-bot/app/i18n"
+	"github.com/nyanyamaga/finance-tracker-bot/app/storage"
+	"strings"
+)
+
+// ErrSettlementOutdated is returned when a transfer marked as settled is no longer suggested,
+// e.g. it was already settled or the balances changed since it was shown.
+var ErrSettlementOutdated = errors.New("settlement is outdated")
+
+type BotBalanceManager struct {
+	TbKeyboards TbKeyboards
+	Ledgers     LedgerManager
+	Spendings   SpendingsRepository
+	Settlements SettlementsRepository
+	Accounts    AccountManager
+}
+
+// Balances shows the current balances of the accounts of the conversation's ledger and who owes whom,
+// with the transfers settling everyone up.
+func (bm *BotBalanceManager) Balances(lang string, conv Conversation) (string, tbapi.InlineKeyboardMarkup, error) {
+	// an empty, not nil, keyboard removes the buttons of an edited message once everyone is settled
+	keyboard := tbapi.InlineKeyboardMarkup{InlineKeyboard: [][]tbapi.InlineKeyboardButton{}}
+
+	member, err := bm.Ledgers.LedgerFor(conv)
+	if err != nil {
+		return "", keyboard, err
+	}
+
+	ledger, err := bm.Ledgers.Ledger(member.LedgerID)
+	if err != nil {
+		return "", keyboard, err
+	}
+
+	members, err := bm.Ledgers.Members(member.LedgerID)
+	if err != nil {
+		return "", keyboard, err
+	}
+
+	balances, err := bm.balances(member.LedgerID)
+	if err != nil {
+		return "", keyboard, err
+	}
+	transfers := settleUp(member.LedgerID, balances)
+
+	accounts, err := bm.Accounts.List(lang, conv)
+	if err != nil {
+		return "", keyboard, err
+	}
+
+	names := make(map[int64]string, len(members))
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "⚖️ *%s* · %s\n\n", i18n.Text(lang, "balances.title"), ledger.Name)
+	if accounts != "" {
+		sb.WriteString(accounts + "\n")
+	}
+	for _, m := range members {
+		names[m.UserID] = m.Name
+		if cents := balances[m.UserID]; cents != 0 {
+			fmt.Fprintf(&sb, "👤 %s: %+.2f\n", m.Name, float64(cents)/100)
+		}
+	}
+
+	if len(transfers) == 0 {
+		sb.WriteString(i18n.Text(lang, "balances.settled"))
+		return sb.String(), keyboard, nil
+	}
+
+	fmt.Fprintf(&sb, "\n*%s*\n", i18n.Text(lang, "balances.settle_up"))
+	for _, t := range transfers {
+		fmt.Fprintf(&sb, "%s → %s: %.2f\n", names[t.FromUserID], names[t.ToUserID], t.Amount)
+	}
+
+	return sb.String(), bm.TbKeyboards.GetSettleKeyboard(lang, transfers, members), nil
+}
+
+// Settle records a suggested transfer as paid. The transfer must still be suggested for the ledger,
+// so tapping an outdated button twice doesn't record it twice.
+func (bm *BotBalanceManager) Settle(conv Conversation, transfer storage.SettlementInfo) error {
+	member, err := bm.Ledgers.LedgerFor(conv)
+	if err != nil {
+		return err
+	}
+	if !canEdit(member) {
+		return fmt.Errorf("user %d can't settle in ledger %d: %w", conv.UserID, member.LedgerID, ErrPermissionDenied)
+	}
+
+	balances, err := bm.balances(member.LedgerID)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range settleUp(member.LedgerID, balances) {
+		if t.FromUserID == transfer.FromUserID && t.ToUserID == transfer.ToUserID && toCents(t.Amount) == toCents(transfer.Amount) {
+			t.CreatedBy = conv.UserID
+			return bm.Settlements.AddSettlement(t)
+		}
+	}
+	return ErrSettlementOutdated
+}
+
+// balances returns the net position of each ledger member in cents, split spendings minus settlements.
+func (bm *BotBalanceManager) balances(ledgerID int64) (map[int64]int64, error) {
+	splits, err := bm.Spendings.SplitBalances(ledgerID)
+	if err != nil {
+		return nil, err
+	}
+
+	settlements, err := bm.Settlements.ListSettlements(ledgerID)
+	if err != nil {
+		return nil, err
+	}
+
+	balances := make(map[int64]int64, len(splits))
+	for userID, amount := range splits {
+		balances[userID] += toCents(amount)
+	}
+	for _, s := range settlements {
+		balances[s.FromUserID] += toCents(s.Amount)
+		balances[s.ToUserID] -= toCents(s.Amount)
+	}
+	return balances, nil
+}
@@ -0,0 +1,346 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	"github.com/nyanyamaga/finance-tracker-bot/app/i18n"
+	"github.com/nyanyamaga/finance-tracker-bot/app/storage"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrCategoryNotFound is returned when a category named by the user doesn't exist in the ledger.
+var ErrCategoryNotFound = errors.New("category not found")
+
+// Envelope errors
+var (
+	ErrBudgetNotFound = errors.New("budget not found")
+	ErrNotOverspent   = errors.New("envelope is not overspent")
+	ErrEnvelopeEmpty  = errors.New("envelope has nothing left")
+)
+
+type BotBudgetManager struct {
+	Ledgers    LedgerManager
+	Categories CategoriesRepository
+	Spendings  SpendingsRepository
+	Budgets    BudgetsRepository
+}
+
+// Status shows the envelopes of the conversation's ledger against this month's spendings.
+func (bm *BotBudgetManager) Status(lang string, conv Conversation) (string, error) {
+	member, err := bm.Ledgers.LedgerFor(conv)
+	if err != nil {
+		return "", err
+	}
+
+	from := monthStart(time.Now())
+	if err = openEnvelopes(member.LedgerID, from, bm.Budgets, bm.Categories, bm.Spendings); err != nil {
+		return "", err
+	}
+
+	status, err := budgetStatus(lang, member.LedgerID, from, bm.Budgets, bm.Categories, bm.Spendings)
+	if err != nil {
+		return "", err
+	}
+	if status == "" {
+		return i18n.Text(lang, "budget.none"), nil
+	}
+
+	return fmt.Sprintf("💰 *%s %d*\n\n%s\n%s", i18n.MonthName(lang, from.Month()), from.Year(), status, i18n.Text(lang, "budget.hint")), nil
+}
+
+// SetBudget sets the monthly budget of the conversation's ledger, or of its category when one is named.
+// Categories are matched by name or emoji, a zero amount removes the budget.
+// The envelope of the current month gets the new amount right away.
+func (bm *BotBudgetManager) SetBudget(conv Conversation, category string, amount float64) error {
+	member, err := bm.Ledgers.LedgerFor(conv)
+	if err != nil {
+		return err
+	}
+	if !canEdit(member) {
+		return fmt.Errorf("user %d can't set budgets in ledger %d: %w", conv.UserID, member.LedgerID, ErrPermissionDenied)
+	}
+
+	categoryID, err := bm.budgetCategory(member.LedgerID, category)
+	if err != nil {
+		return err
+	}
+
+	if err = bm.Budgets.SetBudget(storage.BudgetInfo{LedgerID: member.LedgerID, CategoryID: categoryID, Amount: amount}); err != nil {
+		return err
+	}
+
+	from := monthStart(time.Now())
+	if err = bm.Budgets.SetAllocation(member.LedgerID, categoryID, from.Format(storage.EnvelopeMonthLayout), amount); err != nil {
+		return err
+	}
+	return openEnvelopes(member.LedgerID, from, bm.Budgets, bm.Categories, bm.Spendings)
+}
+
+// ToggleRollover switches whether the unspent amount of a budget is carried over to the next month
+// and returns the new setting. An empty category means the whole ledger budget.
+func (bm *BotBudgetManager) ToggleRollover(conv Conversation, category string) (bool, error) {
+	member, err := bm.Ledgers.LedgerFor(conv)
+	if err != nil {
+		return false, err
+	}
+	if !canEdit(member) {
+		return false, fmt.Errorf("user %d can't change budgets in ledger %d: %w", conv.UserID, member.LedgerID, ErrPermissionDenied)
+	}
+
+	categoryID, err := bm.budgetCategory(member.LedgerID, category)
+	if err != nil {
+		return false, err
+	}
+
+	budgets, err := bm.Budgets.ListBudgets(member.LedgerID)
+	if err != nil {
+		return false, err
+	}
+	for _, b := range budgets {
+		if b.CategoryID == categoryID {
+			return !b.Rollover, bm.Budgets.SetRollover(member.LedgerID, categoryID, !b.Rollover)
+		}
+	}
+	return false, fmt.Errorf("%q: %w", category, ErrBudgetNotFound)
+}
+
+// Cover pulls the overspending of a category envelope this month from the envelope of the source category,
+// as much as the source has left, and returns the moved amount.
+func (bm *BotBudgetManager) Cover(conv Conversation, category, source string) (float64, error) {
+	member, err := bm.Ledgers.LedgerFor(conv)
+	if err != nil {
+		return 0, err
+	}
+	if !canEdit(member) {
+		return 0, fmt.Errorf("user %d can't change budgets in ledger %d: %w", conv.UserID, member.LedgerID, ErrPermissionDenied)
+	}
+
+	toID, err := bm.budgetCategory(member.LedgerID, category)
+	if err != nil {
+		return 0, err
+	}
+	fromID, err := bm.budgetCategory(member.LedgerID, source)
+	if err != nil {
+		return 0, err
+	}
+
+	from := monthStart(time.Now())
+	if err = openEnvelopes(member.LedgerID, from, bm.Budgets, bm.Categories, bm.Spendings); err != nil {
+		return 0, err
+	}
+	envelopes, err := monthEnvelopes(member.LedgerID, from, bm.Budgets, bm.Categories, bm.Spendings)
+	if err != nil {
+		return 0, err
+	}
+
+	var target, origin *envelopeStatus
+	for i := range envelopes {
+		switch envelopes[i].CategoryID {
+		case toID:
+			target = &envelopes[i]
+		case fromID:
+			origin = &envelopes[i]
+		}
+	}
+	if target == nil {
+		return 0, fmt.Errorf("%q: %w", category, ErrBudgetNotFound)
+	}
+	if origin == nil || fromID == toID {
+		return 0, fmt.Errorf("%q: %w", source, ErrBudgetNotFound)
+	}
+
+	deficit := toCents(target.Spent - target.Available())
+	if deficit <= 0 {
+		return 0, fmt.Errorf("%q: %w", category, ErrNotOverspent)
+	}
+	left := toCents(origin.Available() - origin.Spent)
+	if left <= 0 {
+		return 0, fmt.Errorf("%q: %w", source, ErrEnvelopeEmpty)
+	}
+
+	amount := float64(min(deficit, left)) / 100
+	if err = bm.Budgets.MoveAllocation(member.LedgerID, from.Format(storage.EnvelopeMonthLayout), fromID, toID, amount); err != nil {
+		return 0, err
+	}
+	return amount, nil
+}
+
+// budgetCategory returns the ID of the category a budget is set for, 0 for the whole ledger when none is named.
+func (bm *BotBudgetManager) budgetCategory(ledgerID int64, category string) (int64, error) {
+	if category == "" {
+		return 0, nil
+	}
+
+	categories, err := bm.Categories.ListCategories(ledgerID)
+	if err != nil {
+		return 0, err
+	}
+	c, ok := findCategory(categories, category)
+	if !ok {
+		return 0, fmt.Errorf("%q: %w", category, ErrCategoryNotFound)
+	}
+	return c.ID, nil
+}
+
+// envelopeStatus is an envelope of a month along with what was spent from it.
+type envelopeStatus struct {
+	storage.EnvelopeInfo
+	Rollover bool
+	Spent    float64
+}
+
+// monthEnvelopes returns the envelopes of the ledger for the month starting at from with their spendings.
+// Budgets without an envelope for the month, e.g. before the month was opened, are allocated their amount.
+func monthEnvelopes(ledgerID int64, from time.Time, budgets BudgetsRepository, categories CategoriesRepository, spendings SpendingsRepository) ([]envelopeStatus, error) {
+	limits, err := budgets.ListBudgets(ledgerID)
+	if err != nil {
+		return nil, err
+	}
+
+	month := from.Format(storage.EnvelopeMonthLayout)
+	opened, err := budgets.ListEnvelopes(ledgerID, month)
+	if err != nil {
+		return nil, err
+	}
+	if len(limits) == 0 && len(opened) == 0 {
+		return nil, nil
+	}
+
+	envelopes := make(map[int64]*envelopeStatus, len(limits)+len(opened))
+	var order []int64
+	for _, e := range opened {
+		envelopes[e.CategoryID] = &envelopeStatus{EnvelopeInfo: e}
+		order = append(order, e.CategoryID)
+	}
+	for _, b := range limits {
+		if _, ok := envelopes[b.CategoryID]; !ok {
+			envelopes[b.CategoryID] = &envelopeStatus{EnvelopeInfo: storage.EnvelopeInfo{LedgerID: ledgerID, CategoryID: b.CategoryID, Month: month, Allocated: b.Amount}}
+			order = append(order, b.CategoryID)
+		}
+		envelopes[b.CategoryID].Rollover = b.Rollover
+	}
+
+	sort.Slice(order, func(i, j int) bool { return order[i] < order[j] })
+
+	spent, err := categorySpent(ledgerID, from, categories, spendings)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]envelopeStatus, 0, len(order))
+	for _, categoryID := range order {
+		e := envelopes[categoryID]
+		e.Spent = spent[categoryID]
+		result = append(result, *e)
+	}
+	return result, nil
+}
+
+// categorySpent sums spendings of the month starting at from per category, 0 holding the total.
+// Budgets of parent categories cover their sub-categories as well.
+func categorySpent(ledgerID int64, from time.Time, categories CategoriesRepository, spendings SpendingsRepository) (map[int64]float64, error) {
+	totals, err := spendings.TotalsByCategory(ledgerID, 0, from, from.AddDate(0, 1, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	ledgerCategories, err := categories.ListCategories(ledgerID)
+	if err != nil {
+		return nil, err
+	}
+	parents := make(map[int64]int64, len(ledgerCategories))
+	for _, c := range ledgerCategories {
+		parents[c.ID] = c.ParentID
+	}
+
+	spent := make(map[int64]float64, len(totals)+1)
+	for _, t := range totals {
+		spent[t.CategoryID] += t.Total
+		spent[0] += t.Total
+		if parentID := parents[t.CategoryID]; parentID != 0 {
+			spent[parentID] += t.Total
+		}
+	}
+	return spent, nil
+}
+
+// budgetStatus lists the ledger's envelopes against spendings of the month starting at from,
+// empty when the ledger has no budgets.
+func budgetStatus(lang string, ledgerID int64, from time.Time, budgets BudgetsRepository, categories CategoriesRepository, spendings SpendingsRepository) (string, error) {
+	envelopes, err := monthEnvelopes(ledgerID, from, budgets, categories, spendings)
+	if err != nil || len(envelopes) == 0 {
+		return "", err
+	}
+
+	ledgerCategories, err := categories.ListCategories(ledgerID)
+	if err != nil {
+		return "", err
+	}
+	names := map[int64]string{0: "💰 " + i18n.Text(lang, "report.total")}
+	for _, c := range ledgerCategories {
+		names[c.ID] = c.Emoji + " " + c.Name
+	}
+
+	var sb strings.Builder
+	for _, e := range envelopes {
+		available := e.Available()
+		fmt.Fprintf(&sb, "%s — %.2f / %.2f", names[e.CategoryID], e.Spent, available)
+		if available > 0 {
+			fmt.Fprintf(&sb, " (%.0f%%)", e.Spent/available*100)
+		}
+		if toCents(e.Spent) > toCents(available) {
+			sb.WriteString(" ⚠️")
+		}
+		if e.Rollover {
+			sb.WriteString(" 🔁")
+		}
+		if toCents(e.Carried) != 0 {
+			fmt.Fprintf(&sb, " ↪%+.2f", e.Carried)
+		}
+		if toCents(e.Moved) != 0 {
+			fmt.Fprintf(&sb, " ⇄%+.2f", e.Moved)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String(), nil
+}
+
+// openEnvelopes allocates the envelopes of the month starting at from from the ledger's budgets,
+// carrying over what's left in the previous month's envelopes of budgets that roll over.
+// Envelopes already opened are kept as they are.
+func openEnvelopes(ledgerID int64, from time.Time, budgets BudgetsRepository, categories CategoriesRepository, spendings SpendingsRepository) error {
+	limits, err := budgets.ListBudgets(ledgerID)
+	if err != nil || len(limits) == 0 {
+		return err
+	}
+
+	previous, err := monthEnvelopes(ledgerID, from.AddDate(0, -1, 0), budgets, categories, spendings)
+	if err != nil {
+		return err
+	}
+	left := make(map[int64]float64, len(previous))
+	for _, e := range previous {
+		if cents := toCents(e.Available() - e.Spent); cents > 0 {
+			left[e.CategoryID] = float64(cents) / 100
+		}
+	}
+
+	month := from.Format(storage.EnvelopeMonthLayout)
+	for _, b := range limits {
+		envelope := storage.EnvelopeInfo{LedgerID: ledgerID, CategoryID: b.CategoryID, Month: month, Allocated: b.Amount}
+		if b.Rollover {
+			envelope.Carried = left[b.CategoryID]
+		}
+		if err = budgets.OpenEnvelope(envelope); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// monthStart returns the beginning of the month of t.
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
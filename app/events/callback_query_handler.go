@@ -2,13 +2,29 @@ package events
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/nyanyamaga/finance-tracker-bot/app/i18n"
+	"github.com/nyanyamaga/finance-tracker-bot/app/keyboards"
+	"github.com/nyanyamaga/finance-tracker-bot/app/storage"
 	"log"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type BotCallbackQueryHandler struct {
+	TbAPI        TbAPI
+	TbKeyboards  TbKeyboards
 	StateManager StateManager
+	Ledgers      LedgerManager
+	Reporter     Reporter
+	Balances     BalanceManager
+	Settings     SettingsManager
+	Rules        RuleManager
+	Receipts     ReceiptManager
+	Goals        GoalManager
 }
 
 func (h *BotCallbackQueryHandler) HandleCallbackQuery(ctx context.Context, update tbapi.Update) {
@@ -24,22 +40,486 @@ func (h *BotCallbackQueryHandler) HandleCallbackQuery(ctx context.Context, updat
 
 	log.Printf("[info] handling callback query: user %d, data %s", userID, callbackData)
 
-	currentState, err := h.StateManager.GetCurrentState(ctx, userID)
+	// buttons that don't advance the conversation are handled without touching the state machine
+	switch {
+	case callbackData == keyboards.DatePick || strings.HasPrefix(callbackData, keyboards.CalendarNavPrefix):
+		err = h.navigateCalendar(update.CallbackQuery)
+	case strings.HasPrefix(callbackData, keyboards.LanguagePrefix):
+		err = h.chooseLanguage(update.CallbackQuery)
+	case strings.HasPrefix(callbackData, keyboards.LedgerPrefix):
+		err = h.switchLedger(update.CallbackQuery)
+	case strings.HasPrefix(callbackData, keyboards.ReportMemberPrefix):
+		err = h.filterReport(update.CallbackQuery)
+	case strings.HasPrefix(callbackData, keyboards.ReminderPrefix):
+		err = h.handleReminder(ctx, update.CallbackQuery)
+	case strings.HasPrefix(callbackData, keyboards.SettingsPrefix):
+		err = h.changeSettings(update.CallbackQuery)
+	case strings.HasPrefix(callbackData, keyboards.ChartPrefix):
+		err = h.switchChart(update.CallbackQuery)
+	case strings.HasPrefix(callbackData, keyboards.SettlePrefix):
+		err = h.settle(update.CallbackQuery)
+	case strings.HasPrefix(callbackData, keyboards.FindPagePrefix):
+		err = h.turnFindPage(update.CallbackQuery)
+	case strings.HasPrefix(callbackData, keyboards.CategoryPagePrefix):
+		err = h.turnCategoryPage(update.CallbackQuery)
+	case strings.HasPrefix(callbackData, keyboards.RuleDeletePrefix):
+		err = h.deleteRule(update.CallbackQuery)
+	case strings.HasPrefix(callbackData, keyboards.HistoryPrefix):
+		err = h.browseHistory(update.CallbackQuery)
+	case strings.HasPrefix(callbackData, keyboards.GoalPrefix):
+		err = h.handleGoal(ctx, update.CallbackQuery)
+	case strings.HasPrefix(callbackData, keyboards.ReceiptPrefix):
+		err = h.sendReceipt(update.CallbackQuery)
+	case strings.HasPrefix(callbackData, keyboards.UnusualPrefix):
+		err = h.handleUnusual(ctx, update.CallbackQuery)
+	case callbackData == keyboards.Noop:
+		h.answer(update.CallbackQuery, "")
+	default:
+		err = h.triggerTransition(ctx, callbackConversation(update.CallbackQuery), callbackData)
+	}
+
 	if err != nil {
-		log.Printf("Error retrieving current state for user %d: %v", userID, err)
-		return
+		log.Printf("[warn] error handling callback query %s: %v", callbackData, err)
+	}
+}
+
+func (h *BotCallbackQueryHandler) triggerTransition(ctx context.Context, conv Conversation, callbackData string) error {
+	currentState, err := h.StateManager.GetCurrentState(ctx, conv)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve current state for %v: %w", conv, err)
 	}
 
-	nextStates := currentState.AvailableTransitions()
+	// optional steps are opened by their own buttons
+	if callbackData == keyboards.DateChoose {
+		if err = h.StateManager.TriggerStateChange(ctx, conv, "ChooseDate", callbackData); err != nil {
+			return fmt.Errorf("error triggering state change: %w", err)
+		}
+		return nil
+	}
+
+	nextStates := inputTransitions(currentState)
 	if len(nextStates) == 0 {
-		err = fmt.Errorf("no available transitions from current state")
+		return fmt.Errorf("no available transitions from current state")
 	} else if len(nextStates) > 1 {
-		err = fmt.Errorf("more than one available transition from current state")
+		return fmt.Errorf("more than one available transition from current state")
+	}
+
+	if err = h.StateManager.TriggerStateChange(ctx, conv, nextStates[0], callbackData); err != nil {
+		return fmt.Errorf("error triggering state change: %w", err)
+	}
+	return nil
+}
+
+// navigateCalendar swaps the keyboard of the date prompt to the calendar of the requested month.
+// It doesn't touch the user state, the date is submitted only when a day is tapped.
+func (h *BotCallbackQueryHandler) navigateCalendar(query *tbapi.CallbackQuery) error {
+	h.answer(query, "")
+
+	if query.Data == keyboards.CalendarIgnore || query.Message == nil {
+		return nil
+	}
+
+	month := time.Now()
+	if query.Data != keyboards.DatePick {
+		var err error
+		month, err = time.Parse(keyboards.CalendarMonthLayout, strings.TrimPrefix(query.Data, keyboards.CalendarNavPrefix))
+		if err != nil {
+			return fmt.Errorf("invalid calendar month %q: %w", query.Data, err)
+		}
+	}
+
+	keyboard := h.TbKeyboards.GetCalendarKeyboard(h.StateManager.Language(query.From.ID), month)
+	edit := tbapi.NewEditMessageReplyMarkup(query.Message.Chat.ID, query.Message.MessageID, keyboard)
+	return send(edit, h.TbAPI)
+}
+
+// chooseLanguage saves the picked language and re-sends the main keyboard translated.
+func (h *BotCallbackQueryHandler) chooseLanguage(query *tbapi.CallbackQuery) error {
+	userID := query.From.ID
+	lang := strings.TrimPrefix(query.Data, keyboards.LanguagePrefix)
+
+	if err := h.StateManager.SetLanguage(userID, lang); err != nil {
+		h.answer(query, "")
+		return err
+	}
+	lang = h.StateManager.Language(userID)
+	h.answer(query, i18n.Text(lang, "language.saved"))
+
+	if query.Message == nil {
+		return nil
+	}
+
+	msg := tbapi.NewMessage(query.Message.Chat.ID, i18n.Text(lang, "language.saved"))
+	msg.ReplyMarkup = h.TbKeyboards.GetMainKeyboard(lang)
+	return send(msg, h.TbAPI)
+}
+
+// switchLedger makes the tapped ledger active and marks it in the ledger list.
+func (h *BotCallbackQueryHandler) switchLedger(query *tbapi.CallbackQuery) error {
+	userID := query.From.ID
+	lang := h.StateManager.Language(userID)
+
+	ledgerID, err := strconv.ParseInt(strings.TrimPrefix(query.Data, keyboards.LedgerPrefix), 10, 64)
+	if err != nil {
+		h.answer(query, "")
+		return fmt.Errorf("invalid ledger id %q: %w", query.Data, err)
+	}
+
+	if err = h.Ledgers.SwitchLedger(userID, ledgerID); err != nil {
+		h.answer(query, i18n.Text(lang, "error.generic"))
+		return err
+	}
+
+	ledger, err := h.Ledgers.Ledger(ledgerID)
+	if err != nil {
+		h.answer(query, "")
+		return err
+	}
+	h.answer(query, i18n.Text(lang, "ledger.switched", ledger.Name))
+
+	ledgers, err := h.Ledgers.UserLedgers(userID)
+	if err != nil || query.Message == nil {
+		return err
+	}
+
+	keyboard := h.TbKeyboards.GetLedgerKeyboard(ledgers, ledgerID)
+	return send(tbapi.NewEditMessageReplyMarkup(query.Message.Chat.ID, query.Message.MessageID, keyboard), h.TbAPI)
+}
+
+// filterReport re-renders the report message for the selected member and category.
+func (h *BotCallbackQueryHandler) filterReport(query *tbapi.CallbackQuery) error {
+	h.answer(query, "")
+
+	// reports sent before sub-categories carry the member only
+	memberArg, parentArg, _ := strings.Cut(strings.TrimPrefix(query.Data, keyboards.ReportMemberPrefix), "_")
+	memberID, err := strconv.ParseInt(memberArg, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid report member %q: %w", query.Data, err)
+	}
+	var parentID int64
+	if parentArg != "" {
+		if parentID, err = strconv.ParseInt(parentArg, 10, 64); err != nil {
+			return fmt.Errorf("invalid report category %q: %w", query.Data, err)
+		}
+	}
+
+	text, keyboard, err := h.Reporter.MonthlyReport(h.StateManager.Language(query.From.ID), callbackConversation(query), memberID, parentID)
+	if err != nil || query.Message == nil {
+		return err
+	}
+
+	return send(tbapi.NewEditMessageTextAndMarkup(query.Message.Chat.ID, query.Message.MessageID, text, keyboard), h.TbAPI)
+}
+
+// changeSettings toggles the tapped setting and refreshes the settings keyboard.
+// The language button swaps the keyboard to the language choice.
+func (h *BotCallbackQueryHandler) changeSettings(query *tbapi.CallbackQuery) error {
+	userID := query.From.ID
+	lang := h.StateManager.Language(userID)
+
+	if query.Data == keyboards.SettingsLanguage {
+		h.answer(query, "")
+		if query.Message == nil {
+			return nil
+		}
+		edit := tbapi.NewEditMessageTextAndMarkup(query.Message.Chat.ID, query.Message.MessageID, i18n.Text(lang, "language.prompt"), h.TbKeyboards.GetLanguageKeyboard())
+		return send(edit, h.TbAPI)
+	}
+
+	settings, err := h.Settings.ToggleDigest(userID, strings.TrimPrefix(query.Data, keyboards.SettingsDigestPrefix))
+	if err != nil {
+		h.answer(query, i18n.Text(lang, "error.generic"))
+		return err
+	}
+	h.answer(query, i18n.Text(lang, "settings.saved"))
+
+	if query.Message == nil {
+		return nil
+	}
+	keyboard := h.TbKeyboards.GetSettingsKeyboard(lang, *settings)
+	return send(tbapi.NewEditMessageReplyMarkup(query.Message.Chat.ID, query.Message.MessageID, keyboard), h.TbAPI)
+}
+
+// handleReminder snoozes a reminder or starts adding a spending right from it.
+// Either way the reminder buttons are removed, so they aren't tapped twice.
+func (h *BotCallbackQueryHandler) handleReminder(ctx context.Context, query *tbapi.CallbackQuery) error {
+	conv := callbackConversation(query)
+	lang := h.StateManager.Language(conv.UserID)
+
+	var err error
+	if query.Data == keyboards.ReminderAdd {
+		h.answer(query, "")
+		h.StateManager.SetIdleState(ctx, conv)
+		err = h.StateManager.TriggerStateChange(ctx, conv, "ChooseAddSpending", "")
+	} else {
+		var minutes int
+		if minutes, err = strconv.Atoi(strings.TrimPrefix(query.Data, keyboards.ReminderSnoozePrefix)); err != nil {
+			h.answer(query, "")
+			return fmt.Errorf("invalid snooze %q: %w", query.Data, err)
+		}
+		if err = h.Settings.SnoozeReminder(conv.UserID, time.Duration(minutes)*time.Minute); err != nil {
+			h.answer(query, i18n.Text(lang, "error.generic"))
+			return err
+		}
+		h.answer(query, i18n.Text(lang, "reminder.snoozed", minutes/60))
+	}
+
+	if query.Message != nil {
+		noButtons := tbapi.InlineKeyboardMarkup{InlineKeyboard: [][]tbapi.InlineKeyboardButton{}}
+		edit := tbapi.NewEditMessageReplyMarkup(query.Message.Chat.ID, query.Message.MessageID, noButtons)
+		if editErr := send(edit, h.TbAPI); editErr != nil {
+			log.Printf("[warn] error removing reminder buttons: %v", editErr)
+		}
+	}
+	return err
+}
+
+// switchChart replaces the chart image with the chart of the tapped kind and period.
+func (h *BotCallbackQueryHandler) switchChart(query *tbapi.CallbackQuery) error {
+	h.answer(query, "")
+
+	kind, period, ok := strings.Cut(strings.TrimPrefix(query.Data, keyboards.ChartPrefix), "_")
+	if !ok {
+		return fmt.Errorf("invalid chart %q", query.Data)
+	}
+
+	chart, caption, keyboard, err := h.Reporter.Chart(h.StateManager.Language(query.From.ID), callbackConversation(query), kind, period)
+	if err != nil || query.Message == nil {
+		return err
+	}
+
+	photo := tbapi.NewInputMediaPhoto(tbapi.FileBytes{Name: "chart.png", Bytes: chart})
+	photo.Caption = caption
+	edit := tbapi.EditMessageMediaConfig{
+		BaseEdit: tbapi.BaseEdit{ChatID: query.Message.Chat.ID, MessageID: query.Message.MessageID, ReplyMarkup: &keyboard},
+		Media:    photo,
+	}
+	return send(edit, h.TbAPI)
+}
+
+// settle records the tapped settle-up transfer and refreshes the balances message.
+func (h *BotCallbackQueryHandler) settle(query *tbapi.CallbackQuery) error {
+	conv := callbackConversation(query)
+	lang := h.StateManager.Language(conv.UserID)
+
+	var transfer storage.SettlementInfo
+	var cents int64
+	if _, err := fmt.Sscanf(strings.TrimPrefix(query.Data, keyboards.SettlePrefix), "%d_%d_%d", &transfer.FromUserID, &transfer.ToUserID, &cents); err != nil {
+		h.answer(query, "")
+		return fmt.Errorf("invalid settlement %q: %w", query.Data, err)
+	}
+	transfer.Amount = float64(cents) / 100
+
+	err := h.Balances.Settle(conv, transfer)
+	switch {
+	case errors.Is(err, ErrPermissionDenied):
+		h.answer(query, i18n.Text(lang, "ledger.read_only"))
+		return err
+	case errors.Is(err, ErrSettlementOutdated):
+		h.answer(query, i18n.Text(lang, "balances.outdated"))
+	case err != nil:
+		h.answer(query, i18n.Text(lang, "error.generic"))
+		return err
+	default:
+		h.answer(query, i18n.Text(lang, "balances.recorded"))
+	}
+
+	text, keyboard, err := h.Balances.Balances(lang, conv)
+	if err != nil || query.Message == nil {
+		return err
 	}
 
-	err = h.StateManager.TriggerStateChange(ctx, userID, nextStates[0], callbackData)
+	return send(tbapi.NewEditMessageTextAndMarkup(query.Message.Chat.ID, query.Message.MessageID, text, keyboard), h.TbAPI)
+}
+
+// turnCategoryPage swaps the category keyboard to another page or to sub-categories of a category,
+// leaving the conversation state as is.
+func (h *BotCallbackQueryHandler) turnCategoryPage(query *tbapi.CallbackQuery) error {
+	h.answer(query, "")
+
+	var parentID int64
+	var page int
+	if _, err := fmt.Sscanf(strings.TrimPrefix(query.Data, keyboards.CategoryPagePrefix), "%d_%d", &parentID, &page); err != nil {
+		return fmt.Errorf("invalid category page %q: %w", query.Data, err)
+	}
 
+	conv := callbackConversation(query)
+	member, err := h.Ledgers.LedgerFor(conv)
+	if err != nil || query.Message == nil {
+		return err
+	}
+
+	keyboard := h.TbKeyboards.GetCategoryKeyboard(h.StateManager.Language(conv.UserID), member.LedgerID, conv.UserID, parentID, page)
+	return send(tbapi.NewEditMessageReplyMarkup(query.Message.Chat.ID, query.Message.MessageID, keyboard), h.TbAPI)
+}
+
+// deleteRule removes the tapped auto-categorization rule and refreshes the rule list.
+func (h *BotCallbackQueryHandler) deleteRule(query *tbapi.CallbackQuery) error {
+	conv := callbackConversation(query)
+	lang := h.StateManager.Language(conv.UserID)
+
+	ruleID, err := strconv.ParseInt(strings.TrimPrefix(query.Data, keyboards.RuleDeletePrefix), 10, 64)
 	if err != nil {
-		log.Printf("[warn] error triggering state change: %v", err)
+		h.answer(query, "")
+		return fmt.Errorf("invalid rule %q: %w", query.Data, err)
+	}
+
+	err = h.Rules.DeleteRule(conv, ruleID)
+	switch {
+	case errors.Is(err, ErrPermissionDenied):
+		h.answer(query, i18n.Text(lang, "ledger.read_only"))
+		return err
+	case err != nil:
+		h.answer(query, i18n.Text(lang, "error.generic"))
+		return err
+	}
+	h.answer(query, i18n.Text(lang, "rules.deleted"))
+
+	text, keyboard, err := h.Rules.List(lang, conv)
+	if err != nil || query.Message == nil {
+		return err
+	}
+
+	return send(tbapi.NewEditMessageTextAndMarkup(query.Message.Chat.ID, query.Message.MessageID, text, keyboard), h.TbAPI)
+}
+
+// turnFindPage shows another page of search results, the query is taken from the results message.
+func (h *BotCallbackQueryHandler) turnFindPage(query *tbapi.CallbackQuery) error {
+	h.answer(query, "")
+
+	page, err := strconv.Atoi(strings.TrimPrefix(query.Data, keyboards.FindPagePrefix))
+	if err != nil {
+		return fmt.Errorf("invalid search page %q: %w", query.Data, err)
+	}
+	if query.Message == nil {
+		return nil
+	}
+
+	text, keyboard, err := h.Reporter.Find(h.StateManager.Language(query.From.ID), callbackConversation(query), findQueryOf(query.Message), page)
+	if err != nil {
+		return err
+	}
+
+	return send(tbapi.NewEditMessageTextAndMarkup(query.Message.Chat.ID, query.Message.MessageID, text, keyboard), h.TbAPI)
+}
+
+// browseHistory edits the history message in place to show the tapped page or filter.
+func (h *BotCallbackQueryHandler) browseHistory(query *tbapi.CallbackQuery) error {
+	h.answer(query, "")
+
+	period, scope, cursor, err := parseHistoryCallback(query.Data)
+	if err != nil {
+		return err
+	}
+
+	text, keyboard, err := h.Reporter.History(h.StateManager.Language(query.From.ID), callbackConversation(query), period, scope, cursor)
+	if err != nil || query.Message == nil {
+		return err
+	}
+
+	return send(tbapi.NewEditMessageTextAndMarkup(query.Message.Chat.ID, query.Message.MessageID, text, keyboard), h.TbAPI)
+}
+
+// sendReceipt re-sends the receipt attached to a spending listed in the history.
+func (h *BotCallbackQueryHandler) sendReceipt(query *tbapi.CallbackQuery) error {
+	conv := callbackConversation(query)
+	lang := h.StateManager.Language(conv.UserID)
+
+	spendingID, err := strconv.ParseInt(strings.TrimPrefix(query.Data, keyboards.ReceiptPrefix), 10, 64)
+	if err != nil {
+		h.answer(query, "")
+		return fmt.Errorf("invalid receipt %q: %w", query.Data, err)
+	}
+
+	receipt, err := h.Receipts.Receipt(conv, spendingID)
+	if err != nil {
+		h.answer(query, i18n.Text(lang, "receipt.missing"))
+		return err
+	}
+	h.answer(query, "")
+
+	return send(receiptMessage(conv.ChatID, *receipt), h.TbAPI)
+}
+
+// handleGoal starts contributing to, editing or adding a goal, or closes a goal right away
+// and updates the goals message.
+func (h *BotCallbackQueryHandler) handleGoal(ctx context.Context, query *tbapi.CallbackQuery) error {
+	conv := callbackConversation(query)
+	lang := h.StateManager.Language(conv.UserID)
+
+	var event string
+	switch {
+	case query.Data == keyboards.GoalNew:
+		event = "ChooseAddGoal"
+	case strings.HasPrefix(query.Data, keyboards.GoalContributePrefix):
+		event = "ChooseContribute"
+	case strings.HasPrefix(query.Data, keyboards.GoalEditPrefix):
+		event = "ChooseEditGoal"
+	}
+	if event != "" {
+		h.answer(query, "")
+		h.StateManager.SetIdleState(ctx, conv)
+		return h.StateManager.TriggerStateChange(ctx, conv, event, query.Data)
+	}
+
+	goalID, err := strconv.ParseInt(strings.TrimPrefix(query.Data, keyboards.GoalClosePrefix), 10, 64)
+	if err != nil {
+		h.answer(query, "")
+		return fmt.Errorf("invalid goal %q: %w", query.Data, err)
+	}
+
+	err = h.Goals.CloseGoal(conv, goalID)
+	switch {
+	case errors.Is(err, ErrPermissionDenied):
+		h.answer(query, i18n.Text(lang, "ledger.read_only"))
+		return err
+	case err != nil:
+		h.answer(query, i18n.Text(lang, "error.generic"))
+		return err
+	}
+	h.answer(query, i18n.Text(lang, "goals.closed"))
+
+	text, keyboard, err := h.Goals.List(lang, conv)
+	if err != nil || query.Message == nil {
+		return err
+	}
+
+	return send(tbapi.NewEditMessageTextAndMarkup(query.Message.Chat.ID, query.Message.MessageID, text, keyboard), h.TbAPI)
+}
+
+// callbackConversation returns the conversation a callback query belongs to.
+func callbackConversation(query *tbapi.CallbackQuery) Conversation {
+	if query.Message == nil {
+		return Conversation{ChatID: query.From.ID, UserID: query.From.ID}
+	}
+	return Conversation{ChatID: query.Message.Chat.ID, UserID: query.From.ID}
+}
+
+// handleUnusual removes the buttons of an unusual spending prompt, starting to fix the amount if asked to.
+func (h *BotCallbackQueryHandler) handleUnusual(ctx context.Context, query *tbapi.CallbackQuery) error {
+	conv := callbackConversation(query)
+	lang := h.StateManager.Language(conv.UserID)
+
+	if query.Message != nil {
+		empty := tbapi.InlineKeyboardMarkup{InlineKeyboard: [][]tbapi.InlineKeyboardButton{}}
+		if err := send(tbapi.NewEditMessageReplyMarkup(query.Message.Chat.ID, query.Message.MessageID, empty), h.TbAPI); err != nil {
+			log.Printf("[warn] error removing unusual spending buttons: %v", err)
+		}
+	}
+
+	if strings.HasPrefix(query.Data, keyboards.UnusualConfirmPrefix) {
+		h.answer(query, i18n.Text(lang, "unusual.confirmed"))
+		return nil
+	}
+
+	h.answer(query, "")
+	h.StateManager.SetIdleState(ctx, conv)
+	return h.StateManager.TriggerStateChange(ctx, conv, "ChooseFixAmount", query.Data)
+}
+
+// answer acknowledges the callback query, so the client stops showing the progress indicator.
+func (h *BotCallbackQueryHandler) answer(query *tbapi.CallbackQuery, text string) {
+	if _, err := h.TbAPI.Request(tbapi.NewCallback(query.ID, text)); err != nil {
+		log.Printf("[warn] error answering callback query: %v", err)
 	}
 }
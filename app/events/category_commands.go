@@ -0,0 +1,91 @@
+package events
+
+import (
+	"errors"
+	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/nyanyamaga/finance-tracker-bot/app/i18n"
+	"github.com/nyanyamaga/finance-tracker-bot/app/storage"
+	"strings"
+)
+
+// toggleFavorite pins the named category to the top of the user's category keyboard, or unpins it.
+func (h *BotCommandHandler) toggleFavorite(msg *tbapi.Message, name string) {
+	conv := ConversationOf(msg)
+	lang := h.StateManager.Language(conv.UserID)
+
+	member, err := h.Ledgers.LedgerFor(conv)
+	if err != nil {
+		h.replyError(msg, err)
+		return
+	}
+
+	categories, err := h.Categories.ListCategories(member.LedgerID)
+	if err != nil {
+		h.replyError(msg, err)
+		return
+	}
+
+	category, ok := findCategory(categories, name)
+	if !ok {
+		h.reply(msg, i18n.Text(lang, "category.not_found", name), tbapi.InlineKeyboardMarkup{})
+		return
+	}
+
+	favorite, err := h.Categories.ToggleFavorite(conv.UserID, category.ID)
+	if err != nil {
+		h.replyError(msg, err)
+		return
+	}
+
+	key := "favorite.removed"
+	if favorite {
+		key = "favorite.added"
+	}
+	h.reply(msg, i18n.Text(lang, key, category.Emoji, category.Name), tbapi.InlineKeyboardMarkup{})
+}
+
+// rules lists the auto-categorization rules, or adds one with "add <keyword> <category>".
+// Keywords of several words go in double quotes.
+func (h *BotCommandHandler) rules(msg *tbapi.Message, args string) {
+	conv := ConversationOf(msg)
+	lang := h.StateManager.Language(conv.UserID)
+
+	if args != "" {
+		tokens, err := splitQuery(args)
+		if err != nil || len(tokens) < 3 || strings.ToLower(tokens[0]) != "add" {
+			h.reply(msg, i18n.Text(lang, "rules.usage"), tbapi.InlineKeyboardMarkup{})
+			return
+		}
+
+		category := strings.Join(tokens[2:], " ")
+		err = h.Rules.AddRule(conv, tokens[1], category)
+		switch {
+		case errors.Is(err, ErrPermissionDenied):
+			h.reply(msg, i18n.Text(lang, "ledger.read_only"), tbapi.InlineKeyboardMarkup{})
+			return
+		case errors.Is(err, ErrCategoryNotFound):
+			h.reply(msg, i18n.Text(lang, "category.not_found", category), tbapi.InlineKeyboardMarkup{})
+			return
+		case err != nil:
+			h.replyError(msg, err)
+			return
+		}
+	}
+
+	text, keyboard, err := h.Rules.List(lang, conv)
+	if err != nil {
+		h.replyError(msg, err)
+		return
+	}
+	h.reply(msg, text, keyboard)
+}
+
+// findCategory looks a category up by its name, in any case, or by its emoji.
+func findCategory(categories []storage.CategoryInfo, name string) (storage.CategoryInfo, bool) {
+	for _, c := range categories {
+		if strings.EqualFold(c.Name, name) || c.Emoji == name {
+			return c, true
+		}
+	}
+	return storage.CategoryInfo{}, false
+}
@@ -0,0 +1,148 @@
+package events
+
+import (
+	"fmt"
+	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/nyanyamaga/finance-tracker-bot/app/charts"
+	"github.com/nyanyamaga/finance-tracker-bot/app/i18n"
+	"github.com/nyanyamaga/finance-tracker-bot/app/keyboards"
+	"github.com/nyanyamaga/finance-tracker-bot/app/storage"
+	"strings"
+	"time"
+)
+
+// Chart renders a PNG chart of the conversation's ledger for the period along with its caption.
+// Category charts show the share of each category, trend charts show daily totals, or monthly ones for a year.
+func (r *BotReporter) Chart(lang string, conv Conversation, kind, period string) ([]byte, string, tbapi.InlineKeyboardMarkup, error) {
+	keyboard := r.TbKeyboards.GetChartKeyboard(lang, kind, period)
+
+	member, err := r.Ledgers.LedgerFor(conv)
+	if err != nil {
+		return nil, "", keyboard, err
+	}
+
+	ledger, err := r.Ledgers.Ledger(member.LedgerID)
+	if err != nil {
+		return nil, "", keyboard, err
+	}
+
+	from, to, title := chartPeriod(lang, period, time.Now())
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "📊 *%s* · %s · %s\n\n", i18n.Text(lang, "chart.kind_"+kind), title, ledger.Name)
+
+	var chart []byte
+	var total float64
+	switch kind {
+	case keyboards.ChartCategories:
+		totals, err := r.Spendings.TotalsByCategory(member.LedgerID, 0, from, to)
+		if err != nil {
+			return nil, "", keyboard, err
+		}
+		categories, err := r.Categories.ListCategories(member.LedgerID)
+		if err != nil {
+			return nil, "", keyboard, err
+		}
+		totals = groupSmallCategories(lang, rollUpTotals(totals, categories, 0), len(charts.Palette))
+
+		values := make([]float64, 0, len(totals))
+		for _, t := range totals {
+			values = append(values, t.Total)
+			total += t.Total
+		}
+		for i, t := range totals {
+			fmt.Fprintf(&sb, "%s %s %s — %.2f (%.0f%%)\n", charts.Markers[i], t.Emoji, t.Name, t.Total, t.Total/total*100)
+		}
+
+		if chart, err = charts.Pie(values); err != nil {
+			return nil, "", keyboard, err
+		}
+	case keyboards.ChartTrend:
+		monthly := period == keyboards.ChartYear
+		fetch := r.Spendings.TotalsByDay
+		if monthly {
+			fetch = r.Spendings.TotalsByMonth
+		}
+
+		totals, err := fetch(member.LedgerID, 0, from, to)
+		if err != nil {
+			return nil, "", keyboard, err
+		}
+
+		points := trendPoints(totals, from, to, monthly)
+		for _, p := range points {
+			total += p.Value
+		}
+
+		render := charts.Bars
+		if monthly {
+			render = charts.Line
+		}
+		if chart, err = render(points); err != nil {
+			return nil, "", keyboard, err
+		}
+	default:
+		return nil, "", keyboard, fmt.Errorf("unknown chart kind %q", kind)
+	}
+
+	if total == 0 {
+		sb.WriteString(i18n.Text(lang, "chart.empty"))
+	} else {
+		fmt.Fprintf(&sb, "\n*%s: %.2f*", i18n.Text(lang, "report.total"), total)
+	}
+	return chart, sb.String(), keyboard, nil
+}
+
+// chartPeriod returns the [from, to) range of a chart period ending today, and its title.
+func chartPeriod(lang, period string, now time.Time) (time.Time, time.Time, string) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+	switch period {
+	case keyboards.ChartWeek:
+		return today.AddDate(0, 0, -6), today.AddDate(0, 0, 1), i18n.Text(lang, "chart.title_week")
+	case keyboards.ChartYear:
+		return month.AddDate(0, -11, 0), month.AddDate(0, 1, 0), i18n.Text(lang, "chart.title_year")
+	default:
+		return month, month.AddDate(0, 1, 0), fmt.Sprintf("%s %d", i18n.MonthName(lang, month.Month()), month.Year())
+	}
+}
+
+// groupSmallCategories keeps the largest categories and sums the rest into a single "other" entry,
+// so the result has at most limit entries. Totals are expected to be sorted from the largest.
+func groupSmallCategories(lang string, totals []storage.CategoryTotal, limit int) []storage.CategoryTotal {
+	if len(totals) <= limit {
+		return totals
+	}
+
+	other := storage.CategoryTotal{Name: i18n.Text(lang, "chart.other")}
+	for _, t := range totals[limit-1:] {
+		other.Total += t.Total
+		other.Count += t.Count
+	}
+	return append(totals[:limit-1:limit-1], other)
+}
+
+// trendPoints turns period totals into chart points for every day or month in [from, to),
+// including the ones without spendings.
+func trendPoints(totals []storage.PeriodTotal, from, to time.Time, monthly bool) []charts.Point {
+	layout, step := keyboards.DateLayout, func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }
+	if monthly {
+		layout, step = keyboards.CalendarMonthLayout, func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }
+	}
+
+	byPeriod := make(map[string]float64, len(totals))
+	for _, t := range totals {
+		byPeriod[t.Period] = t.Total
+	}
+
+	var points []charts.Point
+	for t := from; t.Before(to); t = step(t) {
+		label := fmt.Sprint(t.Day())
+		if monthly {
+			label = fmt.Sprint(int(t.Month()))
+		}
+		points = append(points, charts.Point{Label: label, Value: byPeriod[t.Format(layout)]})
+	}
+	return points
+}
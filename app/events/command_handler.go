@@ -2,27 +2,435 @@ package events
 
 import (
 	"context"
+	"errors"
 	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/nyanyamaga/finance-tracker-bot/app/i18n"
+	"github.com/nyanyamaga/finance-tracker-bot/app/keyboards"
+	"github.com/nyanyamaga/finance-tracker-bot/app/storage"
 	"log"
+	"strconv"
+	"strings"
 )
 
+// joinPayloadPrefix marks /start deep-link payloads carrying a ledger invite code.
+const joinPayloadPrefix = "join_"
+
 type BotCommandHandler struct {
 	TbAPI        TbAPI
 	TbKeyboards  TbKeyboards
 	StateManager StateManager // Add StateManager to the command handler
+	Ledgers      LedgerManager
+	Categories   CategoriesRepository
+	Reporter     Reporter
+	Balances     BalanceManager
+	Budgets      BudgetManager
+	Settings     SettingsManager
+	Rules        RuleManager
+	Goals        GoalManager
+	Accounts     AccountManager
+	Admins       AdminManager
+	Access       AccessManager
+	BotUsername  string // Used to build deep links and to tell commands for other bots apart in groups
 }
 
 func (h *BotCommandHandler) HandleCommands(ctx context.Context, update tbapi.Update) {
-	userID := update.Message.From.ID
+	msg := update.Message
+	conv := ConversationOf(msg)
+	if conv.IsGroup() && !h.addressed(msg) {
+		return
+	}
+	args := strings.TrimSpace(msg.CommandArguments())
+	lang := h.StateManager.Language(conv.UserID)
+
+	h.StateManager.TrackMessage(conv, msg.MessageID)
+
+	command, ok := h.command(msg.Command())
+	if !ok {
+		// in groups the command may be meant for another bot
+		if !conv.IsGroup() {
+			h.reply(msg, i18n.Text(lang, "command.unknown"), tbapi.InlineKeyboardMarkup{})
+		}
+		return
+	}
+	if command.Admin && !h.Admins.IsAdmin(conv.UserID) {
+		h.reply(msg, i18n.Text(lang, "admin.denied"), tbapi.InlineKeyboardMarkup{})
+		return
+	}
+	if command.Usage != "" && args == "" {
+		h.reply(msg, i18n.Text(lang, command.Usage), tbapi.InlineKeyboardMarkup{})
+		return
+	}
+
+	command.Handle(ctx, msg, args)
+}
+
+// addressed reports whether the command is meant for this bot, naming no bot like /report or this one like /report@bot.
+func (h *BotCommandHandler) addressed(msg *tbapi.Message) bool {
+	_, botName, ok := strings.Cut(msg.CommandWithAt(), "@")
+	return !ok || h.BotUsername == "" || strings.EqualFold(botName, h.BotUsername)
+}
+
+// start greets the user with the main keyboard, joining a ledger first when opened from an invite link.
+func (h *BotCommandHandler) start(ctx context.Context, msg *tbapi.Message, args string) {
+	conv := ConversationOf(msg)
+	lang := h.StateManager.Language(conv.UserID)
+
+	if strings.HasPrefix(args, joinPayloadPrefix) {
+		h.joinLedger(msg, strings.TrimPrefix(args, joinPayloadPrefix))
+	}
+
+	h.StateManager.SetIdleState(ctx, conv)
+
+	welcome := tbapi.NewMessage(conv.ChatID, i18n.Text(lang, "start.welcome"))
+	keyboard := h.TbKeyboards.GetMainKeyboard(lang)
+	if conv.IsGroup() {
+		welcome.ReplyToMessageID = msg.MessageID
+		keyboard.Selective = true
+	}
+	welcome.ReplyMarkup = keyboard
+
+	if _, err := h.TbAPI.Send(welcome); err != nil {
+		log.Printf("[warn] error sending welcome message: %v", err)
+	}
+}
+
+// report shows the current month of the ledger by category.
+func (h *BotCommandHandler) report(msg *tbapi.Message) {
+	conv := ConversationOf(msg)
+	text, keyboard, err := h.Reporter.MonthlyReport(h.StateManager.Language(conv.UserID), conv, 0, 0)
+	if err != nil {
+		h.replyError(msg, err)
+		return
+	}
+	h.reply(msg, text, keyboard)
+}
+
+// compare shows this month against the previous ones by category.
+func (h *BotCommandHandler) compare(msg *tbapi.Message) {
+	conv := ConversationOf(msg)
+	text, err := h.Reporter.Compare(h.StateManager.Language(conv.UserID), conv)
+	if err != nil {
+		h.replyError(msg, err)
+		return
+	}
+	h.reply(msg, text, tbapi.InlineKeyboardMarkup{})
+}
 
-	if update.Message.Command() == "start" {
-		h.StateManager.SetIdleState(ctx, userID)
+// tags sums spendings per tag, or breaks a single tag down by category.
+func (h *BotCommandHandler) tags(msg *tbapi.Message, tag string) {
+	conv := ConversationOf(msg)
+	text, err := h.Reporter.Tags(h.StateManager.Language(conv.UserID), conv, tag)
+	if err != nil {
+		h.replyError(msg, err)
+		return
+	}
+	h.reply(msg, text, tbapi.InlineKeyboardMarkup{})
+}
+
+// history lists the latest spendings of the ledger this month.
+func (h *BotCommandHandler) history(msg *tbapi.Message) {
+	conv := ConversationOf(msg)
+	text, keyboard, err := h.Reporter.History(h.StateManager.Language(conv.UserID), conv, keyboards.ChartMonth, keyboards.HistoryEveryone, storage.SpendingCursor{})
+	if err != nil {
+		h.replyError(msg, err)
+		return
+	}
+	h.reply(msg, text, keyboard)
+}
+
+// settings shows the user's settings with buttons to change them.
+func (h *BotCommandHandler) settings(msg *tbapi.Message) {
+	conv := ConversationOf(msg)
+	lang := h.StateManager.Language(conv.UserID)
+
+	settings, err := h.Settings.Settings(conv.UserID)
+	if err != nil {
+		h.replyError(msg, err)
+		return
+	}
+	h.reply(msg, i18n.Text(lang, "settings.title"), h.TbKeyboards.GetSettingsKeyboard(lang, *settings))
+}
+
+// balances shows who owes whom in the ledger along with the transfers to settle up.
+func (h *BotCommandHandler) balances(msg *tbapi.Message) {
+	conv := ConversationOf(msg)
+	text, keyboard, err := h.Balances.Balances(h.StateManager.Language(conv.UserID), conv)
+	if err != nil {
+		h.replyError(msg, err)
+		return
+	}
+	h.reply(msg, text, keyboard)
+}
+
+// reply answers a command with a markdown message and an optional inline keyboard.
+// In groups the answer is a reply to the command, so it's clear whom the bot talks to.
+func (h *BotCommandHandler) reply(msg *tbapi.Message, text string, keyboard tbapi.InlineKeyboardMarkup) {
+	tbMsg := tbapi.NewMessage(msg.Chat.ID, text)
+	if len(keyboard.InlineKeyboard) > 0 {
+		tbMsg.ReplyMarkup = keyboard
+	}
+	if ConversationOf(msg).IsGroup() {
+		tbMsg.ReplyToMessageID = msg.MessageID
+	}
+
+	if err := send(tbMsg, h.TbAPI); err != nil {
+		log.Printf("[warn] error sending command reply: %v", err)
+	}
+}
+
+// chart sends the category chart of the current month, the keyboard switches it to other charts.
+func (h *BotCommandHandler) chart(msg *tbapi.Message) {
+	conv := ConversationOf(msg)
+	lang := h.StateManager.Language(conv.UserID)
+
+	chart, caption, keyboard, err := h.Reporter.Chart(lang, conv, keyboards.ChartCategories, keyboards.ChartMonth)
+	if err != nil {
+		h.replyError(msg, err)
+		return
+	}
+
+	photo := tbapi.NewPhoto(conv.ChatID, tbapi.FileBytes{Name: "chart.png", Bytes: chart})
+	photo.Caption = caption
+	photo.ReplyMarkup = keyboard
+	if conv.IsGroup() {
+		photo.ReplyToMessageID = msg.MessageID
+	}
+
+	if err = send(photo, h.TbAPI); err != nil {
+		log.Printf("[warn] error sending chart: %v", err)
+	}
+}
 
-		msg := tbapi.NewMessage(update.Message.Chat.ID, "Welcome! Choose an option.")
-		msg.ReplyMarkup = h.TbKeyboards.GetMainKeyboard()
+// find lists the first page of spendings matching the query, or explains the query syntax.
+func (h *BotCommandHandler) find(msg *tbapi.Message, query string) {
+	conv := ConversationOf(msg)
+	lang := h.StateManager.Language(conv.UserID)
 
-		if _, err := h.TbAPI.Send(msg); err != nil {
-			log.Printf("[warn] error sending welcome message: %v", err)
+	text, keyboard, err := h.Reporter.Find(lang, conv, query, 0)
+	if errors.Is(err, ErrInvalidQuery) {
+		h.reply(msg, i18n.Text(lang, "find.usage"), tbapi.InlineKeyboardMarkup{})
+		return
+	}
+	if err != nil {
+		h.replyError(msg, err)
+		return
+	}
+	h.reply(msg, text, keyboard)
+}
+
+// export sends the ledger's spendings as a CSV file, limited to a tag when one is given.
+func (h *BotCommandHandler) export(msg *tbapi.Message, tag string) {
+	conv := ConversationOf(msg)
+
+	data, err := h.Reporter.Export(conv, tag)
+	if err != nil {
+		h.replyError(msg, err)
+		return
+	}
+
+	name := "spendings.csv"
+	if tag = strings.ToLower(strings.TrimPrefix(tag, "#")); tag != "" {
+		name = "spendings-" + tag + ".csv"
+	}
+	doc := tbapi.NewDocument(conv.ChatID, tbapi.FileBytes{Name: name, Bytes: data})
+	if conv.IsGroup() {
+		doc.ReplyToMessageID = msg.MessageID
+	}
+
+	if err = send(doc, h.TbAPI); err != nil {
+		log.Printf("[warn] error sending export: %v", err)
+	}
+}
+
+// budget shows the envelopes of the ledger, or sets a budget when called with an amount and an optional category.
+// "rollover [category]" switches carrying over the unspent amount, "cover <category> from <category>"
+// pulls overspending from another envelope.
+func (h *BotCommandHandler) budget(msg *tbapi.Message, args string) {
+	conv := ConversationOf(msg)
+	lang := h.StateManager.Language(conv.UserID)
+
+	var notice string
+	if args != "" {
+		action, rest, _ := strings.Cut(args, " ")
+		rest = strings.TrimSpace(rest)
+
+		var (
+			categories = rest // named in the not found message
+			err        error
+		)
+		switch strings.ToLower(action) {
+		case "rollover":
+			var rollover bool
+			if rollover, err = h.Budgets.ToggleRollover(conv, rest); err == nil {
+				notice = i18n.Text(lang, "budget.rollover_off")
+				if rollover {
+					notice = i18n.Text(lang, "budget.rollover_on")
+				}
+			}
+		case "cover":
+			category, source, ok := cutSource(rest)
+			if !ok {
+				h.reply(msg, i18n.Text(lang, "budget.usage"), tbapi.InlineKeyboardMarkup{})
+				return
+			}
+			categories = category + ", " + source
+			var amount float64
+			if amount, err = h.Budgets.Cover(conv, category, source); err == nil {
+				notice = i18n.Text(lang, "budget.covered", amount)
+			}
+		default:
+			amount, parseErr := strconv.ParseFloat(strings.ReplaceAll(action, ",", "."), 64)
+			if parseErr != nil || amount < 0 {
+				h.reply(msg, i18n.Text(lang, "budget.usage"), tbapi.InlineKeyboardMarkup{})
+				return
+			}
+			err = h.Budgets.SetBudget(conv, rest, amount)
+		}
+
+		switch {
+		case errors.Is(err, ErrPermissionDenied):
+			h.reply(msg, i18n.Text(lang, "ledger.read_only"), tbapi.InlineKeyboardMarkup{})
+			return
+		case errors.Is(err, ErrCategoryNotFound):
+			h.reply(msg, i18n.Text(lang, "category.not_found", categories), tbapi.InlineKeyboardMarkup{})
+			return
+		case errors.Is(err, ErrBudgetNotFound):
+			h.reply(msg, i18n.Text(lang, "budget.not_found"), tbapi.InlineKeyboardMarkup{})
+			return
+		case errors.Is(err, ErrNotOverspent):
+			h.reply(msg, i18n.Text(lang, "budget.not_overspent"), tbapi.InlineKeyboardMarkup{})
+			return
+		case errors.Is(err, ErrEnvelopeEmpty):
+			h.reply(msg, i18n.Text(lang, "budget.envelope_empty"), tbapi.InlineKeyboardMarkup{})
+			return
+		case err != nil:
+			h.replyError(msg, err)
+			return
+		}
+
+	}
+
+	text, err := h.Budgets.Status(lang, conv)
+	if err != nil {
+		h.replyError(msg, err)
+		return
+	}
+	if notice != "" {
+		text = notice + "\n\n" + text
+	}
+	h.reply(msg, text, tbapi.InlineKeyboardMarkup{})
+}
+
+// cutSource splits "<category> from <source>" arguments, the separator may be in English or Russian.
+func cutSource(args string) (category, source string, ok bool) {
+	for _, sep := range []string{" from ", " из "} {
+		if category, source, ok = strings.Cut(args, sep); ok {
+			category, source = strings.TrimSpace(category), strings.TrimSpace(source)
+			return category, source, category != "" && source != ""
+		}
+	}
+	return "", "", false
+}
+
+// goals shows the goals of the ledger, or adds one when called with "add" and the goal details.
+func (h *BotCommandHandler) goals(msg *tbapi.Message, args string) {
+	conv := ConversationOf(msg)
+	lang := h.StateManager.Language(conv.UserID)
+
+	if args != "" {
+		action, details, _ := strings.Cut(args, " ")
+		if !strings.EqualFold(action, "add") {
+			h.reply(msg, i18n.Text(lang, "goals.usage"), tbapi.InlineKeyboardMarkup{})
+			return
+		}
+
+		_, err := h.Goals.AddGoal(conv, details)
+		switch {
+		case errors.Is(err, ErrInvalidGoal):
+			h.reply(msg, i18n.Text(lang, "goals.invalid"), tbapi.InlineKeyboardMarkup{})
+			return
+		case errors.Is(err, ErrPermissionDenied):
+			h.reply(msg, i18n.Text(lang, "ledger.read_only"), tbapi.InlineKeyboardMarkup{})
+			return
+		case err != nil:
+			h.replyError(msg, err)
+			return
+		}
+	}
+
+	text, keyboard, err := h.Goals.List(lang, conv)
+	if err != nil {
+		h.replyError(msg, err)
+		return
+	}
+	h.reply(msg, text, keyboard)
+}
+
+// accounts shows the accounts of the ledger with their balances, or adds one when called with "add" and the account details.
+func (h *BotCommandHandler) accounts(msg *tbapi.Message, args string) {
+	conv := ConversationOf(msg)
+	lang := h.StateManager.Language(conv.UserID)
+
+	if args != "" {
+		action, details, _ := strings.Cut(args, " ")
+		if !strings.EqualFold(action, "add") {
+			h.reply(msg, i18n.Text(lang, "accounts.usage"), tbapi.InlineKeyboardMarkup{})
+			return
 		}
+
+		_, err := h.Accounts.AddAccount(conv, details)
+		switch {
+		case errors.Is(err, ErrInvalidAccount):
+			h.reply(msg, i18n.Text(lang, "accounts.usage"), tbapi.InlineKeyboardMarkup{})
+			return
+		case errors.Is(err, ErrPermissionDenied):
+			h.reply(msg, i18n.Text(lang, "ledger.read_only"), tbapi.InlineKeyboardMarkup{})
+			return
+		case err != nil:
+			h.replyError(msg, err)
+			return
+		}
+	}
+
+	text, err := h.Accounts.List(lang, conv)
+	if err != nil {
+		h.replyError(msg, err)
+		return
 	}
+	if text == "" {
+		text = i18n.Text(lang, "accounts.empty") + "\n"
+	}
+	h.reply(msg, text+"\n"+i18n.Text(lang, "accounts.usage"), tbapi.InlineKeyboardMarkup{})
+}
+
+// transfer moves money between two accounts of the ledger and shows the updated balances.
+func (h *BotCommandHandler) transfer(msg *tbapi.Message, args string) {
+	conv := ConversationOf(msg)
+	lang := h.StateManager.Language(conv.UserID)
+
+	_, err := h.Accounts.Transfer(conv, args)
+	switch {
+	case errors.Is(err, ErrInvalidTransfer):
+		h.reply(msg, i18n.Text(lang, "transfer.usage"), tbapi.InlineKeyboardMarkup{})
+		return
+	case errors.Is(err, ErrPermissionDenied):
+		h.reply(msg, i18n.Text(lang, "ledger.read_only"), tbapi.InlineKeyboardMarkup{})
+		return
+	case err != nil:
+		h.replyError(msg, err)
+		return
+	}
+
+	text, err := h.Accounts.List(lang, conv)
+	if err != nil {
+		h.replyError(msg, err)
+		return
+	}
+	h.reply(msg, i18n.Text(lang, "transfer.done")+"\n\n"+text, tbapi.InlineKeyboardMarkup{})
+}
+
+// replyError logs a failed command and lets the user know something went wrong.
+func (h *BotCommandHandler) replyError(msg *tbapi.Message, err error) {
+	log.Printf("[error] error handling command %s of %v: %v", msg.Command(), ConversationOf(msg), err)
+	h.reply(msg, i18n.Text(h.StateManager.Language(msg.From.ID), "error.generic"), tbapi.InlineKeyboardMarkup{})
 }
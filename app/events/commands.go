@@ -0,0 +1,219 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/nyanyamaga/finance-tracker-bot/app/i18n"
+	"log"
+	"strings"
+)
+
+// botCommand is a command of the bot: what it takes, how it's described and how it's handled.
+// Descriptions are the "command.<name>" texts.
+type botCommand struct {
+	Name   string
+	Args   string // Argument syntax listed in /help, empty for commands without arguments
+	Usage  string // Text answered instead of running the command without arguments, empty when they're optional
+	Admin  bool   // Only admins may run it, others don't see it in the menu and in /help
+	Handle func(ctx context.Context, msg *tbapi.Message, args string)
+}
+
+// commands returns the commands of the bot in the order they're listed in the menu and in /help.
+func (h *BotCommandHandler) commands() []botCommand {
+	noArgs := func(handle func(msg *tbapi.Message)) func(context.Context, *tbapi.Message, string) {
+		return func(_ context.Context, msg *tbapi.Message, _ string) { handle(msg) }
+	}
+	withArgs := func(handle func(msg *tbapi.Message, args string)) func(context.Context, *tbapi.Message, string) {
+		return func(_ context.Context, msg *tbapi.Message, args string) { handle(msg, args) }
+	}
+
+	return []botCommand{
+		{Name: "start", Handle: h.start},
+		{Name: "help", Handle: noArgs(h.help)},
+		{Name: "report", Handle: noArgs(h.report)},
+		{Name: "compare", Handle: noArgs(h.compare)},
+		{Name: "chart", Handle: noArgs(h.chart)},
+		{Name: "history", Handle: noArgs(h.history)},
+		{Name: "find", Args: "<query>", Usage: "find.usage", Handle: withArgs(h.find)},
+		{Name: "tags", Args: "[#tag]", Handle: withArgs(h.tags)},
+		{Name: "export", Args: "[#tag]", Handle: withArgs(h.export)},
+		{Name: "budget", Args: "[amount] [category]", Handle: withArgs(h.budget)},
+		{Name: "goals", Args: "[add <details>]", Handle: withArgs(h.goals)},
+		{Name: "accounts", Args: "[add <details>]", Handle: withArgs(h.accounts)},
+		{Name: "transfer", Args: "<amount> <from> <to>", Usage: "transfer.usage", Handle: withArgs(h.transfer)},
+		{Name: "balances", Handle: noArgs(h.balances)},
+		{Name: "rules", Args: "[add <keyword> <category>]", Handle: withArgs(h.rules)},
+		{Name: "favorite", Args: "<category>", Usage: "favorite.usage", Handle: withArgs(h.toggleFavorite)},
+		{Name: "ledgers", Handle: noArgs(h.listLedgers)},
+		{Name: "newledger", Args: "<name>", Usage: "ledger.new_usage", Handle: withArgs(h.createLedger)},
+		{Name: "invite", Args: "[editor|viewer]", Handle: withArgs(h.invite)},
+		{Name: "members", Handle: noArgs(h.listMembers)},
+		{Name: "remind", Args: "[HH:MM|off|quiet HH:MM-HH:MM]", Handle: withArgs(h.remind)},
+		{Name: "timezone", Args: "<zone>", Usage: "timezone.usage", Handle: withArgs(h.timezone)},
+		{Name: "settings", Handle: noArgs(h.settings)},
+		{Name: "language", Handle: noArgs(h.language)},
+		{Name: "admin", Args: "[broadcast <text>]", Admin: true, Handle: h.admin},
+		{Name: "access", Args: "[approve|revoke <id|@username>|invite]", Admin: true, Handle: withArgs(h.access)},
+	}
+}
+
+// command looks up a command by its name.
+func (h *BotCommandHandler) command(name string) (botCommand, bool) {
+	for _, c := range h.commands() {
+		if c.Name == strings.ToLower(name) {
+			return c, true
+		}
+	}
+	return botCommand{}, false
+}
+
+// RegisterCommands sets the command menu of the bot in every supported language,
+// the default language doubling as the menu of users with other languages.
+// Admins get a menu of their own with the admin commands added.
+func (h *BotCommandHandler) RegisterCommands(adminIDs []int64) error {
+	for _, lang := range append([]string{""}, i18n.Languages()...) {
+		config := tbapi.NewSetMyCommandsWithScopeAndLanguage(tbapi.NewBotCommandScopeDefault(), lang, h.menu(lang, false)...)
+		if _, err := h.TbAPI.Request(config); err != nil {
+			return fmt.Errorf("failed to register commands for language %q: %w", lang, err)
+		}
+	}
+
+	for _, adminID := range adminIDs {
+		config := tbapi.NewSetMyCommandsWithScope(tbapi.NewBotCommandScopeChat(adminID), h.menu(i18n.DefaultLanguage, true)...)
+		if _, err := h.TbAPI.Request(config); err != nil {
+			return fmt.Errorf("failed to register admin commands for user %d: %w", adminID, err)
+		}
+	}
+	return nil
+}
+
+// menu returns the commands to register with Telegram, with or without the admin commands.
+func (h *BotCommandHandler) menu(lang string, admin bool) []tbapi.BotCommand {
+	var commands []tbapi.BotCommand
+	for _, c := range h.commands() {
+		if c.Admin && !admin {
+			continue
+		}
+		commands = append(commands, tbapi.BotCommand{Command: c.Name, Description: i18n.Text(lang, "command."+c.Name)})
+	}
+	return commands
+}
+
+// help lists the commands with their arguments and descriptions.
+func (h *BotCommandHandler) help(msg *tbapi.Message) {
+	lang := h.StateManager.Language(msg.From.ID)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "*%s*\n\n", i18n.Text(lang, "help.title"))
+	admin := h.Admins.IsAdmin(msg.From.ID)
+	for _, c := range h.commands() {
+		if c.Admin && !admin {
+			continue
+		}
+		usage := "/" + c.Name
+		if c.Args != "" {
+			usage += " " + c.Args
+		}
+		fmt.Fprintf(&sb, "`%s` — %s\n", usage, i18n.Text(lang, "command."+c.Name))
+	}
+	sb.WriteString("\n" + i18n.Text(lang, "help.footer"))
+
+	h.reply(msg, sb.String(), tbapi.InlineKeyboardMarkup{})
+}
+
+// language offers to switch the language of the bot.
+func (h *BotCommandHandler) language(msg *tbapi.Message) {
+	h.reply(msg, i18n.Text(h.StateManager.Language(msg.From.ID), "language.prompt"), h.TbKeyboards.GetLanguageKeyboard())
+}
+
+// admin shows usage statistics of the bot, or sends a message to every user with "broadcast <text>".
+// Broadcasts take a while, so they run in the background and report back when done.
+func (h *BotCommandHandler) admin(ctx context.Context, msg *tbapi.Message, args string) {
+	lang := h.StateManager.Language(msg.From.ID)
+
+	if args == "" {
+		text, err := h.Admins.Stats(lang)
+		if err != nil {
+			h.replyError(msg, err)
+			return
+		}
+		h.reply(msg, text, tbapi.InlineKeyboardMarkup{})
+		return
+	}
+
+	action, text, _ := strings.Cut(args, " ")
+	if text = strings.TrimSpace(text); !strings.EqualFold(action, "broadcast") || text == "" {
+		h.reply(msg, i18n.Text(lang, "admin.usage"), tbapi.InlineKeyboardMarkup{})
+		return
+	}
+
+	h.reply(msg, i18n.Text(lang, "admin.broadcast_started"), tbapi.InlineKeyboardMarkup{})
+	go func() {
+		// only the language read above is used here, the state manager isn't safe to share with the listener
+		sent, failed, err := h.Admins.Broadcast(ctx, text)
+		if err != nil {
+			log.Printf("[error] error broadcasting a message: %v", err)
+			h.reply(msg, i18n.Text(lang, "error.generic"), tbapi.InlineKeyboardMarkup{})
+			return
+		}
+		h.reply(msg, i18n.Text(lang, "admin.broadcast_done", sent, failed), tbapi.InlineKeyboardMarkup{})
+	}()
+}
+
+// access lists who asked for access to the bot, approves or revokes a user, or creates an invite link.
+func (h *BotCommandHandler) access(msg *tbapi.Message, args string) {
+	lang := h.StateManager.Language(msg.From.ID)
+
+	action, who, _ := strings.Cut(args, " ")
+	who = strings.TrimSpace(who)
+
+	var notice string
+	switch strings.ToLower(action) {
+	case "":
+	case "invite":
+		link, err := h.Access.CreateInvite(msg.From.ID)
+		if err != nil {
+			h.replyError(msg, err)
+			return
+		}
+		h.reply(msg, i18n.Text(lang, "access.invite", link), tbapi.InlineKeyboardMarkup{})
+		return
+	case "approve", "revoke":
+		if who == "" {
+			h.reply(msg, i18n.Text(lang, "access.usage"), tbapi.InlineKeyboardMarkup{})
+			return
+		}
+
+		var err error
+		if strings.EqualFold(action, "approve") {
+			_, err = h.Access.Approve(who)
+			notice = i18n.Text(lang, "access.approved_by_admin", who)
+		} else {
+			_, err = h.Access.Revoke(who)
+			notice = i18n.Text(lang, "access.revoked_by_admin", who)
+		}
+		switch {
+		case errors.Is(err, ErrUserNotFound):
+			h.reply(msg, i18n.Text(lang, "access.user_not_found", who), tbapi.InlineKeyboardMarkup{})
+			return
+		case err != nil:
+			h.replyError(msg, err)
+			return
+		}
+	default:
+		h.reply(msg, i18n.Text(lang, "access.usage"), tbapi.InlineKeyboardMarkup{})
+		return
+	}
+
+	text, err := h.Access.List(lang)
+	if err != nil {
+		h.replyError(msg, err)
+		return
+	}
+	if notice != "" {
+		text = notice + "\n\n" + text
+	}
+	h.reply(msg, text, tbapi.InlineKeyboardMarkup{})
+}
@@ -0,0 +1,118 @@
+package events
+
+import (
+	"fmt"
+	"github.com/nyanyamaga/finance-tracker-bot/app/i18n"
+	"github.com/nyanyamaga/finance-tracker-bot/app/storage"
+	"sort"
+	"strings"
+	"time"
+)
+
+// compareAverageMonths is how many months before the current one are averaged.
+const compareAverageMonths = 3
+
+// Compare shows this month's spendings of the conversation's ledger per category against the same days
+// of last month and the average of the same days of the three months before, so a half month isn't compared
+// to a whole one. Sub-category totals are rolled up into their parents.
+func (r *BotReporter) Compare(lang string, conv Conversation) (string, error) {
+	member, err := r.Ledgers.LedgerFor(conv)
+	if err != nil {
+		return "", err
+	}
+
+	categories, err := r.Categories.ListCategories(member.LedgerID)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	from := monthStart(now)
+	elapsed := now.Sub(from)
+
+	// periods[0] is this month so far, periods[i] the same days i months before
+	periods := make([]map[int64]storage.CategoryTotal, compareAverageMonths+1)
+	for i := range periods {
+		start := from.AddDate(0, -i, 0)
+		end := start.Add(elapsed)
+		if next := start.AddDate(0, 1, 0); end.After(next) {
+			end = next // the 31st of this month is compared to the end of a shorter month
+		}
+
+		totals, err := r.Spendings.TotalsByCategory(member.LedgerID, 0, start, end)
+		if err != nil {
+			return "", err
+		}
+
+		periods[i] = make(map[int64]storage.CategoryTotal)
+		for _, t := range rollUpTotals(totals, categories, 0) {
+			periods[i][t.CategoryID] = t
+		}
+	}
+
+	rows := make(map[int64]storage.CategoryTotal)
+	for _, period := range periods {
+		for categoryID, t := range period {
+			if _, ok := rows[categoryID]; !ok {
+				rows[categoryID] = t
+			}
+		}
+	}
+	order := make([]storage.CategoryTotal, 0, len(rows))
+	for _, t := range rows {
+		order = append(order, t)
+	}
+	sort.Slice(order, func(i, j int) bool {
+		ti, tj := periods[0][order[i].CategoryID].Total, periods[0][order[j].CategoryID].Total
+		if ti != tj {
+			return ti > tj
+		}
+		return order[i].Name < order[j].Name
+	})
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "📈 *%s*\n", i18n.Text(lang, "compare.title", from.Format("02.01"), now.Format("02.01")))
+	sb.WriteString("_" + i18n.Text(lang, "compare.legend") + "_\n\n")
+	if len(order) == 0 {
+		sb.WriteString(i18n.Text(lang, "report.empty"))
+		return sb.String(), nil
+	}
+
+	var current, previous, average float64
+	for _, t := range order {
+		cur, prev, avg := periods[0][t.CategoryID].Total, periods[1][t.CategoryID].Total, 0.0
+		for _, period := range periods[1:] {
+			avg += period[t.CategoryID].Total / compareAverageMonths
+		}
+		current, previous, average = current+cur, previous+prev, average+avg
+		fmt.Fprintf(&sb, "%s %s — %s\n", t.Emoji, t.Name, compareLine(lang, cur, prev, avg))
+	}
+	fmt.Fprintf(&sb, "\n*%s* — %s", i18n.Text(lang, "report.total"), compareLine(lang, current, previous, average))
+
+	return sb.String(), nil
+}
+
+// compareLine formats a current amount with the changes against the previous month and the average.
+func compareLine(lang string, current, previous, average float64) string {
+	return fmt.Sprintf("%.2f · %.2f %s · ⌀ %.2f %s", current, previous, change(lang, current, previous), average, change(lang, current, average))
+}
+
+// change formats the relative change from base to value, with an arrow for notable changes.
+func change(lang string, value, base float64) string {
+	if toCents(base) == 0 {
+		if toCents(value) == 0 {
+			return "(—)"
+		}
+		return "(" + i18n.Text(lang, "compare.new") + ")"
+	}
+
+	pct := (value - base) / base * 100
+	switch {
+	case pct >= 10:
+		return fmt.Sprintf("(↑%.0f%%)", pct)
+	case pct <= -10:
+		return fmt.Sprintf("(↓%.0f%%)", -pct)
+	default:
+		return fmt.Sprintf("(%+.0f%%)", pct)
+	}
+}
@@ -0,0 +1,167 @@
+package events
+
+import (
+	"fmt"
+	"github.com/nyanyamaga/finance-tracker-bot/app/keyboards"
+	"strings"
+	"time"
+)
+
+var weekdayNames = map[string]time.Weekday{
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tues": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thurs": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"пн": time.Monday, "понедельник": time.Monday,
+	"вт": time.Tuesday, "вторник": time.Tuesday,
+	"ср": time.Wednesday, "среда": time.Wednesday,
+	"чт": time.Thursday, "четверг": time.Thursday,
+	"пт": time.Friday, "пятница": time.Friday,
+	"сб": time.Saturday, "суббота": time.Saturday,
+	"вс": time.Sunday, "воскресенье": time.Sunday,
+}
+
+var monthNames = map[string]time.Month{
+	"jan": time.January, "january": time.January,
+	"feb": time.February, "february": time.February,
+	"mar": time.March, "march": time.March,
+	"apr": time.April, "april": time.April,
+	"may": time.May,
+	"jun": time.June, "june": time.June,
+	"jul": time.July, "july": time.July,
+	"aug": time.August, "august": time.August,
+	"sep": time.September, "sept": time.September, "september": time.September,
+	"oct": time.October, "october": time.October,
+	"nov": time.November, "november": time.November,
+	"dec": time.December, "december": time.December,
+	"январь": time.January, "января": time.January,
+	"февраль": time.February, "февраля": time.February,
+	"март": time.March, "марта": time.March,
+	"апрель": time.April, "апреля": time.April,
+	"май": time.May, "мая": time.May,
+	"июнь": time.June, "июня": time.June,
+	"июль": time.July, "июля": time.July,
+	"август": time.August, "августа": time.August,
+	"сентябрь": time.September, "сентября": time.September,
+	"октябрь": time.October, "октября": time.October,
+	"ноябрь": time.November, "ноября": time.November,
+	"декабрь": time.December, "декабря": time.December,
+}
+
+// parseSpendingDate resolves a date picked from the date keyboard or typed by the user
+// (today, yesterday, weekday names, 12.03, 12.03.2026, 2026-03-12) relative to now.
+// Weekdays and day.month dates resolve to the latest matching day that is not in the future.
+// The returned time keeps the clock time of now, so spendings of one day stay ordered.
+func parseSpendingDate(input string, now time.Time) (time.Time, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	day, err := parseDay(strings.ToLower(strings.TrimSpace(input)), today)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if day.After(today) {
+		return time.Time{}, fmt.Errorf("date %s is in the future", day.Format(keyboards.DateLayout))
+	}
+
+	return time.Date(day.Year(), day.Month(), day.Day(), now.Hour(), now.Minute(), now.Second(), 0, now.Location()), nil
+}
+
+// cutSpendingDate takes a date word, like "yesterday", "fri" or "12.03", off the end or the start of a description
+// and resolves it the way parseSpendingDate does. It reports false when the description has no date word.
+func cutSpendingDate(description string, now time.Time) (time.Time, string, bool) {
+	words := strings.Fields(description)
+	if len(words) == 0 {
+		return time.Time{}, description, false
+	}
+	if date, err := parseSpendingDate(words[len(words)-1], now); err == nil {
+		return date, strings.Join(words[:len(words)-1], " "), true
+	}
+	if date, err := parseSpendingDate(words[0], now); err == nil {
+		return date, strings.Join(words[1:], " "), true
+	}
+	return time.Time{}, description, false
+}
+
+func parseDay(text string, today time.Time) (time.Time, error) {
+	switch text {
+	case "", "today", "сегодня", keyboards.DateToday:
+		return today, nil
+	case "yesterday", "вчера", keyboards.DateYesterday:
+		return today.AddDate(0, 0, -1), nil
+	}
+
+	if weekday, ok := weekdayNames[text]; ok {
+		diff := (int(today.Weekday()) - int(weekday) + 7) % 7
+		return today.AddDate(0, 0, -diff), nil
+	}
+
+	text = strings.TrimPrefix(text, keyboards.DatePrefix)
+	for _, layout := range []string{keyboards.DateLayout, "02.01.2006", "2.1.2006", "02/01/2006"} {
+		if t, err := time.ParseInLocation(layout, text, today.Location()); err == nil {
+			return t, nil
+		}
+	}
+
+	for _, layout := range []string{"02.01", "2.1", "02/01"} {
+		if t, err := time.ParseInLocation(layout, text, today.Location()); err == nil {
+			t = time.Date(today.Year(), t.Month(), t.Day(), 0, 0, 0, 0, today.Location())
+			if t.After(today) {
+				t = t.AddDate(-1, 0, 0)
+			}
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognized date %q", text)
+}
+
+// parseDeadline resolves a goal deadline (June, 30.06, 30.06.2027, 06.2027, 2027-06-30) relative to now.
+// Months mean their last day, months and day.month dates resolve to the nearest matching day that is not in the past.
+func parseDeadline(input string, now time.Time) (time.Time, error) {
+	text := strings.ToLower(strings.TrimSpace(input))
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	endOfMonth := func(year int, month time.Month) time.Time {
+		return time.Date(year, month+1, 0, 0, 0, 0, 0, now.Location())
+	}
+
+	deadline := time.Time{}
+	if month, ok := monthNames[text]; ok {
+		deadline = endOfMonth(today.Year(), month)
+		if deadline.Before(today) {
+			deadline = endOfMonth(today.Year()+1, month)
+		}
+	}
+
+	for _, layout := range []string{keyboards.DateLayout, "02.01.2006", "2.1.2006", "02/01/2006"} {
+		if t, err := time.ParseInLocation(layout, text, now.Location()); deadline.IsZero() && err == nil {
+			deadline = t
+		}
+	}
+
+	for _, layout := range []string{"01.2006", "1.2006", "01/2006"} {
+		if t, err := time.ParseInLocation(layout, text, now.Location()); deadline.IsZero() && err == nil {
+			deadline = endOfMonth(t.Year(), t.Month())
+		}
+	}
+
+	for _, layout := range []string{"02.01", "2.1", "02/01"} {
+		if t, err := time.ParseInLocation(layout, text, now.Location()); deadline.IsZero() && err == nil {
+			deadline = time.Date(today.Year(), t.Month(), t.Day(), 0, 0, 0, 0, now.Location())
+			if deadline.Before(today) {
+				deadline = deadline.AddDate(1, 0, 0)
+			}
+		}
+	}
+
+	if deadline.IsZero() {
+		return time.Time{}, fmt.Errorf("unrecognized deadline %q", input)
+	}
+	if deadline.Before(today) {
+		return time.Time{}, fmt.Errorf("deadline %s is in the past", deadline.Format(keyboards.DateLayout))
+	}
+	return deadline, nil
+}
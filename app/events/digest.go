@@ -0,0 +1,273 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/nyanyamaga/finance-tracker-bot/app/i18n"
+	"github.com/nyanyamaga/finance-tracker-bot/app/storage"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Digest scheduling defaults
+const (
+	digestHour          = 9 // digests go out from 9:00 on Monday and the first day of a month
+	digestCheckInterval = 10 * time.Minute
+	digestRetries       = 3
+	digestRetryDelay    = 5 * time.Second
+	digestTopCount      = 3 // categories and single spendings listed in a digest
+)
+
+// BotDigestScheduler sends opted-in users a summary of the last week or month.
+// It reads storages directly instead of going through the managers, as it runs alongside the listener.
+type BotDigestScheduler struct {
+	TbAPI        TbAPI
+	UserSettings UserSettingsRepository
+	Digests      DigestsRepository
+	Ledgers      LedgersRepository
+	Categories   CategoriesRepository
+	Spendings    SpendingsRepository
+	Budgets      BudgetsRepository
+}
+
+// Run sends due digests periodically until the context is canceled.
+func (s *BotDigestScheduler) Run(ctx context.Context) {
+	log.Printf("[info] started digest scheduler")
+
+	ticker := time.NewTicker(digestCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		s.SendDue(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SendDue sends every digest due at the given time which wasn't sent for the current period yet.
+func (s *BotDigestScheduler) SendDue(ctx context.Context, now time.Time) {
+	subscribers, err := s.UserSettings.ListDigestSubscribers()
+	if err != nil {
+		log.Printf("[error] error listing digest subscribers: %v", err)
+		return
+	}
+
+	for _, settings := range subscribers {
+		for kind, enabled := range map[string]bool{storage.DigestWeekly: settings.WeeklyDigest, storage.DigestMonthly: settings.MonthlyDigest} {
+			if !enabled || ctx.Err() != nil {
+				continue
+			}
+
+			if err := s.sendIfDue(ctx, settings, kind, now); err != nil {
+				log.Printf("[error] error sending %s digest to user %d: %v", kind, settings.UserID, err)
+			}
+		}
+	}
+}
+
+func (s *BotDigestScheduler) sendIfDue(ctx context.Context, settings storage.UserSettingsInfo, kind string, now time.Time) error {
+	from, to := digestPeriod(kind, now)
+	if now.Before(to.Add(digestHour * time.Hour)) {
+		return nil
+	}
+
+	lastSent, err := s.Digests.LastSent(settings.UserID, kind)
+	if err != nil {
+		return err
+	}
+	if !lastSent.Before(to) {
+		return nil
+	}
+
+	member, err := s.Ledgers.GetMember(settings.LedgerID, settings.UserID)
+	if err != nil {
+		return fmt.Errorf("no active ledger: %w", err)
+	}
+
+	text, err := s.digest(i18n.Normalize(settings.Language), kind, member.LedgerID, from, to)
+	if err != nil {
+		return err
+	}
+
+	if err = s.sendWithRetry(ctx, tbapi.NewMessage(settings.UserID, text)); err != nil {
+		if !undeliverable(err) {
+			return err
+		}
+		// retrying every check for the rest of the period won't help, e.g. when the user blocked the bot
+		log.Printf("[info] %s digest can't reach user %d, skipping the period: %v", kind, settings.UserID, err)
+		return s.Digests.MarkSent(settings.UserID, kind, now)
+	}
+
+	log.Printf("[info] %s digest sent to user %d", kind, settings.UserID)
+	return s.Digests.MarkSent(settings.UserID, kind, now)
+}
+
+// digest composes the summary of a ledger for [from, to), compared to the period of the same length before.
+func (s *BotDigestScheduler) digest(lang, kind string, ledgerID int64, from, to time.Time) (string, error) {
+	ledger, err := s.Ledgers.GetLedger(ledgerID)
+	if err != nil {
+		return "", err
+	}
+
+	totals, err := s.Spendings.TotalsByCategory(ledgerID, 0, from, to)
+	if err != nil {
+		return "", err
+	}
+
+	categories, err := s.Categories.ListCategories(ledgerID)
+	if err != nil {
+		return "", err
+	}
+	totals = rollUpTotals(totals, categories, 0)
+
+	prevFrom := from.AddDate(0, 0, -7)
+	title := fmt.Sprintf("%s–%s", from.Format("02.01"), to.AddDate(0, 0, -1).Format("02.01"))
+	if kind == storage.DigestMonthly {
+		prevFrom = from.AddDate(0, -1, 0)
+		title = fmt.Sprintf("%s %d", i18n.MonthName(lang, from.Month()), from.Year())
+	}
+
+	prevTotals, err := s.Spendings.TotalsByCategory(ledgerID, 0, prevFrom, from)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "🗓 *%s* · %s · %s\n\n", i18n.Text(lang, "digest.title_"+kind), title, ledger.Name)
+
+	total, prevTotal := sumTotals(totals), sumTotals(prevTotals)
+	if total == 0 {
+		sb.WriteString(i18n.Text(lang, "digest.empty"))
+	} else {
+		fmt.Fprintf(&sb, "%s: *%.2f*", i18n.Text(lang, "digest.spent"), total)
+		if prevTotal > 0 {
+			change := (total - prevTotal) / prevTotal * 100
+			arrow := "▲"
+			if change < 0 {
+				arrow = "▼"
+			}
+			fmt.Fprintf(&sb, ", %s", i18n.Text(lang, "digest.change_"+kind, fmt.Sprintf("%s %.0f%%", arrow, math.Abs(change))))
+		}
+		sb.WriteString("\n")
+
+		fmt.Fprintf(&sb, "\n*%s*\n", i18n.Text(lang, "digest.top_categories"))
+		for i, t := range totals {
+			if i == digestTopCount {
+				break
+			}
+			fmt.Fprintf(&sb, "%s %s — %.2f\n", t.Emoji, t.Name, t.Total)
+		}
+
+		largest, err := s.largestSpendings(ledgerID, from, to)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&sb, "\n*%s*\n%s", i18n.Text(lang, "digest.largest"), largest)
+	}
+
+	// weekly digests show how the current month goes, monthly ones how the finished month went
+	month := time.Date(to.Year(), to.Month(), 1, 0, 0, 0, 0, to.Location())
+	if kind == storage.DigestMonthly {
+		month = from
+	}
+	status, err := budgetStatus(lang, ledgerID, month, s.Budgets, s.Categories, s.Spendings)
+	if err != nil {
+		return "", err
+	}
+	if status != "" {
+		fmt.Fprintf(&sb, "\n*%s · %s %d*\n%s", i18n.Text(lang, "digest.budgets"), i18n.MonthName(lang, month.Month()), month.Year(), status)
+	}
+
+	// monthly digests go out once the month is over, there's nothing left to forecast
+	if kind == storage.DigestWeekly {
+		forecast, err := forecastStatus(lang, ledgerID, to, s.Budgets, s.Categories, s.Spendings)
+		if err != nil {
+			return "", err
+		}
+		if forecast != "" {
+			fmt.Fprintf(&sb, "\n🔮 *%s*\n%s", i18n.Text(lang, "forecast.title"), forecast)
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// largestSpendings lists the largest single spendings of the period, one per line.
+func (s *BotDigestScheduler) largestSpendings(ledgerID int64, from, to time.Time) (string, error) {
+	spendings, err := s.Spendings.LargestSpendings(ledgerID, from, to, digestTopCount)
+	if err != nil {
+		return "", err
+	}
+
+	categories, err := s.Categories.ListCategories(ledgerID)
+	if err != nil {
+		return "", err
+	}
+	names := make(map[int64]string, len(categories))
+	for _, c := range categories {
+		names[c.ID] = c.Emoji + " " + c.Name
+	}
+
+	var sb strings.Builder
+	for _, sp := range spendings {
+		fmt.Fprintf(&sb, "%s %s — %.2f\n", sp.Timestamp.Format("02.01"), names[sp.CategoryID], sp.Amount)
+	}
+	return sb.String(), nil
+}
+
+// sendWithRetry sends a message, retrying with a growing delay when Telegram fails.
+// Messages Telegram refuses to deliver aren't retried.
+func (s *BotDigestScheduler) sendWithRetry(ctx context.Context, msg tbapi.MessageConfig) error {
+	var err error
+	for attempt := 1; attempt <= digestRetries; attempt++ {
+		if err = send(msg, s.TbAPI); err == nil {
+			return nil
+		}
+		if undeliverable(err) || attempt == digestRetries {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(digestRetryDelay * time.Duration(attempt)):
+		}
+	}
+	return fmt.Errorf("failed to send digest: %w", err)
+}
+
+// undeliverable reports whether Telegram refused a message for good, like a bad request
+// or a user who blocked the bot, rather than failing for a while.
+func undeliverable(err error) bool {
+	var tbErr *tbapi.Error
+	return errors.As(err, &tbErr) && (tbErr.Code == http.StatusBadRequest || tbErr.Code == http.StatusForbidden)
+}
+
+// digestPeriod returns the [from, to) range of the last full week, starting on Monday, or month before now.
+func digestPeriod(kind string, now time.Time) (time.Time, time.Time) {
+	if kind == storage.DigestMonthly {
+		to := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		return to.AddDate(0, -1, 0), to
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	to := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7)) // weeks start on Monday
+	return to.AddDate(0, 0, -7), to
+}
+
+func sumTotals(totals []storage.CategoryTotal) float64 {
+	var sum float64
+	for _, t := range totals {
+		sum += t.Total
+	}
+	return sum
+}
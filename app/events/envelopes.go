@@ -0,0 +1,56 @@
+package events
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// envelopeCheckInterval is how often the scheduler looks for months to open envelopes for.
+const envelopeCheckInterval = time.Hour
+
+// BotEnvelopeScheduler opens the budget envelopes of every ledger when a month starts,
+// allocating them from the budgets and carrying over what's left where budgets roll over.
+// Ledgers are also opened on demand when their budgets are shown, so a missed start is caught up.
+type BotEnvelopeScheduler struct {
+	Categories CategoriesRepository
+	Spendings  SpendingsRepository
+	Budgets    BudgetsRepository
+}
+
+// Run opens due envelopes periodically until the context is canceled.
+func (s *BotEnvelopeScheduler) Run(ctx context.Context) {
+	log.Printf("[info] started envelope scheduler")
+
+	ticker := time.NewTicker(envelopeCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		s.OpenDue(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// OpenDue opens the envelopes of the month of now for every ledger with budgets, opened ones are kept.
+func (s *BotEnvelopeScheduler) OpenDue(ctx context.Context, now time.Time) {
+	ledgerIDs, err := s.Budgets.ListBudgetLedgers()
+	if err != nil {
+		log.Printf("[error] error listing ledgers with budgets: %v", err)
+		return
+	}
+
+	for _, ledgerID := range ledgerIDs {
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err := openEnvelopes(ledgerID, monthStart(now), s.Budgets, s.Categories, s.Spendings); err != nil {
+			log.Printf("[error] error opening envelopes of ledger %d: %v", ledgerID, err)
+		}
+	}
+}
@@ -2,11 +2,16 @@ package events
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/looplab/fsm"
+	"github.com/nyanyamaga/finance-tracker-bot/app/parsers"
 	"github.com/nyanyamaga/finance-tracker-bot/app/storage"
 	"log"
+	"strings"
+	"time"
 )
 
 // TbAPI is an interface for telegram bot API, only subset of methods used
@@ -15,26 +20,179 @@ type TbAPI interface {
 	Send(c tbapi.Chattable) (tbapi.Message, error)
 	Request(c tbapi.Chattable) (*tbapi.APIResponse, error)
 	GetChat(config tbapi.ChatInfoConfig) (tbapi.Chat, error)
+	GetFileDirectURL(fileID string) (string, error)
 }
 
 type TbKeyboards interface {
-	GetMainKeyboard() tbapi.ReplyKeyboardMarkup
-	GetCategoryKeyboard(userID int64) tbapi.InlineKeyboardMarkup
+	GetMainKeyboard(lang string) tbapi.ReplyKeyboardMarkup
+	GetCategoryKeyboard(lang string, ledgerID, userID, parentID int64, page int) tbapi.InlineKeyboardMarkup
+	GetDateKeyboard(lang string) tbapi.InlineKeyboardMarkup
+	GetAmountKeyboard(lang string, date time.Time) tbapi.InlineKeyboardMarkup
+	GetCalendarKeyboard(lang string, month time.Time) tbapi.InlineKeyboardMarkup
+	GetLanguageKeyboard() tbapi.InlineKeyboardMarkup
+	GetLedgerKeyboard(ledgers []storage.LedgerInfo, activeID int64) tbapi.InlineKeyboardMarkup
+	GetReportKeyboard(lang string, members []storage.LedgerMemberInfo, selectedID int64, parents []storage.CategoryTotal, parentID int64) tbapi.InlineKeyboardMarkup
+	GetPayerKeyboard(lang string, members []storage.LedgerMemberInfo, userID int64) tbapi.InlineKeyboardMarkup
+	GetSplitKeyboard(lang string) tbapi.InlineKeyboardMarkup
+	GetSettleKeyboard(lang string, transfers []storage.SettlementInfo, members []storage.LedgerMemberInfo) tbapi.InlineKeyboardMarkup
+	GetChartKeyboard(lang, kind, period string) tbapi.InlineKeyboardMarkup
+	GetSettingsKeyboard(lang string, settings storage.UserSettingsInfo) tbapi.InlineKeyboardMarkup
+	GetReminderKeyboard(lang string) tbapi.InlineKeyboardMarkup
+	GetPagerKeyboard(prefix string, page, pages int) tbapi.InlineKeyboardMarkup
+	GetRulesKeyboard(rules []storage.RuleInfo) tbapi.InlineKeyboardMarkup
+	GetSuggestionKeyboard(lang string, category storage.CategoryInfo) tbapi.InlineKeyboardMarkup
+	GetGoalsKeyboard(lang string, goals []storage.GoalInfo) tbapi.InlineKeyboardMarkup
+	GetAccountKeyboard(accounts []storage.AccountInfo, defaultID int64) tbapi.InlineKeyboardMarkup
+	GetUnusualKeyboard(lang string, spendingID int64) tbapi.InlineKeyboardMarkup
+	GetHistoryKeyboard(lang, period, scope string, newerID, olderID int64, shared bool, receipts []storage.SpendingRecord) tbapi.InlineKeyboardMarkup
 }
 
 type UserStateRepository interface {
 	Write(entry storage.UserStateInfo) error
-	Read(userID int64) (*storage.UserStateInfo, error)
+	Read(chatID, userID int64) (*storage.UserStateInfo, error)
+}
+
+type UserSettingsRepository interface {
+	Write(entry storage.UserSettingsInfo) error
+	Read(userID int64) (*storage.UserSettingsInfo, error)
+	ListDigestSubscribers() ([]storage.UserSettingsInfo, error)
+	ListReminderSubscribers() ([]storage.UserSettingsInfo, error)
 }
 
 type CategoriesRepository interface {
 	AddOrUpdateCategory(info storage.CategoryInfo) error
-	ListCategories(userID int64) ([]storage.CategoryInfo, error)
+	ListCategories(ledgerID int64) ([]storage.CategoryInfo, error)
+	ToggleFavorite(userID, categoryID int64) (bool, error)
+	GetCategory(categoryID int64) (*storage.CategoryInfo, error)
 }
 
 type SpendingsRepository interface {
-	AddSpending(info storage.SpendingInfo) error
-	ListSpendings(userID int64) ([]storage.SpendingInfo, error)
+	AddSpending(info storage.SpendingInfo) (int64, error)
+	GetSpending(spendingID int64) (*storage.SpendingInfo, error)
+	UpdateAmount(spendingID int64, amount float64) error
+	CategoryAmounts(ledgerID, categoryID, excludeID int64, limit int) ([]float64, error)
+	ListDescribedSpendings(ledgerID int64, limit int) ([]storage.SpendingInfo, error)
+	ListSpendings(filter storage.SpendingFilter, cursor storage.SpendingCursor, limit int) ([]storage.SpendingRecord, error)
+	FindSpendings(filter storage.SpendingFilter, limit, offset int) ([]storage.SpendingRecord, error)
+	SummarizeSpendings(filter storage.SpendingFilter) (storage.SpendingSummary, error)
+	SummarizeByCategory(filter storage.SpendingFilter) ([]storage.CategoryTotal, error)
+	SummarizeByTag(filter storage.SpendingFilter) ([]storage.TagTotal, error)
+	TotalsByCategory(ledgerID, userID int64, from, to time.Time) ([]storage.CategoryTotal, error)
+	TotalsByCategoryDay(ledgerID int64, from, to time.Time) ([]storage.CategoryDayTotal, error)
+	TotalsByDay(ledgerID, userID int64, from, to time.Time) ([]storage.PeriodTotal, error)
+	TotalsByMonth(ledgerID, userID int64, from, to time.Time) ([]storage.PeriodTotal, error)
+	LargestSpendings(ledgerID int64, from, to time.Time, limit int) ([]storage.SpendingInfo, error)
+	CountUserSpendings(userID int64, from, to time.Time) (int, error)
+	SplitBalances(ledgerID int64) (map[int64]float64, error)
+}
+
+type SettlementsRepository interface {
+	AddSettlement(info storage.SettlementInfo) error
+	ListSettlements(ledgerID int64) ([]storage.SettlementInfo, error)
+}
+
+type RulesRepository interface {
+	AddRule(info storage.RuleInfo) error
+	ListRules(ledgerID int64) ([]storage.RuleInfo, error)
+	DeleteRule(ledgerID, ruleID int64) error
+}
+
+type ReceiptsRepository interface {
+	AddReceipt(info storage.ReceiptInfo) (int64, error)
+	SetLocalPath(receiptID int64, path string) error
+	GetReceipt(spendingID int64) (*storage.ReceiptInfo, error)
+	LinkMessage(chatID int64, messageID int, spendingID int64) error
+	GetMessageSpending(chatID int64, messageID int) (int64, error)
+}
+
+type GoalsRepository interface {
+	AddGoal(info storage.GoalInfo) (int64, error)
+	UpdateGoal(info storage.GoalInfo) error
+	CloseGoal(ledgerID, goalID int64) error
+	GetGoal(goalID int64) (*storage.GoalInfo, error)
+	ListGoals(ledgerID int64) ([]storage.GoalInfo, error)
+	AddContribution(info storage.GoalContributionInfo) error
+}
+
+type AccountsRepository interface {
+	AddAccount(info storage.AccountInfo) error
+	ListAccounts(ledgerID int64) ([]storage.AccountInfo, error)
+	AddTransfer(info storage.AccountTransferInfo) error
+	LastUsedAccount(ledgerID, userID int64) (int64, error)
+}
+
+type BudgetsRepository interface {
+	SetBudget(info storage.BudgetInfo) error
+	ListBudgets(ledgerID int64) ([]storage.BudgetInfo, error)
+	ListBudgetLedgers() ([]int64, error)
+	SetRollover(ledgerID, categoryID int64, rollover bool) error
+	OpenEnvelope(info storage.EnvelopeInfo) error
+	SetAllocation(ledgerID, categoryID int64, month string, amount float64) error
+	ListEnvelopes(ledgerID int64, month string) ([]storage.EnvelopeInfo, error)
+	MoveAllocation(ledgerID int64, month string, fromCategoryID, toCategoryID int64, amount float64) error
+}
+
+type StatsRepository interface {
+	Usage(now time.Time) (storage.UsageInfo, error)
+	ListUserIDs() ([]int64, error)
+	Seen(userID int64, at time.Time) error
+}
+
+type AccessRepository interface {
+	GetAccess(userID int64) (*storage.AccessInfo, error)
+	FindAccess(username string) (*storage.AccessInfo, error)
+	ListAccess() ([]storage.AccessInfo, error)
+	RequestAccess(info storage.AccessInfo) (bool, error)
+	SetStatus(info storage.AccessInfo) error
+	CreateInvite(invite storage.AccessInviteInfo) error
+	UseInvite(code string, info storage.AccessInfo, now time.Time) error
+}
+
+type DigestsRepository interface {
+	LastSent(userID int64, kind string) (time.Time, error)
+	MarkSent(userID int64, kind string, sentAt time.Time) error
+}
+
+type RemindersRepository interface {
+	Read(userID int64) (*storage.ReminderInfo, error)
+	Write(entry storage.ReminderInfo) error
+}
+
+type LedgersRepository interface {
+	CreateLedger(info storage.LedgerInfo, ownerName string) (*storage.LedgerInfo, error)
+	GetLedger(ledgerID int64) (*storage.LedgerInfo, error)
+	GetChatLedger(chatID int64) (*storage.LedgerInfo, error)
+	ListUserLedgers(userID int64) ([]storage.LedgerInfo, error)
+	GetMember(ledgerID, userID int64) (*storage.LedgerMemberInfo, error)
+	ListMembers(ledgerID int64) ([]storage.LedgerMemberInfo, error)
+	AddMember(member storage.LedgerMemberInfo) error
+	UpdateMemberName(userID int64, name string) error
+	CreateInvite(invite storage.LedgerInviteInfo) error
+	UseInvite(code string, userID int64, userName string, now time.Time) (*storage.LedgerInviteInfo, error)
+}
+
+// Conversation identifies a dialog between the bot and a user in a chat.
+// In private chats ChatID is the same as UserID.
+type Conversation struct {
+	ChatID int64
+	UserID int64
+}
+
+// ConversationOf returns the conversation a message belongs to.
+func ConversationOf(msg *tbapi.Message) Conversation {
+	return Conversation{ChatID: msg.Chat.ID, UserID: msg.From.ID}
+}
+
+// IsGroup reports whether the conversation happens in a group chat.
+func (c Conversation) IsGroup() bool {
+	return c.ChatID != c.UserID
+}
+
+func (c Conversation) String() string {
+	if c.IsGroup() {
+		return fmt.Sprintf("user %d in chat %d", c.UserID, c.ChatID)
+	}
+	return fmt.Sprintf("user %d", c.UserID)
 }
 
 type CommandHandler interface {
@@ -49,15 +207,131 @@ type CallbackQueryHandler interface {
 	HandleCallbackQuery(ctx context.Context, update tbapi.Update)
 }
 
+type InlineHandler interface {
+	HandleInlineQuery(ctx context.Context, update tbapi.Update)
+	HandleChosenInlineResult(ctx context.Context, update tbapi.Update)
+}
+
 type StateManager interface {
-	InitializeUserFSM(ctx context.Context, userID int64)
-	SetIdleState(ctx context.Context, userID int64)
-	TriggerStateChange(ctx context.Context, userID int64, action, value string) error
-	GetCurrentState(ctx context.Context, userID int64) (*fsm.FSM, error)
+	InitializeUserFSM(ctx context.Context, conv Conversation)
+	SetIdleState(ctx context.Context, conv Conversation)
+	TriggerStateChange(ctx context.Context, conv Conversation, action, value string) error
+	GetCurrentState(ctx context.Context, conv Conversation) (*fsm.FSM, error)
+	TrackMessage(conv Conversation, messageID int)
+	AttachReceipt(conv Conversation, receipt storage.ReceiptInfo) error
+	EnterParsedSpending(ctx context.Context, conv Conversation, spending parsers.Spending) error
+	Language(userID int64) string
+	RememberLanguage(userID int64, languageCode string)
+	SetLanguage(userID int64, lang string) error
+}
+
+type MessageParser interface {
+	Parse(text string, now time.Time) (parsers.Spending, bool)
+}
+
+type LedgerManager interface {
+	ActiveLedger(userID int64) (*storage.LedgerMemberInfo, error)
+	LedgerFor(conv Conversation) (*storage.LedgerMemberInfo, error)
+	SwitchLedger(userID, ledgerID int64) error
+	CreateLedger(userID int64, name string) (*storage.LedgerInfo, error)
+	CreateInvite(userID int64, role string) (*storage.LedgerInviteInfo, error)
+	JoinLedger(userID int64, code string) (*storage.LedgerInfo, error)
+	UserLedgers(userID int64) ([]storage.LedgerInfo, error)
+	Ledger(ledgerID int64) (*storage.LedgerInfo, error)
+	Members(ledgerID int64) ([]storage.LedgerMemberInfo, error)
+	RememberName(userID int64, name string)
+	RememberChat(chatID int64, title string)
+}
+
+type Reporter interface {
+	MonthlyReport(lang string, conv Conversation, memberID, parentID int64) (string, tbapi.InlineKeyboardMarkup, error)
+	Chart(lang string, conv Conversation, kind, period string) ([]byte, string, tbapi.InlineKeyboardMarkup, error)
+	Find(lang string, conv Conversation, query string, page int) (string, tbapi.InlineKeyboardMarkup, error)
+	History(lang string, conv Conversation, period, scope string, cursor storage.SpendingCursor) (string, tbapi.InlineKeyboardMarkup, error)
+	Tags(lang string, conv Conversation, tag string) (string, error)
+	Compare(lang string, conv Conversation) (string, error)
+	Export(conv Conversation, tag string) ([]byte, error)
+}
+
+type BudgetManager interface {
+	Status(lang string, conv Conversation) (string, error)
+	SetBudget(conv Conversation, category string, amount float64) error
+	ToggleRollover(conv Conversation, category string) (bool, error)
+	Cover(conv Conversation, category, source string) (float64, error)
+}
+
+type SettingsManager interface {
+	Settings(userID int64) (*storage.UserSettingsInfo, error)
+	ToggleDigest(userID int64, kind string) (*storage.UserSettingsInfo, error)
+	SetTimezone(userID int64, timezone string) (*time.Location, error)
+	SetReminder(userID int64, at string) error
+	SetQuietHours(userID int64, start, end string) error
+	SnoozeReminder(userID int64, d time.Duration) error
+}
+
+type RuleManager interface {
+	List(lang string, conv Conversation) (string, tbapi.InlineKeyboardMarkup, error)
+	AddRule(conv Conversation, keyword, category string) error
+	DeleteRule(conv Conversation, ruleID int64) error
+	Suggest(ledgerID int64, description string) (int64, error)
+}
+
+type ReceiptManager interface {
+	Attach(conv Conversation, spendingID int64, receipt storage.ReceiptInfo) error
+	AttachToMessage(conv Conversation, messageID int, receipt storage.ReceiptInfo) error
+	LinkMessage(conv Conversation, messageID int, spendingID int64) error
+	Receipt(conv Conversation, spendingID int64) (*storage.ReceiptInfo, error)
+}
+
+type GoalManager interface {
+	List(lang string, conv Conversation) (string, tbapi.InlineKeyboardMarkup, error)
+	Goal(conv Conversation, goalID int64) (*storage.GoalInfo, error)
+	AddGoal(conv Conversation, details string) (*storage.GoalInfo, error)
+	EditGoal(conv Conversation, goalID int64, details string) (*storage.GoalInfo, error)
+	Contribute(conv Conversation, goalID int64, amount float64) (*storage.GoalInfo, error)
+	CloseGoal(conv Conversation, goalID int64) error
+}
+
+type AccountManager interface {
+	List(lang string, conv Conversation) (string, error)
+	LedgerAccounts(conv Conversation) ([]storage.AccountInfo, error)
+	DefaultAccount(conv Conversation, accounts []storage.AccountInfo) (int64, error)
+	AddAccount(conv Conversation, details string) (*storage.AccountInfo, error)
+	Transfer(conv Conversation, details string) (*storage.AccountTransferInfo, error)
+}
+
+type AdminManager interface {
+	IsAdmin(userID int64) bool
+	Seen(userID int64)
+	Notify(key string, args ...interface{})
+	Stats(lang string) (string, error)
+	Broadcast(ctx context.Context, text string) (sent, failed int, err error)
+}
+
+type AccessControl interface {
+	Allow(update tbapi.Update) bool
+}
+
+type AccessManager interface {
+	List(lang string) (string, error)
+	Approve(who string) (*storage.AccessInfo, error)
+	Revoke(who string) (*storage.AccessInfo, error)
+	CreateInvite(adminID int64) (string, error)
+}
+
+type BalanceManager interface {
+	Balances(lang string, conv Conversation) (string, tbapi.InlineKeyboardMarkup, error)
+	Settle(conv Conversation, transfer storage.SettlementInfo) error
 }
 
 // send a message to the telegram as markdown first and if failed - as plain text
 func send(tbMsg tbapi.Chattable, tbAPI TbAPI) error {
+	_, err := sendMessage(tbMsg, tbAPI)
+	return err
+}
+
+// sendMessage sends a message the way send does and returns the sent message.
+func sendMessage(tbMsg tbapi.Chattable, tbAPI TbAPI) (tbapi.Message, error) {
 	withParseMode := func(tbMsg tbapi.Chattable, parseMode string) tbapi.Chattable {
 		switch msg := tbMsg.(type) {
 		case tbapi.MessageConfig:
@@ -70,17 +344,60 @@ func send(tbMsg tbapi.Chattable, tbAPI TbAPI) error {
 			return msg
 		case tbapi.EditMessageReplyMarkupConfig:
 			return msg
+		case tbapi.PhotoConfig:
+			msg.ParseMode = parseMode
+			return msg
+		case tbapi.DocumentConfig:
+			msg.ParseMode = parseMode
+			return msg
+		case tbapi.EditMessageMediaConfig:
+			if photo, ok := msg.Media.(tbapi.InputMediaPhoto); ok {
+				photo.ParseMode = parseMode
+				msg.Media = photo
+			}
+			return msg
 		}
 		return tbMsg // don't touch other types
 	}
 
 	msg := withParseMode(tbMsg, tbapi.ModeMarkdown) // try markdown first
-	if _, err := tbAPI.Send(msg); err != nil {
+	sent, err := tbAPI.Send(msg)
+	if err != nil {
 		log.Printf("[warn] failed to send message as markdown, %v", err)
 		msg = withParseMode(tbMsg, "") // try plain text
-		if _, err := tbAPI.Send(msg); err != nil {
-			return fmt.Errorf("can't send message to telegram: %w", err)
+		if sent, err = tbAPI.Send(msg); err != nil {
+			return sent, fmt.Errorf("can't send message to telegram: %w", err)
 		}
 	}
+	return sent, nil
+}
+
+// updateUserSettings reads the user's settings, applies the update and writes them back.
+// Users without settings yet start from an empty entry.
+func updateUserSettings(repo UserSettingsRepository, userID int64, update func(settings *storage.UserSettingsInfo)) error {
+	settings, err := repo.Read(userID)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("failed to read settings for user %d: %w", userID, err)
+		}
+		settings = &storage.UserSettingsInfo{UserID: userID}
+	}
+
+	update(settings)
+	if err = repo.Write(*settings); err != nil {
+		return fmt.Errorf("failed to write settings for user %d: %w", userID, err)
+	}
 	return nil
 }
+
+// displayName returns the name used to attribute records to a Telegram user.
+func displayName(user *tbapi.User) string {
+	name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	if name == "" && user.UserName != "" {
+		name = "@" + user.UserName
+	}
+	if name == "" {
+		name = fmt.Sprint(user.ID)
+	}
+	return name
+}
@@ -0,0 +1,180 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/nyanyamaga/finance-tracker-bot/app/i18n"
+	"github.com/nyanyamaga/finance-tracker-bot/app/keyboards"
+	"github.com/nyanyamaga/finance-tracker-bot/app/storage"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// findPageSize is how many spendings a page of search results lists.
+const findPageSize = 10
+
+// findQueryMark starts the first line of search results, the query follows it so pages can be re-run from the message.
+const findQueryMark = "🔍"
+
+// ErrInvalidQuery is returned for search queries that can't be parsed.
+var ErrInvalidQuery = errors.New("invalid search query")
+
+// Find lists a page of spendings of the conversation's ledger matching the query, with totals of all matches.
+func (r *BotReporter) Find(lang string, conv Conversation, query string, page int) (string, tbapi.InlineKeyboardMarkup, error) {
+	keyboard := tbapi.InlineKeyboardMarkup{InlineKeyboard: [][]tbapi.InlineKeyboardButton{}}
+
+	member, err := r.Ledgers.LedgerFor(conv)
+	if err != nil {
+		return "", keyboard, err
+	}
+
+	filter, category, err := parseFindQuery(query, time.Now())
+	if err != nil {
+		return "", keyboard, err
+	}
+	filter.LedgerID = member.LedgerID
+
+	if category != "" {
+		categories, err := r.Categories.ListCategories(member.LedgerID)
+		if err != nil {
+			return "", keyboard, err
+		}
+		filter.CategoryIDs = []int64{0} // matches nothing unless the category exists
+		parents := make(map[int64]bool)
+		for _, c := range categories {
+			if strings.EqualFold(c.Name, category) || c.Emoji == category {
+				filter.CategoryIDs = append(filter.CategoryIDs, c.ID)
+				if c.ParentID == 0 {
+					parents[c.ID] = true
+				}
+			}
+		}
+		for _, c := range categories { // spendings of sub-categories count as the parent's, as in reports
+			if parents[c.ParentID] {
+				filter.CategoryIDs = append(filter.CategoryIDs, c.ID)
+			}
+		}
+	}
+
+	summary, err := r.Spendings.SummarizeSpendings(filter)
+	if err != nil {
+		return "", keyboard, err
+	}
+
+	pages := (summary.Count + findPageSize - 1) / findPageSize
+	page = max(0, min(page, pages-1))
+	records, err := r.Spendings.FindSpendings(filter, findPageSize, page*findPageSize)
+	if err != nil {
+		return "", keyboard, err
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s `%s`\n\n", findQueryMark, query)
+	if summary.Count == 0 {
+		sb.WriteString(i18n.Text(lang, "find.empty"))
+		return sb.String(), keyboard, nil
+	}
+
+	for _, record := range records {
+		sb.WriteString(formatSpendingRecord(record))
+	}
+	fmt.Fprintf(&sb, "\n*%s*", i18n.Text(lang, "find.summary", summary.Count, summary.Total))
+
+	return sb.String(), r.TbKeyboards.GetPagerKeyboard(keyboards.FindPagePrefix, page, pages), nil
+}
+
+// formatSpendingRecord formats a spending as a line of a list.
+func formatSpendingRecord(record storage.SpendingRecord) string {
+	line := fmt.Sprintf("%s %s %s — %.2f", record.Timestamp.Format("02.01.06"), record.CategoryEmoji, record.CategoryName, record.Amount)
+	if record.Description != "" {
+		line += " · " + record.Description
+	}
+	if record.Receipts > 0 {
+		line += " 📎"
+	}
+	return line + "\n"
+}
+
+// parseFindQuery parses a search query into a spending filter. Besides plain words matched against
+// descriptions and category names it understands amount comparisons like >100 or <=50, #tags,
+// cat:<category>, from:<date> and to:<date>, dates in any format the date step accepts.
+// The category is returned by name, as categories are resolved per ledger.
+func parseFindQuery(query string, now time.Time) (storage.SpendingFilter, string, error) {
+	var filter storage.SpendingFilter
+	var category string
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	tokens, err := splitQuery(query)
+	if err != nil {
+		return filter, "", err
+	}
+	if len(tokens) == 0 {
+		return filter, "", fmt.Errorf("empty query: %w", ErrInvalidQuery)
+	}
+
+	for _, token := range tokens {
+		key, value, hasKey := strings.Cut(token, ":")
+		switch key = strings.ToLower(key); {
+		case hasKey && (key == "cat" || key == "category"):
+			category = value
+		case hasKey && (key == "from" || key == "to"):
+			day, err := parseDay(strings.ToLower(value), today)
+			if err != nil {
+				return filter, "", fmt.Errorf("%s: %w", err, ErrInvalidQuery)
+			}
+			if key == "from" {
+				filter.From = day
+			} else {
+				filter.To = day.AddDate(0, 0, 1) // the end date is inclusive
+			}
+		case strings.HasPrefix(token, "#") && len(token) > 1:
+			filter.Tags = append(filter.Tags, strings.ToLower(token[1:]))
+		case strings.ContainsAny(token[:1], "<>="):
+			op := strings.TrimRight(token[:min(2, len(token))], "0123456789.,")
+			amount, err := strconv.ParseFloat(strings.ReplaceAll(token[len(op):], ",", "."), 64)
+			if err != nil || (op != "=" && op != "<" && op != "<=" && op != ">" && op != ">=") {
+				return filter, "", fmt.Errorf("amount %q: %w", token, ErrInvalidQuery)
+			}
+			filter.Amounts = append(filter.Amounts, storage.AmountCondition{Op: op, Value: amount})
+		default:
+			filter.Terms = append(filter.Terms, token)
+		}
+	}
+	return filter, category, nil
+}
+
+// splitQuery splits a query by spaces, keeping double-quoted parts like cat:"Eating out" together.
+func splitQuery(query string) ([]string, error) {
+	var tokens []string
+	var token strings.Builder
+	quoted := false
+
+	for _, r := range query {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ' ' && !quoted:
+			if token.Len() > 0 {
+				tokens = append(tokens, token.String())
+				token.Reset()
+			}
+		default:
+			token.WriteRune(r)
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unclosed quote: %w", ErrInvalidQuery)
+	}
+	if token.Len() > 0 {
+		tokens = append(tokens, token.String())
+	}
+	return tokens, nil
+}
+
+// findQueryOf extracts the query from search results, so their pages can be turned without keeping state.
+func findQueryOf(msg *tbapi.Message) string {
+	line, _, _ := strings.Cut(msg.Text, "\n")
+	return strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, findQueryMark)), "`")
+}
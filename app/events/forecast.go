@@ -0,0 +1,186 @@
+package events
+
+import (
+	"fmt"
+	"github.com/nyanyamaga/finance-tracker-bot/app/i18n"
+	"github.com/nyanyamaga/finance-tracker-bot/app/storage"
+	"sort"
+	"strings"
+	"time"
+)
+
+// forecastHistoryDays is how many days before the month the usual spendings per weekday are taken from.
+const forecastHistoryDays = 12 * 7
+
+// categoryForecast is the spent and the projected month-end total of a category.
+type categoryForecast struct {
+	Spent     float64
+	Projected float64
+}
+
+// monthForecast projects the month-end total of every category of the ledger as of at. The remaining days
+// are expected to go like the days of the month so far and like the same weekdays went over the weeks
+// before the month, the pace of the month weighing more as the month goes on. 0 holds the overall total.
+func monthForecast(ledgerID int64, at time.Time, spendings SpendingsRepository) (map[int64]categoryForecast, error) {
+	from := monthStart(at)
+	end := from.AddDate(0, 1, 0)
+	days := end.AddDate(0, 0, -1).Day()
+	elapsed := at.Day() - 1
+	if at.After(time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, at.Location())) {
+		elapsed++ // the current day counts as gone once it has started
+	}
+
+	totals, err := spendings.TotalsByCategory(ledgerID, 0, from, at)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := spendings.TotalsByCategoryDay(ledgerID, from.AddDate(0, 0, -forecastHistoryDays), from)
+	if err != nil {
+		return nil, err
+	}
+
+	if elapsed == 0 && len(history) == 0 {
+		return nil, nil
+	}
+
+	// usual[c][weekday] is the average spent in the category on that weekday since the first spending
+	usual := make(map[int64][7]float64)
+	if len(history) > 0 {
+		first, err := time.ParseInLocation("2006-01-02", history[0].Day, at.Location())
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse spending day %q: %w", history[0].Day, err)
+		}
+		var weekdays [7]int
+		for d := first; d.Before(from); d = d.AddDate(0, 0, 1) {
+			weekdays[d.Weekday()]++
+		}
+
+		for _, t := range history {
+			day, err := time.ParseInLocation("2006-01-02", t.Day, at.Location())
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse spending day %q: %w", t.Day, err)
+			}
+			u := usual[t.CategoryID]
+			u[day.Weekday()] += t.Total / float64(weekdays[day.Weekday()])
+			usual[t.CategoryID] = u
+		}
+	}
+
+	forecasts := make(map[int64]categoryForecast, len(totals)+len(usual)+1)
+	for _, t := range totals {
+		forecasts[t.CategoryID] = categoryForecast{Spent: t.Total}
+	}
+	for categoryID := range usual {
+		if _, ok := forecasts[categoryID]; !ok {
+			forecasts[categoryID] = categoryForecast{}
+		}
+	}
+
+	// without history only the pace of the month is known, without days gone only the history
+	weight := float64(elapsed) / float64(days)
+	if len(history) == 0 {
+		weight = 1
+	}
+
+	var total categoryForecast
+	for categoryID, f := range forecasts {
+		var paced, usually float64
+		if elapsed > 0 {
+			paced = f.Spent / float64(elapsed) * float64(days-elapsed)
+		}
+		for d := from.AddDate(0, 0, elapsed); d.Before(end); d = d.AddDate(0, 0, 1) {
+			usually += usual[categoryID][d.Weekday()]
+		}
+
+		f.Projected = f.Spent + weight*paced + (1-weight)*usually
+		forecasts[categoryID] = f
+		total.Spent += f.Spent
+		total.Projected += f.Projected
+	}
+	forecasts[0] = total
+
+	return forecasts, nil
+}
+
+// forecastStatus lists the projected month-end totals of the ledger per category as of at, rolled up
+// to parent categories, and the projections against the month's envelopes. It's empty when nothing
+// can be projected yet.
+func forecastStatus(lang string, ledgerID int64, at time.Time, budgets BudgetsRepository, categories CategoriesRepository, spendings SpendingsRepository) (string, error) {
+	forecasts, err := monthForecast(ledgerID, at, spendings)
+	if err != nil || toCents(forecasts[0].Projected) == 0 {
+		return "", err
+	}
+
+	ledgerCategories, err := categories.ListCategories(ledgerID)
+	if err != nil {
+		return "", err
+	}
+	byID := make(map[int64]storage.CategoryInfo, len(ledgerCategories))
+	for _, c := range ledgerCategories {
+		byID[c.ID] = c
+	}
+
+	// budgets of parent categories cover their sub-categories, so projections are summed up the same way
+	rolledUp := map[int64]categoryForecast{0: forecasts[0]}
+	for categoryID, f := range forecasts {
+		if categoryID == 0 {
+			continue
+		}
+		targets := []int64{categoryID}
+		if parentID := byID[categoryID].ParentID; parentID != 0 {
+			targets = append(targets, parentID)
+		}
+		for _, id := range targets {
+			r := rolledUp[id]
+			r.Spent += f.Spent
+			r.Projected += f.Projected
+			rolledUp[id] = r
+		}
+	}
+
+	var order []int64
+	for categoryID, f := range rolledUp {
+		if categoryID != 0 && byID[categoryID].ParentID == 0 && toCents(f.Projected) > 0 {
+			order = append(order, categoryID)
+		}
+	}
+	sort.Slice(order, func(i, j int) bool {
+		pi, pj := rolledUp[order[i]].Projected, rolledUp[order[j]].Projected
+		if pi != pj {
+			return pi > pj
+		}
+		return byID[order[i]].Name < byID[order[j]].Name
+	})
+
+	names := map[int64]string{0: "💰 " + i18n.Text(lang, "report.total")}
+	for _, c := range ledgerCategories {
+		names[c.ID] = c.Emoji + " " + c.Name
+	}
+
+	var sb strings.Builder
+	sb.WriteString("_" + i18n.Text(lang, "forecast.legend") + "_\n")
+	for _, categoryID := range order {
+		f := rolledUp[categoryID]
+		fmt.Fprintf(&sb, "%s — %.2f → ~%.2f\n", names[categoryID], f.Spent, f.Projected)
+	}
+	fmt.Fprintf(&sb, "*%s — %.2f → ~%.2f*\n", i18n.Text(lang, "report.total"), rolledUp[0].Spent, rolledUp[0].Projected)
+
+	envelopes, err := monthEnvelopes(ledgerID, monthStart(at), budgets, categories, spendings)
+	if err != nil || len(envelopes) == 0 {
+		return sb.String(), err
+	}
+
+	fmt.Fprintf(&sb, "\n*%s*\n", i18n.Text(lang, "forecast.budgets"))
+	for _, e := range envelopes {
+		projected, available := rolledUp[e.CategoryID].Projected, e.Available()
+		fmt.Fprintf(&sb, "%s — ~%.2f / %.2f", names[e.CategoryID], projected, available)
+		if over := toCents(projected) - toCents(available); over > 0 {
+			fmt.Fprintf(&sb, " ⚠️ %s", i18n.Text(lang, "forecast.over", float64(over)/100))
+		} else {
+			sb.WriteString(" ✅")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String(), nil
+}
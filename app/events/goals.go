@@ -0,0 +1,378 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/looplab/fsm"
+	"github.com/nyanyamaga/finance-tracker-bot/app/i18n"
+	"github.com/nyanyamaga/finance-tracker-bot/app/keyboards"
+	"github.com/nyanyamaga/finance-tracker-bot/app/storage"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// goalProgressWidth is how many blocks the progress bar of a goal has.
+const goalProgressWidth = 10
+
+// goalMinRateDays is the shortest period contributions are averaged over, so a first contribution
+// right after setting a goal doesn't project it reached within days.
+const goalMinRateDays = 7
+
+// ErrInvalidGoal is returned when goal details can't be parsed.
+var ErrInvalidGoal = errors.New("invalid goal")
+
+type BotGoalManager struct {
+	TbKeyboards TbKeyboards
+	Ledgers     LedgerManager
+	Goals       GoalsRepository
+}
+
+// List lists the open goals of the conversation's ledger with their progress,
+// editors get buttons contributing to, editing and closing them.
+func (gm *BotGoalManager) List(lang string, conv Conversation) (string, tbapi.InlineKeyboardMarkup, error) {
+	keyboard := tbapi.InlineKeyboardMarkup{InlineKeyboard: [][]tbapi.InlineKeyboardButton{}}
+
+	member, err := gm.Ledgers.LedgerFor(conv)
+	if err != nil {
+		return "", keyboard, err
+	}
+
+	goals, err := gm.Goals.ListGoals(member.LedgerID)
+	if err != nil {
+		return "", keyboard, err
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "🎯 *%s*\n\n", i18n.Text(lang, "goals.title"))
+	if len(goals) == 0 {
+		sb.WriteString(i18n.Text(lang, "goals.empty") + "\n\n")
+	}
+	now := time.Now()
+	for _, goal := range goals {
+		sb.WriteString(formatGoal(lang, goal, now) + "\n")
+	}
+	sb.WriteString(i18n.Text(lang, "goals.usage"))
+
+	if canEdit(member) {
+		keyboard = gm.TbKeyboards.GetGoalsKeyboard(lang, goals)
+	}
+	return sb.String(), keyboard, nil
+}
+
+// Goal returns a goal of the conversation's ledger.
+func (gm *BotGoalManager) Goal(conv Conversation, goalID int64) (*storage.GoalInfo, error) {
+	return gm.goal(conv, goalID, false)
+}
+
+// AddGoal adds a goal to the conversation's ledger from details like "Vacation: 2000 by June".
+func (gm *BotGoalManager) AddGoal(conv Conversation, details string) (*storage.GoalInfo, error) {
+	member, err := gm.Ledgers.LedgerFor(conv)
+	if err != nil {
+		return nil, err
+	}
+	if !canEdit(member) {
+		return nil, fmt.Errorf("user %d can't add goals to ledger %d: %w", conv.UserID, member.LedgerID, ErrPermissionDenied)
+	}
+
+	goal, err := parseGoal(details, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	goal.LedgerID = member.LedgerID
+	goal.CreatedBy = conv.UserID
+
+	if goal.ID, err = gm.Goals.AddGoal(goal); err != nil {
+		return nil, err
+	}
+	return gm.Goals.GetGoal(goal.ID)
+}
+
+// EditGoal replaces the name, the target and the deadline of a goal with new details.
+func (gm *BotGoalManager) EditGoal(conv Conversation, goalID int64, details string) (*storage.GoalInfo, error) {
+	goal, err := gm.goal(conv, goalID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	edited, err := parseGoal(details, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	goal.Name, goal.Target, goal.Deadline = edited.Name, edited.Target, edited.Deadline
+
+	if err = gm.Goals.UpdateGoal(*goal); err != nil {
+		return nil, err
+	}
+	return goal, nil
+}
+
+// Contribute puts an amount aside for a goal and returns the goal with the contribution.
+func (gm *BotGoalManager) Contribute(conv Conversation, goalID int64, amount float64) (*storage.GoalInfo, error) {
+	if _, err := gm.goal(conv, goalID, true); err != nil {
+		return nil, err
+	}
+
+	contribution := storage.GoalContributionInfo{GoalID: goalID, UserID: conv.UserID, Amount: amount}
+	if err := gm.Goals.AddContribution(contribution); err != nil {
+		return nil, err
+	}
+	return gm.Goals.GetGoal(goalID)
+}
+
+// CloseGoal closes a goal, reached or abandoned.
+func (gm *BotGoalManager) CloseGoal(conv Conversation, goalID int64) error {
+	goal, err := gm.goal(conv, goalID, true)
+	if err != nil {
+		return err
+	}
+	return gm.Goals.CloseGoal(goal.LedgerID, goal.ID)
+}
+
+// goal returns an open goal of the conversation's ledger, checking the user may change it when edit is set.
+func (gm *BotGoalManager) goal(conv Conversation, goalID int64, edit bool) (*storage.GoalInfo, error) {
+	member, err := gm.Ledgers.LedgerFor(conv)
+	if err != nil {
+		return nil, err
+	}
+
+	goal, err := gm.Goals.GetGoal(goalID)
+	if err != nil {
+		return nil, err
+	}
+	if goal.LedgerID != member.LedgerID || goal.Closed {
+		return nil, fmt.Errorf("goal %d is not open in ledger %d: %w", goalID, member.LedgerID, ErrPermissionDenied)
+	}
+	if edit && !canEdit(member) {
+		return nil, fmt.Errorf("user %d can't change goals of ledger %d: %w", conv.UserID, member.LedgerID, ErrPermissionDenied)
+	}
+	return goal, nil
+}
+
+// formatGoal formats a goal with a progress bar, its deadline and when it's reached at the current rate.
+func formatGoal(lang string, goal storage.GoalInfo, now time.Time) string {
+	progress := 0.0
+	if goal.Target > 0 {
+		progress = math.Min(goal.Saved/goal.Target, 1)
+	}
+	filled := int(math.Round(progress * goalProgressWidth))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "*%s* — %.2f / %.2f\n", goal.Name, goal.Saved, goal.Target)
+	fmt.Fprintf(&sb, "`%s%s` %.0f%%\n", strings.Repeat("█", filled), strings.Repeat("░", goalProgressWidth-filled), progress*100)
+
+	if !goal.Deadline.IsZero() {
+		fmt.Fprintf(&sb, "📅 %s\n", i18n.Text(lang, "goals.deadline", goal.Deadline.Format("02.01.06")))
+	}
+
+	if goal.Saved >= goal.Target {
+		sb.WriteString("🎉 " + i18n.Text(lang, "goals.reached") + "\n")
+		return sb.String()
+	}
+
+	completion, ok := projectGoal(goal, now)
+	switch {
+	case !ok:
+		sb.WriteString("⏳ " + i18n.Text(lang, "goals.no_rate") + "\n")
+	case !goal.Deadline.IsZero() && completion.After(goal.Deadline):
+		sb.WriteString("⏳ " + i18n.Text(lang, "goals.projected", completion.Format("02.01.06")) + " ⚠️\n")
+	default:
+		sb.WriteString("⏳ " + i18n.Text(lang, "goals.projected", completion.Format("02.01.06")) + "\n")
+	}
+	return sb.String()
+}
+
+// projectGoal estimates when a goal is reached, keeping the average daily contribution since it was set.
+// Nothing can be projected before the first contribution.
+func projectGoal(goal storage.GoalInfo, now time.Time) (time.Time, bool) {
+	if goal.Saved <= 0 {
+		return time.Time{}, false
+	}
+
+	days := math.Max(now.Sub(goal.Timestamp).Hours()/24, goalMinRateDays)
+	rate := goal.Saved / days
+	remaining := (goal.Target - goal.Saved) / rate
+	if remaining > 100*365 {
+		return time.Time{}, false // not within a lifetime, don't pretend
+	}
+	return now.Add(time.Duration(remaining * 24 * float64(time.Hour))), true
+}
+
+// parseGoal parses goal details like "Vacation: 2000 by June" or "Новая машина: 500000 до 12.2027",
+// the deadline is optional.
+func parseGoal(details string, now time.Time) (storage.GoalInfo, error) {
+	var goal storage.GoalInfo
+
+	idx := strings.LastIndex(details, ":")
+	if idx < 0 {
+		return goal, fmt.Errorf("no target in %q: %w", details, ErrInvalidGoal)
+	}
+	goal.Name = strings.TrimSpace(details[:idx])
+	if goal.Name == "" {
+		return goal, fmt.Errorf("no name in %q: %w", details, ErrInvalidGoal)
+	}
+
+	fields := strings.Fields(details[idx+1:])
+	if len(fields) == 0 {
+		return goal, fmt.Errorf("no target in %q: %w", details, ErrInvalidGoal)
+	}
+
+	target, err := strconv.ParseFloat(strings.ReplaceAll(fields[0], ",", "."), 64)
+	if err != nil || target <= 0 || math.IsInf(target, 0) {
+		return goal, fmt.Errorf("invalid target %q: %w", fields[0], ErrInvalidGoal)
+	}
+	goal.Target = target
+
+	switch {
+	case len(fields) == 1:
+	case len(fields) == 3 && (strings.EqualFold(fields[1], "by") || strings.EqualFold(fields[1], "до")):
+		if goal.Deadline, err = parseDeadline(fields[2], now); err != nil {
+			return goal, fmt.Errorf("%v: %w", err, ErrInvalidGoal)
+		}
+	default:
+		return goal, fmt.Errorf("unexpected %q after the target: %w", strings.Join(fields[1:], " "), ErrInvalidGoal)
+	}
+	return goal, nil
+}
+
+// goalDetails formats a goal the way parseGoal reads it, to be edited.
+func goalDetails(goal storage.GoalInfo) string {
+	details := fmt.Sprintf("%s: %s", goal.Name, strconv.FormatFloat(goal.Target, 'f', -1, 64))
+	if !goal.Deadline.IsZero() {
+		details += " by " + goal.Deadline.Format("02.01.2006")
+	}
+	return details
+}
+
+// promptGoalDetails asks for the name, the target and the deadline of a new goal,
+// or for the new ones of the goal being edited.
+func (sm *BotStateManager) promptGoalDetails(conv Conversation) {
+	stateData, err := sm.getStateData(conv)
+	if err != nil {
+		log.Printf("[warn] error fetching state data: %v", err)
+		return
+	}
+
+	text := sm.text(conv.UserID, "goals.enter_details")
+	if goalID, ok := goalIDValue(stateData, "ChooseEditGoal", keyboards.GoalEditPrefix); ok {
+		goal, err := sm.Goals.Goal(conv, goalID)
+		if err != nil {
+			log.Printf("[warn] error fetching goal %d: %v", goalID, err)
+			return
+		}
+		text = sm.text(conv.UserID, "goals.edit_details", goalDetails(*goal))
+	}
+
+	if err = sm.sendBotResponse(conv, text, nil); err != nil {
+		log.Printf("[warn] error sending goal details prompt: %v", err)
+	}
+}
+
+// validateGoalDetails cancels the goal details transition when they can't be parsed,
+// so the user can try again.
+func (sm *BotStateManager) validateGoalDetails(e *fsm.Event, conv Conversation) {
+	if _, err := parseGoal(sm.UserValues[conv], time.Now()); err != nil {
+		e.Cancel(err)
+
+		if err := sm.sendBotResponse(conv, sm.text(conv.UserID, "goals.invalid"), nil); err != nil {
+			log.Printf("[warn] error sending invalid goal message: %v", err)
+		}
+	}
+}
+
+// saveGoal adds the new goal or updates the edited one, then returns to the main menu either way.
+func (sm *BotStateManager) saveGoal(ctx context.Context, conv Conversation) {
+	defer func() {
+		if err := sm.UserFSMs[conv].Event(ctx, "GoalSaved"); err != nil {
+			log.Printf("[warn] error transitioning to Idle after saving goal for %v: %v", conv, err)
+		}
+	}()
+
+	stateData, err := sm.getStateData(conv)
+	if err != nil {
+		log.Printf("[warn] error fetching state data: %v", err)
+		return
+	}
+
+	var goal *storage.GoalInfo
+	details := stringValue(stateData, "GoalDetailsEntered")
+	if goalID, ok := goalIDValue(stateData, "ChooseEditGoal", keyboards.GoalEditPrefix); ok {
+		goal, err = sm.Goals.EditGoal(conv, goalID, details)
+	} else {
+		goal, err = sm.Goals.AddGoal(conv, details)
+	}
+
+	text := sm.text(conv.UserID, "error.generic")
+	if err != nil {
+		log.Printf("[warn] error saving goal for %v: %v", conv, err)
+	} else {
+		text = sm.text(conv.UserID, "goals.saved") + "\n\n" + formatGoal(sm.Language(conv.UserID), *goal, time.Now())
+	}
+	if err = sm.sendBotResponse(conv, text, nil); err != nil {
+		log.Printf("[warn] error sending goal saved message: %v", err)
+	}
+}
+
+// promptContributionAmount asks how much is put aside for the chosen goal.
+func (sm *BotStateManager) promptContributionAmount(conv Conversation) {
+	stateData, err := sm.getStateData(conv)
+	if err != nil {
+		log.Printf("[warn] error fetching state data: %v", err)
+		return
+	}
+
+	goalID, _ := goalIDValue(stateData, "ChooseContribute", keyboards.GoalContributePrefix)
+	goal, err := sm.Goals.Goal(conv, goalID)
+	if err != nil {
+		log.Printf("[warn] error fetching goal %d: %v", goalID, err)
+		return
+	}
+
+	text := formatGoal(sm.Language(conv.UserID), *goal, time.Now()) + "\n" + sm.text(conv.UserID, "goals.enter_contribution")
+	if err = sm.sendBotResponse(conv, text, nil); err != nil {
+		log.Printf("[warn] error sending contribution prompt: %v", err)
+	}
+}
+
+// saveContribution records the contribution to the chosen goal, then returns to the main menu either way.
+func (sm *BotStateManager) saveContribution(ctx context.Context, conv Conversation) {
+	defer func() {
+		if err := sm.UserFSMs[conv].Event(ctx, "ContributionSaved"); err != nil {
+			log.Printf("[warn] error transitioning to Idle after saving contribution for %v: %v", conv, err)
+		}
+	}()
+
+	stateData, err := sm.getStateData(conv)
+	if err != nil {
+		log.Printf("[warn] error fetching state data: %v", err)
+		return
+	}
+
+	goalID, _ := goalIDValue(stateData, "ChooseContribute", keyboards.GoalContributePrefix)
+	amount, _, err := parseAmountInput(stringValue(stateData, "ContributionEntered"))
+	if err != nil {
+		log.Printf("[warn] error converting contribution to float: %v", err)
+		return
+	}
+
+	text := sm.text(conv.UserID, "error.generic")
+	goal, err := sm.Goals.Contribute(conv, goalID, amount)
+	if err != nil {
+		log.Printf("[warn] error saving contribution for %v: %v", conv, err)
+	} else {
+		text = sm.text(conv.UserID, "goals.contributed", amount) + "\n\n" + formatGoal(sm.Language(conv.UserID), *goal, time.Now())
+	}
+	if err = sm.sendBotResponse(conv, text, nil); err != nil {
+		log.Printf("[warn] error sending contribution saved message: %v", err)
+	}
+}
+
+// goalIDValue returns the goal ID of a goal button value of the conversation state data.
+func goalIDValue(stateData map[string]interface{}, key, prefix string) (int64, bool) {
+	goalID, err := strconv.ParseInt(strings.TrimPrefix(stringValue(stateData, key), prefix), 10, 64)
+	return goalID, err == nil
+}
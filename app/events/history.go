@@ -0,0 +1,103 @@
+package events
+
+import (
+	"fmt"
+	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/nyanyamaga/finance-tracker-bot/app/i18n"
+	"github.com/nyanyamaga/finance-tracker-bot/app/keyboards"
+	"github.com/nyanyamaga/finance-tracker-bot/app/storage"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// historyPageSize is how many spendings a page of the history lists.
+const historyPageSize = 10
+
+// History lists a page of the conversation's ledger spendings within the period, the latest first.
+// The mine scope limits it to spendings recorded by the user, the cursor points at the page.
+func (r *BotReporter) History(lang string, conv Conversation, period, scope string, cursor storage.SpendingCursor) (string, tbapi.InlineKeyboardMarkup, error) {
+	keyboard := tbapi.InlineKeyboardMarkup{InlineKeyboard: [][]tbapi.InlineKeyboardButton{}}
+
+	member, err := r.Ledgers.LedgerFor(conv)
+	if err != nil {
+		return "", keyboard, err
+	}
+
+	members, err := r.Ledgers.Members(member.LedgerID)
+	if err != nil {
+		return "", keyboard, err
+	}
+
+	filter := storage.SpendingFilter{LedgerID: member.LedgerID}
+	title := i18n.Text(lang, "history.period_all")
+	if period != keyboards.HistoryAll {
+		filter.From, filter.To, title = chartPeriod(lang, period, time.Now())
+	}
+	if scope == keyboards.HistoryMine {
+		filter.UserID = conv.UserID
+	}
+
+	// one spending more than a page tells whether there is a page further in the same direction
+	records, err := r.Spendings.ListSpendings(filter, cursor, historyPageSize+1)
+	if err != nil {
+		return "", keyboard, err
+	}
+
+	more := len(records) > historyPageSize
+	if cursor.Backward && !more {
+		// back at the latest spendings, show a full first page rather than what's left of it
+		return r.History(lang, conv, period, scope, storage.SpendingCursor{})
+	}
+	if more && cursor.Backward {
+		records = records[1:]
+	} else if more {
+		records = records[:historyPageSize]
+	}
+
+	var newerID, olderID int64
+	if len(records) > 0 {
+		if cursor.ID != 0 && (!cursor.Backward || more) {
+			newerID = records[0].ID
+		}
+		if cursor.Backward || more {
+			olderID = records[len(records)-1].ID
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "🧾 *%s* · %s\n\n", i18n.Text(lang, "history.title"), title)
+	if len(records) == 0 {
+		sb.WriteString(i18n.Text(lang, "history.empty"))
+	}
+	var receipts []storage.SpendingRecord
+	for _, record := range records {
+		sb.WriteString(formatSpendingRecord(record))
+		if record.Receipts > 0 {
+			receipts = append(receipts, record)
+		}
+	}
+
+	keyboard = r.TbKeyboards.GetHistoryKeyboard(lang, period, scope, newerID, olderID, len(members) > 1, receipts)
+	return sb.String(), keyboard, nil
+}
+
+// parseHistoryCallback splits history callback data into the period, the scope and the page cursor.
+func parseHistoryCallback(data string) (string, string, storage.SpendingCursor, error) {
+	var cursor storage.SpendingCursor
+
+	parts := strings.Split(strings.TrimPrefix(data, keyboards.HistoryPrefix), "_")
+	if len(parts) != 3 || parts[2] == "" {
+		return "", "", cursor, fmt.Errorf("invalid history callback %q", data)
+	}
+
+	if parts[2] != "0" {
+		cursor.Backward = parts[2][0] == 'n'
+		id, err := strconv.ParseInt(parts[2][1:], 10, 64)
+		if err != nil {
+			return "", "", cursor, fmt.Errorf("invalid history cursor %q: %w", data, err)
+		}
+		cursor.ID = id
+	}
+	return parts[0], parts[1], cursor, nil
+}
@@ -0,0 +1,207 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/nyanyamaga/finance-tracker-bot/app/i18n"
+	"github.com/nyanyamaga/finance-tracker-bot/app/storage"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Inline mode defaults
+const (
+	inlineMaxResults   = 50 // the most results Telegram accepts in a single answer
+	inlineMinWordLen   = 3  // shorter words of a description don't name categories
+	inlineStartParam   = "inline"
+	inlineResultPrefix = "category_"
+)
+
+// BotInlineHandler logs spendings from any chat through inline queries like "@bot 12.50 coffee".
+// Spendings go to the active ledger of the querying user, as if they were entered in the private chat.
+type BotInlineHandler struct {
+	TbAPI        TbAPI
+	StateManager StateManager
+	Ledgers      LedgerManager
+	Categories   CategoriesRepository
+	Spendings    SpendingsRepository
+	Rules        RuleManager
+	Accounts     AccountManager
+}
+
+// HandleInlineQuery offers a result per category for the amount and description typed after the bot's name,
+// matching categories first. Queries without an amount get a hint instead.
+func (h *BotInlineHandler) HandleInlineQuery(_ context.Context, update tbapi.Update) {
+	query := update.InlineQuery
+	if query == nil {
+		return
+	}
+
+	lang := h.StateManager.Language(query.From.ID)
+	answer := tbapi.InlineConfig{InlineQueryID: query.ID, IsPersonal: true, Results: []interface{}{}}
+
+	categories, amount, description, err := h.offer(query.From.ID, query.Query)
+	switch {
+	case errors.Is(err, ErrPermissionDenied):
+		answer.SwitchPMText, answer.SwitchPMParameter = i18n.Text(lang, "inline.read_only"), inlineStartParam
+	case err != nil:
+		answer.SwitchPMText, answer.SwitchPMParameter = i18n.Text(lang, "inline.hint"), inlineStartParam
+	}
+
+	for _, c := range categories {
+		title := strings.TrimSpace(c.Emoji + " " + c.Name)
+		text := i18n.Text(lang, "inline.result", amount, title)
+		if description != "" {
+			text += " · " + description
+		}
+		result := tbapi.NewInlineQueryResultArticle(fmt.Sprintf("%s%d", inlineResultPrefix, c.ID), title, text)
+		result.Description = strings.TrimSpace(fmt.Sprintf("%.2f %s", amount, description))
+		answer.Results = append(answer.Results, result)
+	}
+
+	if _, err = h.TbAPI.Request(answer); err != nil {
+		log.Printf("[warn] error answering inline query of user %d: %v", query.From.ID, err)
+	}
+}
+
+// HandleChosenInlineResult records the spending of the result picked by the user.
+// Telegram only reports picked results when inline feedback is enabled with BotFather.
+func (h *BotInlineHandler) HandleChosenInlineResult(_ context.Context, update tbapi.Update) {
+	chosen := update.ChosenInlineResult
+	if chosen == nil {
+		return
+	}
+
+	log.Printf("[info] handling chosen inline result: user %d, result %s", chosen.From.ID, chosen.ResultID)
+
+	err := h.record(chosen.From.ID, chosen.ResultID, chosen.Query)
+	if err == nil {
+		return
+	}
+
+	text := i18n.Text(h.StateManager.Language(chosen.From.ID), "error.generic")
+	if errors.Is(err, ErrPermissionDenied) {
+		text = i18n.Text(h.StateManager.Language(chosen.From.ID), "ledger.read_only")
+	} else {
+		log.Printf("[error] error recording inline spending of user %d: %v", chosen.From.ID, err)
+	}
+	if err = send(tbapi.NewMessage(chosen.From.ID, text), h.TbAPI); err != nil {
+		log.Printf("[warn] error sending inline spending failure: %v", err)
+	}
+}
+
+// offer parses the query and returns the categories of the user's active ledger to offer,
+// the one suggested by the ledger's rules first, then the ones named in the description.
+func (h *BotInlineHandler) offer(userID int64, query string) ([]storage.CategoryInfo, float64, string, error) {
+	amount, description, err := parseAmountInput(query)
+	if err != nil {
+		return nil, 0, "", err
+	}
+
+	member, err := h.Ledgers.LedgerFor(Conversation{ChatID: userID, UserID: userID})
+	if err != nil {
+		return nil, 0, "", err
+	}
+	if !canEdit(member) {
+		return nil, 0, "", fmt.Errorf("user %d can't add spendings to ledger %d: %w", userID, member.LedgerID, ErrPermissionDenied)
+	}
+
+	categories, err := h.Categories.ListCategories(member.LedgerID)
+	if err != nil {
+		return nil, 0, "", err
+	}
+
+	suggestedID, err := h.Rules.Suggest(member.LedgerID, description)
+	if err != nil {
+		log.Printf("[warn] error suggesting category for user %d: %v", userID, err)
+	}
+
+	var suggested, named, rest []storage.CategoryInfo
+	words := strings.Fields(strings.ToLower(description))
+	for _, c := range categories {
+		switch {
+		case c.ID == suggestedID:
+			suggested = append(suggested, c)
+		case namesCategory(words, c):
+			named = append(named, c)
+		default:
+			rest = append(rest, c)
+		}
+	}
+
+	offered := append(append(suggested, named...), rest...)
+	if len(offered) > inlineMaxResults {
+		offered = offered[:inlineMaxResults]
+	}
+	return offered, amount, description, nil
+}
+
+// record saves the spending of the query to the category of the picked result, paid from the default account.
+func (h *BotInlineHandler) record(userID int64, resultID, query string) error {
+	categoryID, err := strconv.ParseInt(strings.TrimPrefix(resultID, inlineResultPrefix), 10, 64)
+	if err != nil || !strings.HasPrefix(resultID, inlineResultPrefix) {
+		return fmt.Errorf("invalid inline result %q", resultID)
+	}
+
+	amount, description, err := parseAmountInput(query)
+	if err != nil {
+		return err
+	}
+
+	conv := Conversation{ChatID: userID, UserID: userID}
+	member, err := h.Ledgers.LedgerFor(conv)
+	if err != nil {
+		return err
+	}
+	if !canEdit(member) {
+		return fmt.Errorf("user %d can't add spendings to ledger %d: %w", userID, member.LedgerID, ErrPermissionDenied)
+	}
+
+	category, err := h.Categories.GetCategory(categoryID)
+	if err != nil {
+		return err
+	}
+	if category.LedgerID != member.LedgerID {
+		return fmt.Errorf("category %d isn't in ledger %d: %w", categoryID, member.LedgerID, ErrCategoryNotFound)
+	}
+
+	accounts, err := h.Accounts.LedgerAccounts(conv)
+	if err != nil {
+		return err
+	}
+	accountID, err := h.Accounts.DefaultAccount(conv, accounts)
+	if err != nil {
+		return err
+	}
+
+	_, err = h.Spendings.AddSpending(storage.SpendingInfo{
+		UserID:      userID,
+		LedgerID:    member.LedgerID,
+		AccountID:   accountID,
+		CategoryID:  categoryID,
+		Amount:      amount,
+		Description: description,
+		Timestamp:   time.Now(),
+		Tags:        parseTags(description),
+	})
+	return err
+}
+
+// namesCategory reports whether one of the words is the category's emoji or shares the start
+// with its name, like "coffee" and "Coffee shops".
+func namesCategory(words []string, category storage.CategoryInfo) bool {
+	name := strings.ToLower(category.Name)
+	for _, word := range words {
+		if category.Emoji != "" && word == category.Emoji {
+			return true
+		}
+		if len([]rune(word)) >= inlineMinWordLen && (strings.HasPrefix(name, word) || strings.HasPrefix(word, name)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package events
+
+import (
+	"fmt"
+	"github.com/nyanyamaga/finance-tracker-bot/app/i18n"
+	"github.com/nyanyamaga/finance-tracker-bot/app/storage"
+	"log"
+)
+
+// Language returns the language the user talks to the bot in.
+// Languages are cached in memory, the settings storage is read only once per user.
+func (sm *BotStateManager) Language(userID int64) string {
+	if lang, ok := sm.UserLanguages[userID]; ok {
+		return lang
+	}
+
+	lang := i18n.DefaultLanguage
+	if settings, err := sm.UserSettings.Read(userID); err == nil && settings.Language != "" {
+		lang = i18n.Normalize(settings.Language)
+	}
+
+	sm.UserLanguages[userID] = lang
+	return lang
+}
+
+// RememberLanguage stores the language reported by Telegram for users who haven't chosen one yet.
+func (sm *BotStateManager) RememberLanguage(userID int64, languageCode string) {
+	if _, ok := sm.UserLanguages[userID]; ok {
+		return
+	}
+
+	if settings, err := sm.UserSettings.Read(userID); err == nil && settings.Language != "" {
+		sm.UserLanguages[userID] = i18n.Normalize(settings.Language)
+		return
+	}
+
+	if err := sm.SetLanguage(userID, i18n.Normalize(languageCode)); err != nil {
+		log.Printf("[warn] error remembering language for user %d: %v", userID, err)
+	}
+}
+
+// SetLanguage persists the user's language choice.
+func (sm *BotStateManager) SetLanguage(userID int64, lang string) error {
+	lang = i18n.Normalize(lang)
+	err := updateUserSettings(sm.UserSettings, userID, func(settings *storage.UserSettingsInfo) {
+		settings.Language = lang
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save language for user %d: %w", userID, err)
+	}
+
+	sm.UserLanguages[userID] = lang
+	return nil
+}
+
+// text returns a catalog message in the user's language.
+func (sm *BotStateManager) text(userID int64, key string, args ...interface{}) string {
+	return i18n.Text(sm.Language(userID), key, args...)
+}
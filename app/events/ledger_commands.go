@@ -0,0 +1,127 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/nyanyamaga/finance-tracker-bot/app/i18n"
+	"github.com/nyanyamaga/finance-tracker-bot/app/storage"
+	"strings"
+)
+
+func (h *BotCommandHandler) listLedgers(msg *tbapi.Message) {
+	userID := msg.From.ID
+	lang := h.StateManager.Language(userID)
+
+	member, err := h.Ledgers.ActiveLedger(userID)
+	if err != nil {
+		h.replyError(msg, err)
+		return
+	}
+
+	ledgers, err := h.Ledgers.UserLedgers(userID)
+	if err != nil {
+		h.replyError(msg, err)
+		return
+	}
+
+	h.reply(msg, i18n.Text(lang, "ledger.list"), h.TbKeyboards.GetLedgerKeyboard(ledgers, member.LedgerID))
+}
+
+func (h *BotCommandHandler) createLedger(msg *tbapi.Message, name string) {
+	userID := msg.From.ID
+	lang := h.StateManager.Language(userID)
+
+	ledger, err := h.Ledgers.CreateLedger(userID, name)
+	if err != nil {
+		h.replyError(msg, err)
+		return
+	}
+
+	h.reply(msg, i18n.Text(lang, "ledger.created", ledger.Name), tbapi.InlineKeyboardMarkup{})
+}
+
+func (h *BotCommandHandler) invite(msg *tbapi.Message, role string) {
+	userID := msg.From.ID
+	lang := h.StateManager.Language(userID)
+
+	role = strings.ToLower(role)
+	if role == "" {
+		role = storage.RoleEditor
+	}
+	if role != storage.RoleEditor && role != storage.RoleViewer {
+		h.reply(msg, i18n.Text(lang, "ledger.invite_usage"), tbapi.InlineKeyboardMarkup{})
+		return
+	}
+
+	invite, err := h.Ledgers.CreateInvite(userID, role)
+	if errors.Is(err, ErrPermissionDenied) {
+		h.reply(msg, i18n.Text(lang, "ledger.owner_only"), tbapi.InlineKeyboardMarkup{})
+		return
+	}
+	if err != nil {
+		h.replyError(msg, err)
+		return
+	}
+
+	ledger, err := h.Ledgers.Ledger(invite.LedgerID)
+	if err != nil {
+		h.replyError(msg, err)
+		return
+	}
+
+	// the link goes into a button as well, markdown mangles underscores in plain links
+	link := fmt.Sprintf("https://t.me/%s?start=%s%s", h.BotUsername, joinPayloadPrefix, invite.Code)
+	text := i18n.Text(lang, "ledger.invite", ledger.Name, i18n.Text(lang, "role."+role), link)
+	keyboard := tbapi.NewInlineKeyboardMarkup(tbapi.NewInlineKeyboardRow(
+		tbapi.NewInlineKeyboardButtonURL(i18n.Text(lang, "ledger.invite_button", ledger.Name), link),
+	))
+	h.reply(msg, text, keyboard)
+}
+
+func (h *BotCommandHandler) joinLedger(msg *tbapi.Message, code string) {
+	userID := msg.From.ID
+	lang := h.StateManager.Language(userID)
+
+	ledger, err := h.Ledgers.JoinLedger(userID, code)
+	if errors.Is(err, storage.ErrInviteNotFound) {
+		h.reply(msg, i18n.Text(lang, "ledger.invite_invalid"), tbapi.InlineKeyboardMarkup{})
+		return
+	}
+	if err != nil {
+		h.replyError(msg, err)
+		return
+	}
+
+	h.reply(msg, i18n.Text(lang, "ledger.joined", ledger.Name), tbapi.InlineKeyboardMarkup{})
+}
+
+func (h *BotCommandHandler) listMembers(msg *tbapi.Message) {
+	userID := msg.From.ID
+	lang := h.StateManager.Language(userID)
+
+	member, err := h.Ledgers.LedgerFor(ConversationOf(msg))
+	if err != nil {
+		h.replyError(msg, err)
+		return
+	}
+
+	ledger, err := h.Ledgers.Ledger(member.LedgerID)
+	if err != nil {
+		h.replyError(msg, err)
+		return
+	}
+
+	members, err := h.Ledgers.Members(member.LedgerID)
+	if err != nil {
+		h.replyError(msg, err)
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString(i18n.Text(lang, "ledger.members", ledger.Name))
+	for _, m := range members {
+		fmt.Fprintf(&sb, "\n👤 %s — %s", m.Name, i18n.Text(lang, "role."+m.Role))
+	}
+	h.reply(msg, sb.String(), tbapi.InlineKeyboardMarkup{})
+}
@@ -0,0 +1,205 @@
+package events
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/nyanyamaga/finance-tracker-bot/app/storage"
+	"log"
+	"time"
+)
+
+// inviteTTL is how long an invitation code stays valid.
+const inviteTTL = 7 * 24 * time.Hour
+
+// ErrPermissionDenied is returned when the user's role in a ledger doesn't allow an action.
+var ErrPermissionDenied = errors.New("permission denied")
+
+type BotLedgerManager struct {
+	Ledgers      LedgersRepository
+	UserSettings UserSettingsRepository
+	MemberNames  map[int64]string
+	ChatTitles   map[int64]string
+}
+
+func NewBotLedgerManager(lRepository LedgersRepository, ssRepository UserSettingsRepository) *BotLedgerManager {
+	return &BotLedgerManager{
+		Ledgers:      lRepository,
+		UserSettings: ssRepository,
+		MemberNames:  make(map[int64]string),
+		ChatTitles:   make(map[int64]string),
+	}
+}
+
+// ActiveLedger returns the user's membership in the ledger new records go to.
+// Users without an active ledger are switched to their personal one, which is created on first use.
+func (lm *BotLedgerManager) ActiveLedger(userID int64) (*storage.LedgerMemberInfo, error) {
+	if settings, err := lm.UserSettings.Read(userID); err == nil && settings.LedgerID != 0 {
+		if member, err := lm.Ledgers.GetMember(settings.LedgerID, userID); err == nil {
+			return member, nil
+		}
+	}
+
+	ledgers, err := lm.Ledgers.ListUserLedgers(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var personal *storage.LedgerInfo
+	for i := range ledgers {
+		if ledgers[i].Personal && ledgers[i].OwnerID == userID {
+			personal = &ledgers[i]
+			break
+		}
+	}
+
+	if personal == nil {
+		info := storage.LedgerInfo{Name: "Personal", OwnerID: userID, Personal: true}
+		if personal, err = lm.Ledgers.CreateLedger(info, lm.MemberNames[userID]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = lm.SwitchLedger(userID, personal.ID); err != nil {
+		return nil, err
+	}
+	return lm.Ledgers.GetMember(personal.ID, userID)
+}
+
+// LedgerFor returns the user's membership in the ledger a conversation records to:
+// the shared ledger of the chat in groups and the user's active ledger in private chats.
+// Group ledgers are created on first use, everyone talking to the bot in the group joins as an editor.
+func (lm *BotLedgerManager) LedgerFor(conv Conversation) (*storage.LedgerMemberInfo, error) {
+	if !conv.IsGroup() {
+		return lm.ActiveLedger(conv.UserID)
+	}
+
+	ledger, err := lm.Ledgers.GetChatLedger(conv.ChatID)
+	if errors.Is(err, sql.ErrNoRows) {
+		name := lm.ChatTitles[conv.ChatID]
+		if name == "" {
+			name = "Group"
+		}
+		info := storage.LedgerInfo{Name: name, OwnerID: conv.UserID, ChatID: conv.ChatID}
+		ledger, err = lm.Ledgers.CreateLedger(info, lm.MemberNames[conv.UserID])
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	member := storage.LedgerMemberInfo{LedgerID: ledger.ID, UserID: conv.UserID, Role: storage.RoleEditor, Name: lm.MemberNames[conv.UserID]}
+	if err = lm.Ledgers.AddMember(member); err != nil {
+		return nil, err
+	}
+	return lm.Ledgers.GetMember(ledger.ID, conv.UserID)
+}
+
+// SwitchLedger makes the ledger active for the user, who must be its member.
+func (lm *BotLedgerManager) SwitchLedger(userID, ledgerID int64) error {
+	if _, err := lm.Ledgers.GetMember(ledgerID, userID); err != nil {
+		return fmt.Errorf("user %d is not a member of ledger %d: %w", userID, ledgerID, ErrPermissionDenied)
+	}
+
+	return updateUserSettings(lm.UserSettings, userID, func(settings *storage.UserSettingsInfo) {
+		settings.LedgerID = ledgerID
+	})
+}
+
+// CreateLedger creates a shared ledger owned by the user and makes it active.
+func (lm *BotLedgerManager) CreateLedger(userID int64, name string) (*storage.LedgerInfo, error) {
+	ledger, err := lm.Ledgers.CreateLedger(storage.LedgerInfo{Name: name, OwnerID: userID}, lm.MemberNames[userID])
+	if err != nil {
+		return nil, err
+	}
+
+	if err = lm.SwitchLedger(userID, ledger.ID); err != nil {
+		return nil, err
+	}
+	return ledger, nil
+}
+
+// CreateInvite creates a one-time code inviting to the user's active ledger with the given role.
+// Only ledger owners can invite.
+func (lm *BotLedgerManager) CreateInvite(userID int64, role string) (*storage.LedgerInviteInfo, error) {
+	if role != storage.RoleEditor && role != storage.RoleViewer {
+		return nil, fmt.Errorf("unsupported role %q", role)
+	}
+
+	member, err := lm.ActiveLedger(userID)
+	if err != nil {
+		return nil, err
+	}
+	if member.Role != storage.RoleOwner {
+		return nil, fmt.Errorf("user %d can't invite to ledger %d: %w", userID, member.LedgerID, ErrPermissionDenied)
+	}
+
+	code := make([]byte, 12)
+	if _, err = rand.Read(code); err != nil {
+		return nil, fmt.Errorf("failed to generate invite code: %w", err)
+	}
+
+	invite := storage.LedgerInviteInfo{
+		Code:      base64.RawURLEncoding.EncodeToString(code),
+		LedgerID:  member.LedgerID,
+		Role:      role,
+		CreatedBy: userID,
+		ExpiresAt: time.Now().Add(inviteTTL),
+	}
+	if err = lm.Ledgers.CreateInvite(invite); err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+// JoinLedger redeems an invite code and makes the joined ledger active.
+func (lm *BotLedgerManager) JoinLedger(userID int64, code string) (*storage.LedgerInfo, error) {
+	invite, err := lm.Ledgers.UseInvite(code, userID, lm.MemberNames[userID], time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if err = lm.SwitchLedger(userID, invite.LedgerID); err != nil {
+		return nil, err
+	}
+	return lm.Ledgers.GetLedger(invite.LedgerID)
+}
+
+// UserLedgers returns all ledgers the user is a member of.
+func (lm *BotLedgerManager) UserLedgers(userID int64) ([]storage.LedgerInfo, error) {
+	return lm.Ledgers.ListUserLedgers(userID)
+}
+
+// Ledger returns a ledger by its ID.
+func (lm *BotLedgerManager) Ledger(ledgerID int64) (*storage.LedgerInfo, error) {
+	return lm.Ledgers.GetLedger(ledgerID)
+}
+
+// Members returns all members of a ledger.
+func (lm *BotLedgerManager) Members(ledgerID int64) ([]storage.LedgerMemberInfo, error) {
+	return lm.Ledgers.ListMembers(ledgerID)
+}
+
+// RememberName keeps member display names up to date, writing only when a name changes.
+func (lm *BotLedgerManager) RememberName(userID int64, name string) {
+	if lm.MemberNames[userID] == name {
+		return
+	}
+
+	if err := lm.Ledgers.UpdateMemberName(userID, name); err != nil {
+		log.Printf("[warn] error updating member name for user %d: %v", userID, err)
+		return
+	}
+	lm.MemberNames[userID] = name
+}
+
+// RememberChat keeps the title of a group chat, used to name its ledger.
+func (lm *BotLedgerManager) RememberChat(chatID int64, title string) {
+	lm.ChatTitles[chatID] = title
+}
+
+// canEdit reports whether a member may add records to the ledger.
+func canEdit(member *storage.LedgerMemberInfo) bool {
+	return member.Role == storage.RoleOwner || member.Role == storage.RoleEditor
+}
@@ -12,6 +12,11 @@ type TelegramListener struct {
 	MessageHandler       MessageHandler
 	CommandHandler       CommandHandler
 	CallbackQueryHandler CallbackQueryHandler
+	InlineHandler        InlineHandler
+	Access               AccessControl
+	Admins               AdminManager
+	StateManager         StateManager
+	Ledgers              LedgerManager
 }
 
 func (l *TelegramListener) StartListening(ctx context.Context) error {
@@ -32,6 +37,19 @@ func (l *TelegramListener) StartListening(ctx context.Context) error {
 				return fmt.Errorf("telegram updates channel closed")
 			}
 
+			if !l.Access.Allow(update) {
+				continue
+			}
+
+			if from := update.SentFrom(); from != nil {
+				l.StateManager.RememberLanguage(from.ID, from.LanguageCode)
+				l.Admins.Seen(from.ID)
+				l.Ledgers.RememberName(from.ID, displayName(from))
+			}
+			if chat := update.FromChat(); chat != nil && !chat.IsPrivate() {
+				l.Ledgers.RememberChat(chat.ID, chat.Title)
+			}
+
 			if update.Message != nil {
 				if update.Message.IsCommand() {
 					l.CommandHandler.HandleCommands(ctx, update)
@@ -40,6 +58,10 @@ func (l *TelegramListener) StartListening(ctx context.Context) error {
 				}
 			} else if update.CallbackQuery != nil {
 				l.CallbackQueryHandler.HandleCallbackQuery(ctx, update)
+			} else if update.InlineQuery != nil {
+				l.InlineHandler.HandleInlineQuery(ctx, update)
+			} else if update.ChosenInlineResult != nil {
+				l.InlineHandler.HandleChosenInlineResult(ctx, update)
 			}
 		}
 	}
@@ -2,36 +2,69 @@ package events
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/looplab/fsm"
+	"github.com/nyanyamaga/finance-tracker-bot/app/i18n"
 	"github.com/nyanyamaga/finance-tracker-bot/app/keyboards"
+	"github.com/nyanyamaga/finance-tracker-bot/app/storage"
 	"log"
+	"time"
 )
 
 type BotMessageHandler struct {
 	TbAPI        TbAPI
 	StateManager StateManager
+	Receipts     ReceiptManager
+	Parser       MessageParser
 }
 
 func (h *BotMessageHandler) HandleMessages(ctx context.Context, update tbapi.Update) {
 	var err error
 
-	userID := update.Message.From.ID
+	conv := ConversationOf(update.Message)
 	messageText := update.Message.Text
 
-	switch messageText {
-	case keyboards.ActionMessages[keyboards.ActionAddSpending]:
-		err = h.StateManager.TriggerStateChange(ctx, userID, "ChooseAddSpending", "")
-	case keyboards.ActionMessages[keyboards.ActionNewSpendingCategory]:
-		err = h.StateManager.TriggerStateChange(ctx, userID, "ChooseAddCategory", "")
+	h.StateManager.TrackMessage(conv, update.Message.MessageID)
+
+	if receipt, ok := receiptOf(update.Message); ok {
+		h.attachReceipt(update.Message, receipt)
+		return
+	}
+
+	action, _ := keyboards.MatchAction(messageText)
+
+	switch action {
+	case keyboards.ActionAddSpending:
+		err = h.StateManager.TriggerStateChange(ctx, conv, "ChooseAddSpending", "")
+	case keyboards.ActionNewSpendingCategory:
+		err = h.StateManager.TriggerStateChange(ctx, conv, "ChooseAddCategory", "")
 	default:
-		currentState, stateErr := h.StateManager.GetCurrentState(ctx, userID)
+		currentState, stateErr := h.StateManager.GetCurrentState(ctx, conv)
+		idle := stateErr != nil || currentState.Current() == "Idle"
+		if conv.IsGroup() && idle {
+			return // regular group chatter, the member isn't talking to the bot
+		}
+		if idle {
+			// a forwarded bank message or a receipt QR code, confirmed like a quick entry.
+			// Bank messages may start with a number too, so they're tried before quick entries.
+			if spending, ok := h.Parser.Parse(messageText, time.Now()); ok {
+				err = h.StateManager.EnterParsedSpending(ctx, conv, spending)
+				break
+			}
+		}
+		if _, _, amountErr := parseAmountInput(messageText); idle && amountErr == nil {
+			// quick entry, an amount with a description records a spending without going through the menu
+			err = h.StateManager.TriggerStateChange(ctx, conv, "AmountEntered", messageText)
+			break
+		}
 		if stateErr != nil {
 			err = fmt.Errorf("failed to get current state: %v", stateErr)
 			break
 		}
 
-		nextStates := currentState.AvailableTransitions()
+		nextStates := inputTransitions(currentState)
 		if len(nextStates) == 0 {
 			err = fmt.Errorf("no available transitions from current state")
 			break
@@ -40,10 +73,57 @@ func (h *BotMessageHandler) HandleMessages(ctx context.Context, update tbapi.Upd
 			break
 		}
 
-		err = h.StateManager.TriggerStateChange(ctx, userID, nextStates[0], messageText)
+		err = h.StateManager.TriggerStateChange(ctx, conv, nextStates[0], messageText)
 	}
 
 	if err != nil {
 		log.Printf("[warn] error triggering state change: %v", err)
 	}
 }
+
+// attachReceipt attaches a photo or a document to the saved spending the message replies to,
+// or keeps it for the spending being added.
+func (h *BotMessageHandler) attachReceipt(msg *tbapi.Message, receipt storage.ReceiptInfo) {
+	conv := ConversationOf(msg)
+	lang := h.StateManager.Language(conv.UserID)
+
+	var err error
+	key := "receipt.pending"
+	if msg.ReplyToMessage != nil {
+		err = h.Receipts.AttachToMessage(conv, msg.ReplyToMessage.MessageID, receipt)
+		key = "receipt.attached"
+	} else {
+		err = h.StateManager.AttachReceipt(conv, receipt)
+	}
+
+	switch {
+	case errors.Is(err, ErrNotSpendingMessage), errors.Is(err, ErrNotAddingSpending):
+		if conv.IsGroup() {
+			return // photos shared in a group aren't necessarily receipts
+		}
+		key = "receipt.hint"
+	case errors.Is(err, ErrPermissionDenied):
+		key = "ledger.read_only"
+	case err != nil:
+		log.Printf("[warn] error attaching receipt for %v: %v", conv, err)
+		key = "error.generic"
+	}
+
+	tbMsg := tbapi.NewMessage(conv.ChatID, i18n.Text(lang, key))
+	tbMsg.ReplyToMessageID = msg.MessageID
+	if err = send(tbMsg, h.TbAPI); err != nil {
+		log.Printf("[warn] error sending receipt reply: %v", err)
+	}
+}
+
+// inputTransitions returns the transitions an answer to the current prompt may trigger,
+// leaving out optional steps, which are opened by their buttons only.
+func inputTransitions(userFSM *fsm.FSM) []string {
+	var transitions []string
+	for _, t := range userFSM.AvailableTransitions() {
+		if !optionalSteps[t] {
+			transitions = append(transitions, t)
+		}
+	}
+	return transitions
+}
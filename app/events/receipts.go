@@ -0,0 +1,173 @@
+package events
+
+import (
+	"errors"
+	"fmt"
+	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/nyanyamaga/finance-tracker-bot/app/storage"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+// receiptDownloadTimeout limits how long keeping a local copy of a receipt may take.
+const receiptDownloadTimeout = 30 * time.Second
+
+// ErrNotSpendingMessage is returned when a receipt replies to a message that didn't confirm a saved spending.
+var ErrNotSpendingMessage = errors.New("not a saved spending message")
+
+type BotReceiptManager struct {
+	TbAPI     TbAPI
+	Ledgers   LedgerManager
+	Spendings SpendingsRepository
+	Receipts  ReceiptsRepository
+	Dir       string // Local copies of receipts are kept here when set
+}
+
+// Attach attaches a receipt to a spending of a ledger the user can edit.
+func (rm *BotReceiptManager) Attach(conv Conversation, spendingID int64, receipt storage.ReceiptInfo) error {
+	spending, err := rm.Spendings.GetSpending(spendingID)
+	if err != nil {
+		return err
+	}
+
+	member, err := rm.member(spending.LedgerID, conv.UserID)
+	if err != nil {
+		return err
+	}
+	if !canEdit(member) {
+		return fmt.Errorf("user %d can't attach receipts in ledger %d: %w", conv.UserID, spending.LedgerID, ErrPermissionDenied)
+	}
+
+	receipt.SpendingID = spendingID
+	if receipt.ID, err = rm.Receipts.AddReceipt(receipt); err != nil {
+		return err
+	}
+
+	if rm.Dir != "" {
+		// downloads may be slow, so the copy is kept in the background not to hold up other updates
+		go rm.keepLocalCopy(receipt)
+	}
+	return nil
+}
+
+// keepLocalCopy saves a local copy of an attached receipt and records its path.
+// The file_id is enough to send the receipt again, so a failed copy is only logged.
+func (rm *BotReceiptManager) keepLocalCopy(receipt storage.ReceiptInfo) {
+	localPath, err := rm.saveLocalCopy(receipt)
+	if err != nil {
+		log.Printf("[warn] error keeping a local copy of receipt %d: %v", receipt.ID, err)
+		return
+	}
+	if err = rm.Receipts.SetLocalPath(receipt.ID, localPath); err != nil {
+		log.Printf("[error] error saving local path of receipt %d: %v", receipt.ID, err)
+	}
+}
+
+// AttachToMessage attaches a receipt to the spending a bot message in the conversation's chat confirmed.
+func (rm *BotReceiptManager) AttachToMessage(conv Conversation, messageID int, receipt storage.ReceiptInfo) error {
+	spendingID, err := rm.Receipts.GetMessageSpending(conv.ChatID, messageID)
+	if err != nil {
+		return fmt.Errorf("message %d in chat %d: %w", messageID, conv.ChatID, ErrNotSpendingMessage)
+	}
+	return rm.Attach(conv, spendingID, receipt)
+}
+
+// LinkMessage remembers the bot message confirming a saved spending, so receipts can reply to it.
+func (rm *BotReceiptManager) LinkMessage(conv Conversation, messageID int, spendingID int64) error {
+	return rm.Receipts.LinkMessage(conv.ChatID, messageID, spendingID)
+}
+
+// Receipt returns the latest receipt of a spending of a ledger the user is a member of.
+func (rm *BotReceiptManager) Receipt(conv Conversation, spendingID int64) (*storage.ReceiptInfo, error) {
+	spending, err := rm.Spendings.GetSpending(spendingID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = rm.member(spending.LedgerID, conv.UserID); err != nil {
+		return nil, err
+	}
+	return rm.Receipts.GetReceipt(spendingID)
+}
+
+// member returns the user's membership in a ledger.
+func (rm *BotReceiptManager) member(ledgerID, userID int64) (*storage.LedgerMemberInfo, error) {
+	members, err := rm.Ledgers.Members(ledgerID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range members {
+		if members[i].UserID == userID {
+			return &members[i], nil
+		}
+	}
+	return nil, fmt.Errorf("user %d is not a member of ledger %d: %w", userID, ledgerID, ErrPermissionDenied)
+}
+
+// saveLocalCopy downloads a receipt into the receipts directory and returns the path of the copy.
+func (rm *BotReceiptManager) saveLocalCopy(receipt storage.ReceiptInfo) (string, error) {
+	fileURL, err := rm.TbAPI.GetFileDirectURL(receipt.FileID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get file url: %w", err)
+	}
+
+	client := http.Client{Timeout: receiptDownloadTimeout}
+	resp, err := client.Get(fileURL)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err // the url holds the bot token, keep it out of logs
+		}
+		return "", fmt.Errorf("failed to download file: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download file: status %d", resp.StatusCode)
+	}
+
+	if err = os.MkdirAll(rm.Dir, 0o750); err != nil {
+		return "", fmt.Errorf("failed to create receipts directory: %w", err)
+	}
+
+	localPath := filepath.Join(rm.Dir, fmt.Sprintf("%d-%d%s", receipt.SpendingID, receipt.ID, path.Ext(fileURL)))
+	file, err := os.Create(localPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", localPath, err)
+	}
+
+	if _, err = io.Copy(file, resp.Body); err != nil {
+		_ = file.Close()
+		return "", fmt.Errorf("failed to write %s: %w", localPath, err)
+	}
+	if err = file.Close(); err != nil {
+		return "", fmt.Errorf("failed to close %s: %w", localPath, err)
+	}
+	return localPath, nil
+}
+
+// receiptOf returns the photo or the document of a message as a receipt, the largest size of a photo.
+func receiptOf(msg *tbapi.Message) (storage.ReceiptInfo, bool) {
+	switch {
+	case len(msg.Photo) > 0:
+		return storage.ReceiptInfo{Kind: storage.ReceiptPhoto, FileID: msg.Photo[len(msg.Photo)-1].FileID}, true
+	case msg.Document != nil:
+		return storage.ReceiptInfo{Kind: storage.ReceiptDocument, FileID: msg.Document.FileID}, true
+	}
+	return storage.ReceiptInfo{}, false
+}
+
+// receiptMessage builds the message re-sending a receipt to a chat.
+func receiptMessage(chatID int64, receipt storage.ReceiptInfo) tbapi.Chattable {
+	if receipt.Kind == storage.ReceiptPhoto {
+		return tbapi.NewPhoto(chatID, tbapi.FileID(receipt.FileID))
+	}
+	return tbapi.NewDocument(chatID, tbapi.FileID(receipt.FileID))
+}
@@ -0,0 +1,182 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/nyanyamaga/finance-tracker-bot/app/i18n"
+	"github.com/nyanyamaga/finance-tracker-bot/app/storage"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// reminderCheckInterval is how often the reminder scheduler looks for due reminders.
+const reminderCheckInterval = time.Minute
+
+// Errors of reminder settings, reported to the user with a usage hint.
+var (
+	ErrInvalidClock    = errors.New("invalid time of day")
+	ErrInvalidTimezone = errors.New("invalid time zone")
+)
+
+// BotReminderScheduler reminds users who logged nothing today, at the time they chose in their time zone.
+type BotReminderScheduler struct {
+	TbAPI        TbAPI
+	TbKeyboards  TbKeyboards
+	UserSettings UserSettingsRepository
+	Reminders    RemindersRepository
+	Spendings    SpendingsRepository
+}
+
+// Run sends due reminders every minute until the context is canceled.
+func (s *BotReminderScheduler) Run(ctx context.Context) {
+	log.Printf("[info] started reminder scheduler")
+
+	ticker := time.NewTicker(reminderCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		s.SendDue(time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SendDue sends reminders due at the given time.
+func (s *BotReminderScheduler) SendDue(now time.Time) {
+	subscribers, err := s.UserSettings.ListReminderSubscribers()
+	if err != nil {
+		log.Printf("[error] error listing reminder subscribers: %v", err)
+		return
+	}
+
+	for _, settings := range subscribers {
+		if err := s.sendIfDue(settings, now); err != nil {
+			log.Printf("[warn] error sending reminder to user %d: %v", settings.UserID, err)
+		}
+	}
+}
+
+// sendIfDue sends the reminder once a day after its time, or when a snooze runs out.
+// Nothing is sent during quiet hours or when the user has already logged something today.
+func (s *BotReminderScheduler) sendIfDue(settings storage.UserSettingsInfo, now time.Time) error {
+	loc, err := loadTimezone(settings.Timezone)
+	if err != nil {
+		return err
+	}
+	remindAt, err := parseClock(settings.ReminderTime)
+	if err != nil {
+		return err
+	}
+
+	local := now.In(loc)
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	if inQuietHours(settings, local) {
+		return nil
+	}
+
+	state, err := s.Reminders.Read(settings.UserID)
+	if err != nil {
+		return err
+	}
+
+	snoozeOver := !state.SnoozedUntil.IsZero() && !now.Before(state.SnoozedUntil)
+	timeCome := !local.Before(today.Add(remindAt)) && state.SentAt.Before(today)
+	if !snoozeOver && !timeCome {
+		return nil
+	}
+
+	// spendings are stored in server time, so compare them with server time bounds
+	logged, err := s.Spendings.CountUserSpendings(settings.UserID, today.In(time.Local), today.AddDate(0, 0, 1).In(time.Local))
+	if err != nil {
+		return err
+	}
+
+	state.SentAt, state.SnoozedUntil = now, time.Time{}
+	if logged == 0 {
+		lang := i18n.Normalize(settings.Language)
+		msg := tbapi.NewMessage(settings.UserID, i18n.Text(lang, "reminder.text"))
+		msg.ReplyMarkup = s.TbKeyboards.GetReminderKeyboard(lang)
+		if err = send(msg, s.TbAPI); err != nil {
+			return err
+		}
+		log.Printf("[info] reminder sent to user %d", settings.UserID)
+	}
+
+	return s.Reminders.Write(*state)
+}
+
+// inQuietHours reports whether the local time falls within the user's quiet hours, which may span midnight.
+func inQuietHours(settings storage.UserSettingsInfo, local time.Time) bool {
+	start, err := parseClock(settings.QuietStart)
+	if err != nil {
+		return false
+	}
+	end, err := parseClock(settings.QuietEnd)
+	if err != nil {
+		return false
+	}
+
+	now := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute
+	if start <= end {
+		return now >= start && now < end
+	}
+	return now >= start || now < end
+}
+
+// parseClock parses a time of day like 21:00, 9:30 or 21 into the duration since midnight.
+func parseClock(value string) (time.Duration, error) {
+	hours, minutes, _ := strings.Cut(strings.TrimSpace(value), ":")
+	h, err := strconv.Atoi(hours)
+	if err != nil || h < 0 || h > 23 {
+		return 0, fmt.Errorf("%q: %w", value, ErrInvalidClock)
+	}
+
+	m := 0
+	if minutes != "" {
+		if m, err = strconv.Atoi(minutes); err != nil || m < 0 || m > 59 || len(minutes) != 2 {
+			return 0, fmt.Errorf("%q: %w", value, ErrInvalidClock)
+		}
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
+
+// formatClock formats the duration since midnight as a time of day.
+func formatClock(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// loadTimezone returns the location of an IANA time zone name or a UTC offset like +3, -04:30 or UTC+05:30.
+// An empty name stands for the server time zone.
+func loadTimezone(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return time.Local, nil
+	}
+
+	offset := strings.TrimPrefix(strings.ToUpper(name), "UTC")
+	if offset != "" && (offset[0] == '+' || offset[0] == '-') {
+		d, err := parseClock(offset[1:])
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", name, ErrInvalidTimezone)
+		}
+		seconds := int(d.Seconds())
+		if offset[0] == '-' {
+			seconds = -seconds
+		}
+		return time.FixedZone("UTC"+offset[:1]+formatClock(d), seconds), nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("%q: %w", name, ErrInvalidTimezone)
+	}
+	return loc, nil
+}
@@ -0,0 +1,147 @@
+package events
+
+import (
+	"fmt"
+	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/nyanyamaga/finance-tracker-bot/app/i18n"
+	"github.com/nyanyamaga/finance-tracker-bot/app/storage"
+	"sort"
+	"strings"
+	"time"
+)
+
+type BotReporter struct {
+	TbKeyboards TbKeyboards
+	Ledgers     LedgerManager
+	Categories  CategoriesRepository
+	Spendings   SpendingsRepository
+	Budgets     BudgetsRepository
+}
+
+// MonthlyReport summarizes the current month of the conversation's ledger by category.
+// A non-zero memberID limits the report to spendings recorded by that member.
+// Sub-category totals are rolled up into their parents, a non-zero parentID drills down into the parent's sub-categories.
+func (r *BotReporter) MonthlyReport(lang string, conv Conversation, memberID, parentID int64) (string, tbapi.InlineKeyboardMarkup, error) {
+	keyboard := tbapi.NewInlineKeyboardMarkup()
+
+	member, err := r.Ledgers.LedgerFor(conv)
+	if err != nil {
+		return "", keyboard, err
+	}
+
+	ledger, err := r.Ledgers.Ledger(member.LedgerID)
+	if err != nil {
+		return "", keyboard, err
+	}
+
+	members, err := r.Ledgers.Members(member.LedgerID)
+	if err != nil {
+		return "", keyboard, err
+	}
+
+	categories, err := r.Categories.ListCategories(member.LedgerID)
+	if err != nil {
+		return "", keyboard, err
+	}
+
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	totals, err := r.Spendings.TotalsByCategory(member.LedgerID, memberID, from, from.AddDate(0, 1, 0))
+	if err != nil {
+		return "", keyboard, err
+	}
+	totals = rollUpTotals(totals, categories, parentID)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "📊 *%s %d* · %s\n", i18n.MonthName(lang, from.Month()), from.Year(), ledger.Name)
+	for _, m := range members {
+		if memberID != 0 && m.UserID == memberID {
+			fmt.Fprintf(&sb, "👤 %s\n", m.Name)
+		}
+	}
+	for _, c := range categories {
+		if parentID != 0 && c.ID == parentID {
+			fmt.Fprintf(&sb, "🔍 %s %s\n", c.Emoji, c.Name)
+		}
+	}
+	sb.WriteString("\n")
+
+	if len(totals) == 0 {
+		sb.WriteString(i18n.Text(lang, "report.empty"))
+	}
+
+	var total float64
+	var parents []storage.CategoryTotal
+	for _, t := range totals {
+		fmt.Fprintf(&sb, "%s %s — %.2f (%d)\n", t.Emoji, t.Name, t.Total, t.Count)
+		total += t.Total
+		if parentID == 0 && hasSubcategories(categories, t.CategoryID) {
+			parents = append(parents, t)
+		}
+	}
+	if len(totals) > 0 {
+		fmt.Fprintf(&sb, "\n*%s: %.2f*", i18n.Text(lang, "report.total"), total)
+	}
+
+	// the forecast covers the whole ledger, so filtered reports go without it
+	if memberID == 0 && parentID == 0 {
+		forecast, err := forecastStatus(lang, member.LedgerID, now, r.Budgets, r.Categories, r.Spendings)
+		if err != nil {
+			return "", keyboard, err
+		}
+		if forecast != "" {
+			fmt.Fprintf(&sb, "\n\n🔮 *%s*\n%s", i18n.Text(lang, "forecast.title"), forecast)
+		}
+	}
+
+	if len(members) > 1 || len(parents) > 0 || parentID != 0 {
+		keyboard = r.TbKeyboards.GetReportKeyboard(lang, members, memberID, parents, parentID)
+	}
+	return sb.String(), keyboard, nil
+}
+
+// rollUpTotals adds sub-category totals to their parents, keeping the largest first.
+// A non-zero parentID keeps the parent's own total and totals of its sub-categories instead.
+func rollUpTotals(totals []storage.CategoryTotal, categories []storage.CategoryInfo, parentID int64) []storage.CategoryTotal {
+	byID := make(map[int64]storage.CategoryInfo, len(categories))
+	for _, c := range categories {
+		byID[c.ID] = c
+	}
+
+	var result []storage.CategoryTotal
+	if parentID != 0 {
+		for _, t := range totals {
+			if t.CategoryID == parentID || byID[t.CategoryID].ParentID == parentID {
+				result = append(result, t)
+			}
+		}
+		return result
+	}
+
+	index := make(map[int64]int, len(totals))
+	for _, t := range totals {
+		if parent, ok := byID[byID[t.CategoryID].ParentID]; ok {
+			t.CategoryID, t.Name, t.Emoji = parent.ID, parent.Name, parent.Emoji
+		}
+		if i, ok := index[t.CategoryID]; ok {
+			result[i].Total += t.Total
+			result[i].Count += t.Count
+			continue
+		}
+		index[t.CategoryID] = len(result)
+		result = append(result, t)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Total > result[j].Total })
+	return result
+}
+
+// hasSubcategories reports whether any category is a sub-category of the given one.
+func hasSubcategories(categories []storage.CategoryInfo, categoryID int64) bool {
+	for _, c := range categories {
+		if c.ParentID == categoryID {
+			return true
+		}
+	}
+	return false
+}
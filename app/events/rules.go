@@ -0,0 +1,199 @@
+package events
+
+import (
+	"fmt"
+	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/nyanyamaga/finance-tracker-bot/app/i18n"
+	"github.com/nyanyamaga/finance-tracker-bot/app/storage"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// suggestionHistory is how many of the latest described spendings the category suggestion learns from.
+const suggestionHistory = 500
+
+// suggestionConfidence is the least score a learned suggestion needs, a score of 1 means
+// a word of the description was always filed under the category.
+const suggestionConfidence = 0.6
+
+type BotRuleManager struct {
+	TbKeyboards TbKeyboards
+	Ledgers     LedgerManager
+	Categories  CategoriesRepository
+	Spendings   SpendingsRepository
+	Rules       RulesRepository
+}
+
+// List lists the auto-categorization rules of the conversation's ledger with buttons removing them.
+func (rm *BotRuleManager) List(lang string, conv Conversation) (string, tbapi.InlineKeyboardMarkup, error) {
+	keyboard := tbapi.InlineKeyboardMarkup{InlineKeyboard: [][]tbapi.InlineKeyboardButton{}}
+
+	member, err := rm.Ledgers.LedgerFor(conv)
+	if err != nil {
+		return "", keyboard, err
+	}
+
+	rules, err := rm.Rules.ListRules(member.LedgerID)
+	if err != nil {
+		return "", keyboard, err
+	}
+
+	categories, err := rm.Categories.ListCategories(member.LedgerID)
+	if err != nil {
+		return "", keyboard, err
+	}
+	names := make(map[int64]string, len(categories))
+	for _, c := range categories {
+		names[c.ID] = c.Emoji + " " + c.Name
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "🪄 *%s*\n\n", i18n.Text(lang, "rules.title"))
+	if len(rules) == 0 {
+		sb.WriteString(i18n.Text(lang, "rules.empty"))
+	}
+	for _, rule := range rules {
+		fmt.Fprintf(&sb, "`%s` → %s\n", rule.Keyword, names[rule.CategoryID])
+	}
+	fmt.Fprintf(&sb, "\n%s", i18n.Text(lang, "rules.usage"))
+
+	if canEdit(member) {
+		keyboard = rm.TbKeyboards.GetRulesKeyboard(rules)
+	}
+	return sb.String(), keyboard, nil
+}
+
+// AddRule files spendings of the conversation's ledger whose description contains the keyword under the category.
+func (rm *BotRuleManager) AddRule(conv Conversation, keyword, category string) error {
+	member, err := rm.Ledgers.LedgerFor(conv)
+	if err != nil {
+		return err
+	}
+	if !canEdit(member) {
+		return fmt.Errorf("user %d can't add rules to ledger %d: %w", conv.UserID, member.LedgerID, ErrPermissionDenied)
+	}
+
+	categories, err := rm.Categories.ListCategories(member.LedgerID)
+	if err != nil {
+		return err
+	}
+	c, ok := findCategory(categories, category)
+	if !ok {
+		return fmt.Errorf("%q: %w", category, ErrCategoryNotFound)
+	}
+
+	rule := storage.RuleInfo{LedgerID: member.LedgerID, Keyword: strings.ToLower(keyword), CategoryID: c.ID, CreatedBy: conv.UserID}
+	return rm.Rules.AddRule(rule)
+}
+
+// DeleteRule removes a rule of the conversation's ledger.
+func (rm *BotRuleManager) DeleteRule(conv Conversation, ruleID int64) error {
+	member, err := rm.Ledgers.LedgerFor(conv)
+	if err != nil {
+		return err
+	}
+	if !canEdit(member) {
+		return fmt.Errorf("user %d can't remove rules of ledger %d: %w", conv.UserID, member.LedgerID, ErrPermissionDenied)
+	}
+
+	return rm.Rules.DeleteRule(member.LedgerID, ruleID)
+}
+
+// Suggest picks a category for a spending description, 0 when there's no good guess.
+// Rules go first, the most specific matching keyword winning, then categories learned from past spendings.
+func (rm *BotRuleManager) Suggest(ledgerID int64, description string) (int64, error) {
+	if description == "" {
+		return 0, nil
+	}
+
+	rules, err := rm.Rules.ListRules(ledgerID)
+	if err != nil {
+		return 0, err
+	}
+	if categoryID := matchRule(rules, description); categoryID != 0 {
+		return categoryID, nil
+	}
+
+	history, err := rm.Spendings.ListDescribedSpendings(ledgerID, suggestionHistory)
+	if err != nil {
+		return 0, err
+	}
+	return learnedCategory(history, description), nil
+}
+
+// matchRule returns the category of the rule with the longest keyword found in the description, 0 if none matches.
+func matchRule(rules []storage.RuleInfo, description string) int64 {
+	description = strings.ToLower(description)
+
+	var best storage.RuleInfo
+	for _, rule := range rules {
+		if strings.Contains(description, rule.Keyword) && len(rule.Keyword) > len(best.Keyword) {
+			best = rule
+		}
+	}
+	return best.CategoryID
+}
+
+// learnedCategory guesses the category of a description from past spendings. Each word of the description
+// votes for the categories it was filed under, in proportion to how often it was, so words seen
+// with many categories weigh little. Returns 0 when no category scores high enough.
+func learnedCategory(history []storage.SpendingInfo, description string) int64 {
+	words := make(map[string]bool)
+	for _, w := range descriptionWords(description) {
+		words[w] = true
+	}
+
+	uses := make(map[string]map[int64]int)
+	totals := make(map[string]int)
+	for _, sp := range history {
+		seen := make(map[string]bool)
+		for _, w := range descriptionWords(sp.Description) {
+			if !words[w] || seen[w] {
+				continue
+			}
+			seen[w] = true
+			if uses[w] == nil {
+				uses[w] = make(map[int64]int)
+			}
+			uses[w][sp.CategoryID]++
+			totals[w]++
+		}
+	}
+
+	scores := make(map[int64]float64)
+	for w, categories := range uses {
+		for categoryID, n := range categories {
+			scores[categoryID] += float64(n) / float64(totals[w])
+		}
+	}
+
+	candidates := make([]int64, 0, len(scores))
+	for categoryID := range scores {
+		candidates = append(candidates, categoryID)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if scores[candidates[i]] != scores[candidates[j]] {
+			return scores[candidates[i]] > scores[candidates[j]]
+		}
+		return candidates[i] < candidates[j]
+	})
+
+	if len(candidates) == 0 || scores[candidates[0]] < suggestionConfidence {
+		return 0
+	}
+	return candidates[0]
+}
+
+// descriptionWords splits a description into lowercase words of letters and digits, skipping one-letter ones.
+func descriptionWords(description string) []string {
+	var words []string
+	for _, w := range strings.FieldsFunc(strings.ToLower(description), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len([]rune(w)) > 1 {
+			words = append(words, w)
+		}
+	}
+	return words
+}
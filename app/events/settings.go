@@ -0,0 +1,108 @@
+package events
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/nyanyamaga/finance-tracker-bot/app/storage"
+	"time"
+)
+
+type BotSettingsManager struct {
+	UserSettings UserSettingsRepository
+	Digests      DigestsRepository
+	Reminders    RemindersRepository
+}
+
+// Settings returns the user's settings, empty ones for users who haven't changed anything yet.
+func (m *BotSettingsManager) Settings(userID int64) (*storage.UserSettingsInfo, error) {
+	settings, err := m.UserSettings.Read(userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &storage.UserSettingsInfo{UserID: userID}, nil
+	}
+	return settings, err
+}
+
+// ToggleDigest turns a digest of the kind on or off. A digest turned on starts with the next period,
+// so the user isn't sent a summary of a period right away.
+func (m *BotSettingsManager) ToggleDigest(userID int64, kind string) (*storage.UserSettingsInfo, error) {
+	var enabled bool
+	err := updateUserSettings(m.UserSettings, userID, func(settings *storage.UserSettingsInfo) {
+		switch kind {
+		case storage.DigestWeekly:
+			settings.WeeklyDigest = !settings.WeeklyDigest
+			enabled = settings.WeeklyDigest
+		case storage.DigestMonthly:
+			settings.MonthlyDigest = !settings.MonthlyDigest
+			enabled = settings.MonthlyDigest
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to toggle %s digest: %w", kind, err)
+	}
+
+	if enabled {
+		if err = m.Digests.MarkSent(userID, kind, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+	return m.Settings(userID)
+}
+
+// SetTimezone validates and saves the user's time zone, reminders are evaluated in it.
+func (m *BotSettingsManager) SetTimezone(userID int64, timezone string) (*time.Location, error) {
+	loc, err := loadTimezone(timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	err = updateUserSettings(m.UserSettings, userID, func(settings *storage.UserSettingsInfo) {
+		settings.Timezone = loc.String()
+	})
+	return loc, err
+}
+
+// SetReminder sets the time of the daily logging reminder, an empty time turns it off.
+func (m *BotSettingsManager) SetReminder(userID int64, at string) error {
+	if at != "" {
+		d, err := parseClock(at)
+		if err != nil {
+			return err
+		}
+		at = formatClock(d)
+	}
+
+	return updateUserSettings(m.UserSettings, userID, func(settings *storage.UserSettingsInfo) {
+		settings.ReminderTime = at
+	})
+}
+
+// SetQuietHours sets the hours reminders are not sent in, empty start and end remove them.
+func (m *BotSettingsManager) SetQuietHours(userID int64, start, end string) error {
+	if start != "" || end != "" {
+		from, err := parseClock(start)
+		if err != nil {
+			return err
+		}
+		to, err := parseClock(end)
+		if err != nil {
+			return err
+		}
+		start, end = formatClock(from), formatClock(to)
+	}
+
+	return updateUserSettings(m.UserSettings, userID, func(settings *storage.UserSettingsInfo) {
+		settings.QuietStart, settings.QuietEnd = start, end
+	})
+}
+
+// SnoozeReminder sends the reminder again after the given time, unless the user logs something meanwhile.
+func (m *BotSettingsManager) SnoozeReminder(userID int64, d time.Duration) error {
+	state, err := m.Reminders.Read(userID)
+	if err != nil {
+		return err
+	}
+
+	state.SnoozedUntil = time.Now().Add(d)
+	return m.Reminders.Write(*state)
+}
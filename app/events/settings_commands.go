@@ -0,0 +1,86 @@
+package events
+
+import (
+	"errors"
+	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/nyanyamaga/finance-tracker-bot/app/i18n"
+	"strings"
+	"time"
+)
+
+// offSwitch is the argument turning a reminder setting off.
+const offSwitch = "off"
+
+func (h *BotCommandHandler) remind(msg *tbapi.Message, args string) {
+	userID := msg.From.ID
+	lang := h.StateManager.Language(userID)
+
+	var err error
+	var text string
+	switch quiet, isQuiet := strings.CutPrefix(strings.ToLower(args), "quiet"); {
+	case args == "":
+		settings, err := h.Settings.Settings(userID)
+		if err != nil {
+			h.replyError(msg, err)
+			return
+		}
+
+		off := i18n.Text(lang, "reminder.off_value")
+		reminder, quietHours, timezone := off, off, settings.Timezone
+		if settings.ReminderTime != "" {
+			reminder = settings.ReminderTime
+		}
+		if settings.QuietStart != "" {
+			quietHours = settings.QuietStart + "–" + settings.QuietEnd
+		}
+		if timezone == "" {
+			timezone = time.Local.String()
+		}
+		text = i18n.Text(lang, "reminder.status", reminder, quietHours, timezone)
+	case strings.EqualFold(args, offSwitch):
+		err = h.Settings.SetReminder(userID, "")
+		text = i18n.Text(lang, "reminder.disabled")
+	case isQuiet && strings.TrimSpace(quiet) == offSwitch:
+		err = h.Settings.SetQuietHours(userID, "", "")
+		text = i18n.Text(lang, "reminder.quiet_disabled")
+	case isQuiet:
+		start, end, _ := strings.Cut(strings.TrimSpace(quiet), "-")
+		start, end = strings.TrimSpace(start), strings.TrimSpace(end)
+		if start == "" || end == "" {
+			err = ErrInvalidClock
+			break
+		}
+		err = h.Settings.SetQuietHours(userID, start, end)
+		text = i18n.Text(lang, "reminder.quiet_set")
+	default:
+		err = h.Settings.SetReminder(userID, args)
+		text = i18n.Text(lang, "reminder.set")
+	}
+
+	if errors.Is(err, ErrInvalidClock) {
+		h.reply(msg, i18n.Text(lang, "reminder.usage"), tbapi.InlineKeyboardMarkup{})
+		return
+	}
+	if err != nil {
+		h.replyError(msg, err)
+		return
+	}
+	h.reply(msg, text, tbapi.InlineKeyboardMarkup{})
+}
+
+func (h *BotCommandHandler) timezone(msg *tbapi.Message, args string) {
+	userID := msg.From.ID
+	lang := h.StateManager.Language(userID)
+
+	loc, err := h.Settings.SetTimezone(userID, args)
+	if errors.Is(err, ErrInvalidTimezone) {
+		h.reply(msg, i18n.Text(lang, "timezone.usage"), tbapi.InlineKeyboardMarkup{})
+		return
+	}
+	if err != nil {
+		h.replyError(msg, err)
+		return
+	}
+
+	h.reply(msg, i18n.Text(lang, "timezone.set", loc.String(), time.Now().In(loc).Format("15:04")), tbapi.InlineKeyboardMarkup{})
+}
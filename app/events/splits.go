@@ -0,0 +1,335 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"github.com/looplab/fsm"
+	"github.com/nyanyamaga/finance-tracker-bot/app/keyboards"
+	"github.com/nyanyamaga/finance-tracker-bot/app/storage"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// toCents converts an amount to whole cents, split math is done in cents to avoid losing a cent to rounding.
+func toCents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// computeShares splits the amount between ledger members according to the split mode.
+// Percentage and exact splits take one number per member, in the order members are listed.
+// Rounding leftovers go to the first members, so shares always add up to the amount.
+func computeShares(mode, input string, amount float64, members []storage.LedgerMemberInfo) ([]storage.SpendingShareInfo, error) {
+	total := toCents(amount)
+	cents := make([]int64, len(members))
+
+	switch mode {
+	case keyboards.SplitNone:
+		return nil, nil
+	case keyboards.SplitEqual:
+		for i := range cents {
+			cents[i] = total / int64(len(members))
+			if int64(i) < total%int64(len(members)) {
+				cents[i]++
+			}
+		}
+	case keyboards.SplitPercent, keyboards.SplitExact:
+		values, err := parseShareValues(input, len(members))
+		if err != nil {
+			return nil, err
+		}
+
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+
+		if mode == keyboards.SplitExact {
+			if toCents(sum) != total {
+				return nil, fmt.Errorf("shares add up to %.2f instead of %.2f", sum, amount)
+			}
+			for i, v := range values {
+				cents[i] = toCents(v)
+			}
+			break
+		}
+
+		if math.Abs(sum-100) > 0.01 {
+			return nil, fmt.Errorf("percentages add up to %.2f instead of 100", sum)
+		}
+		var assigned int64
+		for i, v := range values {
+			cents[i] = int64(math.Floor(float64(total) * v / 100))
+			assigned += cents[i]
+		}
+		for i := 0; assigned < total; i = (i + 1) % len(cents) {
+			if values[i] > 0 {
+				cents[i]++
+				assigned++
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown split mode %q", mode)
+	}
+
+	var shares []storage.SpendingShareInfo
+	for i, member := range members {
+		if cents[i] > 0 {
+			shares = append(shares, storage.SpendingShareInfo{UserID: member.UserID, Amount: float64(cents[i]) / 100})
+		}
+	}
+	return shares, nil
+}
+
+// parseShareValues parses one non-negative number per member, separated by spaces.
+func parseShareValues(input string, count int) ([]float64, error) {
+	fields := strings.Fields(input)
+	if len(fields) != count {
+		return nil, fmt.Errorf("expected %d shares, got %d", count, len(fields))
+	}
+
+	values := make([]float64, 0, count)
+	for _, field := range fields {
+		value, err := strconv.ParseFloat(strings.TrimSuffix(strings.ReplaceAll(field, ",", "."), "%"), 64)
+		if err != nil || value < 0 {
+			return nil, fmt.Errorf("invalid share %q", field)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// settleUp suggests transfers that zero out the balances, matching the largest debtor with the largest creditor.
+// Balances are positive for members who are owed money.
+func settleUp(ledgerID int64, balances map[int64]int64) []storage.SettlementInfo {
+	type position struct {
+		userID int64
+		cents  int64
+	}
+
+	var debtors, creditors []position
+	for userID, cents := range balances {
+		switch {
+		case cents < 0:
+			debtors = append(debtors, position{userID, -cents})
+		case cents > 0:
+			creditors = append(creditors, position{userID, cents})
+		}
+	}
+
+	largest := func(positions []position) int {
+		idx := -1
+		for i, p := range positions {
+			if p.cents > 0 && (idx < 0 || p.cents > positions[idx].cents || p.cents == positions[idx].cents && p.userID < positions[idx].userID) {
+				idx = i
+			}
+		}
+		return idx
+	}
+
+	var transfers []storage.SettlementInfo
+	for {
+		d, c := largest(debtors), largest(creditors)
+		if d < 0 || c < 0 {
+			break
+		}
+
+		cents := min(debtors[d].cents, creditors[c].cents)
+		transfers = append(transfers, storage.SettlementInfo{
+			LedgerID:   ledgerID,
+			FromUserID: debtors[d].userID,
+			ToUserID:   creditors[c].userID,
+			Amount:     float64(cents) / 100,
+		})
+		debtors[d].cents -= cents
+		creditors[c].cents -= cents
+	}
+	return transfers
+}
+
+// promptPayerSelection asks who paid for the spending. Ledgers with a single member skip the step.
+func (sm *BotStateManager) promptPayerSelection(ctx context.Context, conv Conversation) {
+	members, err := sm.ledgerMembers(conv)
+	if err != nil {
+		log.Printf("[warn] error fetching ledger members: %v", err)
+		return
+	}
+
+	if len(members) < 2 {
+		sm.skipStep(ctx, conv, "PayerSelected", fmt.Sprintf("%s%d", keyboards.PayerPrefix, conv.UserID))
+		return
+	}
+
+	keyboard := sm.TbKeyboards.GetPayerKeyboard(sm.Language(conv.UserID), members, conv.UserID)
+	if err = sm.sendBotResponse(conv, sm.text(conv.UserID, "payer.select"), &keyboard); err != nil {
+		log.Printf("[warn] error sending payer selection prompt: %v", err)
+	}
+}
+
+// validatePayer cancels the payer transition unless a member of the ledger was picked.
+func (sm *BotStateManager) validatePayer(e *fsm.Event, conv Conversation) {
+	members, err := sm.ledgerMembers(conv)
+	if err != nil {
+		e.Cancel(err)
+		return
+	}
+
+	payerID, err := strconv.ParseInt(strings.TrimPrefix(sm.UserValues[conv], keyboards.PayerPrefix), 10, 64)
+	for _, member := range members {
+		if err == nil && member.UserID == payerID {
+			return
+		}
+	}
+
+	e.Cancel(fmt.Errorf("invalid payer %q", sm.UserValues[conv]))
+	keyboard := sm.TbKeyboards.GetPayerKeyboard(sm.Language(conv.UserID), members, conv.UserID)
+	if err := sm.sendBotResponse(conv, sm.text(conv.UserID, "payer.select"), &keyboard); err != nil {
+		log.Printf("[warn] error sending payer selection prompt: %v", err)
+	}
+}
+
+// promptSplitSelection asks how to split the spending. Ledgers with a single member skip the step.
+func (sm *BotStateManager) promptSplitSelection(ctx context.Context, conv Conversation) {
+	members, err := sm.ledgerMembers(conv)
+	if err != nil {
+		log.Printf("[warn] error fetching ledger members: %v", err)
+		return
+	}
+
+	if len(members) < 2 {
+		sm.skipStep(ctx, conv, "SplitSelected", keyboards.SplitNone)
+		return
+	}
+
+	keyboard := sm.TbKeyboards.GetSplitKeyboard(sm.Language(conv.UserID))
+	if err = sm.sendBotResponse(conv, sm.text(conv.UserID, "split.select"), &keyboard); err != nil {
+		log.Printf("[warn] error sending split selection prompt: %v", err)
+	}
+}
+
+// validateSplit cancels the split transition unless one of the split buttons was tapped.
+func (sm *BotStateManager) validateSplit(e *fsm.Event, conv Conversation) {
+	for _, mode := range keyboards.SplitModes {
+		if sm.UserValues[conv] == mode {
+			return
+		}
+	}
+
+	e.Cancel(fmt.Errorf("invalid split mode %q", sm.UserValues[conv]))
+	keyboard := sm.TbKeyboards.GetSplitKeyboard(sm.Language(conv.UserID))
+	if err := sm.sendBotResponse(conv, sm.text(conv.UserID, "split.select"), &keyboard); err != nil {
+		log.Printf("[warn] error sending split selection prompt: %v", err)
+	}
+}
+
+// promptSplitShares asks for per-member shares of percentage and exact splits, other splits skip the step.
+func (sm *BotStateManager) promptSplitShares(ctx context.Context, conv Conversation) {
+	stateData, err := sm.getStateData(conv)
+	if err != nil {
+		log.Printf("[warn] error fetching state data: %v", err)
+		return
+	}
+
+	mode := stringValue(stateData, "SplitSelected")
+	if mode != keyboards.SplitPercent && mode != keyboards.SplitExact {
+		sm.skipStep(ctx, conv, "SharesEntered", "")
+		return
+	}
+
+	if err = sm.sendBotResponse(conv, sm.sharesPrompt(conv, stateData, "split.enter_"), nil); err != nil {
+		log.Printf("[warn] error sending split shares prompt: %v", err)
+	}
+}
+
+// validateShares cancels the shares transition when the entered shares don't add up.
+func (sm *BotStateManager) validateShares(e *fsm.Event, conv Conversation) {
+	stateData, err := sm.getStateData(conv)
+	if err != nil {
+		e.Cancel(err)
+		return
+	}
+
+	member, err := sm.Ledgers.LedgerFor(conv)
+	if err != nil {
+		e.Cancel(err)
+		return
+	}
+
+	stateData["SharesEntered"] = sm.UserValues[conv]
+	amount, _, _ := parseAmountInput(stringValue(stateData, "AmountEntered"))
+	if _, err = sm.spendingShares(member.LedgerID, stateData, amount); err != nil {
+		e.Cancel(err)
+
+		if err := sm.sendBotResponse(conv, sm.sharesPrompt(conv, stateData, "split.invalid_"), nil); err != nil {
+			log.Printf("[warn] error sending invalid shares message: %v", err)
+		}
+	}
+}
+
+// sharesPrompt builds the message asking for shares, listing members in the order shares are expected
+// along with an example of an equal split.
+func (sm *BotStateManager) sharesPrompt(conv Conversation, stateData map[string]interface{}, keyPrefix string) string {
+	members, err := sm.ledgerMembers(conv)
+	if err != nil {
+		log.Printf("[warn] error fetching ledger members: %v", err)
+	}
+
+	mode := stringValue(stateData, "SplitSelected")
+	amount, _, _ := parseAmountInput(stringValue(stateData, "AmountEntered"))
+	total := amount
+	if mode == keyboards.SplitPercent {
+		total = 100
+	}
+
+	names := make([]string, 0, len(members))
+	example := make([]string, 0, len(members))
+	for i, member := range members {
+		names = append(names, member.Name)
+
+		cents := toCents(total) / int64(len(members))
+		if int64(i) < toCents(total)%int64(len(members)) {
+			cents++
+		}
+		example = append(example, strconv.FormatFloat(float64(cents)/100, 'f', -1, 64))
+	}
+
+	return sm.text(conv.UserID, keyPrefix+strings.TrimPrefix(mode, keyboards.SplitPrefix), amount, strings.Join(names, ", "), strings.Join(example, " "))
+}
+
+// spendingShares splits the spending between ledger members as chosen in the conversation.
+func (sm *BotStateManager) spendingShares(ledgerID int64, stateData map[string]interface{}, amount float64) ([]storage.SpendingShareInfo, error) {
+	mode := stringValue(stateData, "SplitSelected")
+	if mode == "" || mode == keyboards.SplitNone {
+		return nil, nil
+	}
+
+	members, err := sm.Ledgers.Members(ledgerID)
+	if err != nil {
+		return nil, err
+	}
+	return computeShares(mode, stringValue(stateData, "SharesEntered"), amount, members)
+}
+
+// ledgerMembers returns the members of the ledger the conversation records to.
+func (sm *BotStateManager) ledgerMembers(conv Conversation) ([]storage.LedgerMemberInfo, error) {
+	member, err := sm.Ledgers.LedgerFor(conv)
+	if err != nil {
+		return nil, err
+	}
+	return sm.Ledgers.Members(member.LedgerID)
+}
+
+// skipStep answers a step on the user's behalf when there's nothing to ask, e.g. who paid in a personal ledger.
+func (sm *BotStateManager) skipStep(ctx context.Context, conv Conversation, event, value string) {
+	sm.UserValues[conv] = value
+	if err := sm.UserFSMs[conv].Event(ctx, event, value); err != nil {
+		log.Printf("[warn] error skipping %s for %v: %v", event, conv, err)
+	}
+}
+
+// stringValue returns a string value of the conversation state data, empty if missing.
+func stringValue(stateData map[string]interface{}, key string) string {
+	value, _ := stateData[key].(string)
+	return value
+}
@@ -3,40 +3,83 @@ package events
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/looplab/fsm"
+	"github.com/nyanyamaga/finance-tracker-bot/app/keyboards"
+	"github.com/nyanyamaga/finance-tracker-bot/app/parsers"
 	"github.com/nyanyamaga/finance-tracker-bot/app/storage"
 	"log"
+	"math"
 	"strconv"
 	"strings"
 	"time"
 )
 
+// receiptKey is the state data key of a receipt sent while a spending is being added, kept as <kind>:<file_id>.
+const receiptKey = "ReceiptAttached"
+
+// addingSpending are the states of adding a spending, receipts sent in them are attached to it.
+var addingSpending = map[string]bool{
+	"AwaitingCategorySelection":    true,
+	"AwaitingCategoryConfirmation": true,
+	"AwaitingAmountInput":          true,
+	"AwaitingDateSelection":        true,
+	"AwaitingAccountSelection":     true,
+	"AwaitingPayerSelection":       true,
+	"AwaitingSplitSelection":       true,
+	"AwaitingSplitShares":          true,
+}
+
+// optionalSteps are the transitions into optional steps of adding a spending, triggered by their buttons only.
+var optionalSteps = map[string]bool{
+	"ChooseDate": true,
+}
+
+// ErrNotAddingSpending is returned when a receipt is sent while no spending is being added.
+var ErrNotAddingSpending = errors.New("no spending is being added")
+
 type BotStateManager struct {
-	TbAPI       TbAPI
-	TbKeyboards TbKeyboards
-	UserState   UserStateRepository
-	Categories  CategoriesRepository
-	Spendings   SpendingsRepository
-	UserFSMs    map[int64]*fsm.FSM
-	UserValues  map[int64]string
+	TbAPI         TbAPI
+	TbKeyboards   TbKeyboards
+	UserState     UserStateRepository
+	UserSettings  UserSettingsRepository
+	Categories    CategoriesRepository
+	Spendings     SpendingsRepository
+	Ledgers       LedgerManager
+	Rules         RuleManager
+	Receipts      ReceiptManager
+	Goals         GoalManager
+	Accounts      AccountManager
+	UserFSMs      map[Conversation]*fsm.FSM
+	UserValues    map[Conversation]string
+	ReplyTo       map[Conversation]int
+	UserLanguages map[int64]string
 }
 
-func NewBotStateManager(tbAPI TbAPI, tbKeyboards TbKeyboards, usRepository UserStateRepository, cRepository CategoriesRepository, sRepository SpendingsRepository) *BotStateManager {
+func NewBotStateManager(tbAPI TbAPI, tbKeyboards TbKeyboards, usRepository UserStateRepository, ssRepository UserSettingsRepository, cRepository CategoriesRepository, sRepository SpendingsRepository, ledgers LedgerManager, rules RuleManager, receipts ReceiptManager, goals GoalManager, accounts AccountManager) *BotStateManager {
 	return &BotStateManager{
-		TbAPI:       tbAPI,
-		TbKeyboards: tbKeyboards,
-		UserState:   usRepository,
-		Categories:  cRepository,
-		Spendings:   sRepository,
-		UserFSMs:    make(map[int64]*fsm.FSM),
-		UserValues:  make(map[int64]string),
+		TbAPI:         tbAPI,
+		TbKeyboards:   tbKeyboards,
+		UserState:     usRepository,
+		UserSettings:  ssRepository,
+		Categories:    cRepository,
+		Spendings:     sRepository,
+		Ledgers:       ledgers,
+		Rules:         rules,
+		Receipts:      receipts,
+		Goals:         goals,
+		Accounts:      accounts,
+		UserFSMs:      make(map[Conversation]*fsm.FSM),
+		UserValues:    make(map[Conversation]string),
+		ReplyTo:       make(map[Conversation]int),
+		UserLanguages: make(map[int64]string),
 	}
 }
 
-func (sm *BotStateManager) InitializeUserFSM(ctx context.Context, userID int64) {
-	sm.UserFSMs[userID] = fsm.NewFSM(
+func (sm *BotStateManager) InitializeUserFSM(ctx context.Context, conv Conversation) {
+	sm.UserFSMs[conv] = fsm.NewFSM(
 		"Idle",
 		fsm.Events{
 			{Name: "ChooseAddSpending", Src: []string{"Idle"}, Dst: "AwaitingCategorySelection"},
@@ -47,39 +90,88 @@ func (sm *BotStateManager) InitializeUserFSM(ctx context.Context, userID int64)
 			{Name: "SaveNewCategory", Src: []string{"AwaitingSaveCategoryName"}, Dst: "Idle"},
 
 			{Name: "CategorySelected", Src: []string{"AwaitingCategorySelection"}, Dst: "AwaitingAmountInput"},
-			{Name: "AmountEntered", Src: []string{"AwaitingAmountInput"}, Dst: "SaveSpending"},
+			{Name: "CategorySelected", Src: []string{"AwaitingCategoryConfirmation"}, Dst: "SaveSpending"},
+			{Name: "AmountEntered", Src: []string{"Idle"}, Dst: "AwaitingCategoryConfirmation"}, // quick entry
+			{Name: "AmountEntered", Src: []string{"AwaitingAmountInput"}, Dst: "AwaitingAccountSelection"},
+			{Name: "ChooseDate", Src: []string{"AwaitingAmountInput"}, Dst: "AwaitingDateSelection"}, // optional, today otherwise
+			{Name: "DateSelected", Src: []string{"AwaitingDateSelection"}, Dst: "AwaitingAmountInput"},
+			{Name: "AccountSelected", Src: []string{"AwaitingAccountSelection"}, Dst: "AwaitingPayerSelection"},
+			{Name: "PayerSelected", Src: []string{"AwaitingPayerSelection"}, Dst: "AwaitingSplitSelection"},
+			{Name: "SplitSelected", Src: []string{"AwaitingSplitSelection"}, Dst: "AwaitingSplitShares"},
+			{Name: "SharesEntered", Src: []string{"AwaitingSplitShares"}, Dst: "SaveSpending"},
 			{Name: "SpendingSaved", Src: []string{"SaveSpending"}, Dst: "Idle"},
+
+			{Name: "ChooseAddGoal", Src: []string{"Idle"}, Dst: "AwaitingGoalDetails"},
+			{Name: "ChooseEditGoal", Src: []string{"Idle"}, Dst: "AwaitingGoalDetails"},
+			{Name: "GoalDetailsEntered", Src: []string{"AwaitingGoalDetails"}, Dst: "SaveGoal"},
+			{Name: "GoalSaved", Src: []string{"SaveGoal"}, Dst: "Idle"},
+			{Name: "ChooseContribute", Src: []string{"Idle"}, Dst: "AwaitingContributionAmount"},
+			{Name: "ContributionEntered", Src: []string{"AwaitingContributionAmount"}, Dst: "SaveContribution"},
+			{Name: "ContributionSaved", Src: []string{"SaveContribution"}, Dst: "Idle"},
+
+			{Name: "ChooseFixAmount", Src: []string{"Idle"}, Dst: "AwaitingAmountFix"},
+			{Name: "AmountFixEntered", Src: []string{"AwaitingAmountFix"}, Dst: "SaveAmountFix"},
+			{Name: "AmountFixSaved", Src: []string{"SaveAmountFix"}, Dst: "Idle"},
 		},
 		fsm.Callbacks{
-			"leave_state":                     func(ctx context.Context, e *fsm.Event) { sm.leaveState(e, userID) },
-			"enter_Idle":                      func(ctx context.Context, e *fsm.Event) { sm.promptEnterIdle(userID) },
-			"enter_AwaitingCategorySelection": func(ctx context.Context, e *fsm.Event) { sm.promptCategorySelection(userID) },
-			"enter_AwaitingAmountInput":       func(ctx context.Context, e *fsm.Event) { sm.promptAmountInput(userID) },
-			"enter_SaveSpending":              func(ctx context.Context, e *fsm.Event) { sm.saveSpending(ctx, userID) },
-			"enter_AwaitingNewCategoryName":   func(ctx context.Context, e *fsm.Event) { sm.promptNewCategoryName(userID) },
-			"enter_AwaitingNewCategoryEmoji":  func(ctx context.Context, e *fsm.Event) { sm.promptNewCategoryEmoji(userID) },
-			"enter_AwaitingSaveCategoryName":  func(ctx context.Context, e *fsm.Event) { sm.promptSaveNewCategory(ctx, userID) },
+			"leave_state":                        func(ctx context.Context, e *fsm.Event) { sm.leaveState(e, conv) },
+			"before_ChooseAddSpending":           func(ctx context.Context, e *fsm.Event) { sm.checkCanEdit(e, conv) },
+			"before_ChooseAddCategory":           func(ctx context.Context, e *fsm.Event) { sm.checkCanEdit(e, conv) },
+			"enter_Idle":                         func(ctx context.Context, e *fsm.Event) { sm.promptEnterIdle(conv) },
+			"enter_AwaitingCategorySelection":    func(ctx context.Context, e *fsm.Event) { sm.promptCategorySelection(conv) },
+			"before_CategorySelected":            func(ctx context.Context, e *fsm.Event) { sm.validateCategory(e, conv) },
+			"enter_AwaitingCategoryConfirmation": func(ctx context.Context, e *fsm.Event) { sm.promptCategoryConfirmation(conv) },
+			"enter_AwaitingAmountInput":          func(ctx context.Context, e *fsm.Event) { sm.promptAmountInput(conv) },
+			"before_AmountEntered":               func(ctx context.Context, e *fsm.Event) { sm.validateAmount(e, conv) },
+			"enter_AwaitingDateSelection":        func(ctx context.Context, e *fsm.Event) { sm.promptDateSelection(conv) },
+			"before_DateSelected":                func(ctx context.Context, e *fsm.Event) { sm.validateDate(e, conv) },
+			"enter_AwaitingAccountSelection":     func(ctx context.Context, e *fsm.Event) { sm.promptAccountSelection(ctx, conv) },
+			"before_AccountSelected":             func(ctx context.Context, e *fsm.Event) { sm.validateAccount(e, conv) },
+			"enter_AwaitingPayerSelection":       func(ctx context.Context, e *fsm.Event) { sm.promptPayerSelection(ctx, conv) },
+			"before_PayerSelected":               func(ctx context.Context, e *fsm.Event) { sm.validatePayer(e, conv) },
+			"enter_AwaitingSplitSelection":       func(ctx context.Context, e *fsm.Event) { sm.promptSplitSelection(ctx, conv) },
+			"before_SplitSelected":               func(ctx context.Context, e *fsm.Event) { sm.validateSplit(e, conv) },
+			"enter_AwaitingSplitShares":          func(ctx context.Context, e *fsm.Event) { sm.promptSplitShares(ctx, conv) },
+			"before_SharesEntered":               func(ctx context.Context, e *fsm.Event) { sm.validateShares(e, conv) },
+			"enter_SaveSpending":                 func(ctx context.Context, e *fsm.Event) { sm.saveSpending(ctx, conv) },
+			"enter_AwaitingNewCategoryName":      func(ctx context.Context, e *fsm.Event) { sm.promptNewCategoryName(conv) },
+			"enter_AwaitingNewCategoryEmoji":     func(ctx context.Context, e *fsm.Event) { sm.promptNewCategoryEmoji(conv) },
+			"enter_AwaitingSaveCategoryName":     func(ctx context.Context, e *fsm.Event) { sm.promptSaveNewCategory(ctx, conv) },
+			"before_ChooseAddGoal":               func(ctx context.Context, e *fsm.Event) { sm.checkCanEdit(e, conv) },
+			"before_ChooseEditGoal":              func(ctx context.Context, e *fsm.Event) { sm.checkCanEdit(e, conv) },
+			"enter_AwaitingGoalDetails":          func(ctx context.Context, e *fsm.Event) { sm.promptGoalDetails(conv) },
+			"before_GoalDetailsEntered":          func(ctx context.Context, e *fsm.Event) { sm.validateGoalDetails(e, conv) },
+			"enter_SaveGoal":                     func(ctx context.Context, e *fsm.Event) { sm.saveGoal(ctx, conv) },
+			"before_ChooseContribute":            func(ctx context.Context, e *fsm.Event) { sm.checkCanEdit(e, conv) },
+			"enter_AwaitingContributionAmount":   func(ctx context.Context, e *fsm.Event) { sm.promptContributionAmount(conv) },
+			"before_ContributionEntered":         func(ctx context.Context, e *fsm.Event) { sm.validateAmount(e, conv) },
+			"enter_SaveContribution":             func(ctx context.Context, e *fsm.Event) { sm.saveContribution(ctx, conv) },
+			"before_ChooseFixAmount":             func(ctx context.Context, e *fsm.Event) { sm.checkCanEdit(e, conv) },
+			"enter_AwaitingAmountFix":            func(ctx context.Context, e *fsm.Event) { sm.promptAmountFix(conv) },
+			"before_AmountFixEntered":            func(ctx context.Context, e *fsm.Event) { sm.validateAmount(e, conv) },
+			"enter_SaveAmountFix":                func(ctx context.Context, e *fsm.Event) { sm.saveAmountFix(ctx, conv) },
 		},
 	)
 
 	initialState := storage.UserStateInfo{
-		UserID:   userID,
+		ChatID:   conv.ChatID,
+		UserID:   conv.UserID,
 		State:    "Idle",
 		DataJSON: "{}",
 	}
 	if err := sm.UserState.Write(initialState); err != nil {
-		log.Printf("[error] Failed to create initial state for user %d: %v", userID, err)
+		log.Printf("[error] Failed to create initial state for %v: %v", conv, err)
 	}
 }
 
-func (sm *BotStateManager) TriggerStateChange(ctx context.Context, userID int64, action, value string) error {
+func (sm *BotStateManager) TriggerStateChange(ctx context.Context, conv Conversation, action, value string) error {
 	var err error
 
-	if _, exists := sm.UserFSMs[userID]; !exists {
-		sm.InitializeUserFSM(ctx, userID)
+	if _, exists := sm.UserFSMs[conv]; !exists {
+		sm.InitializeUserFSM(ctx, conv)
 	}
-	userFSM := sm.UserFSMs[userID]
-	sm.UserValues[userID] = value
+	userFSM := sm.UserFSMs[conv]
+	sm.UserValues[conv] = value
 
 	if userFSM.Can(action) {
 		err = userFSM.Event(ctx, action, value)
@@ -93,105 +185,145 @@ func (sm *BotStateManager) TriggerStateChange(ctx context.Context, userID int64,
 	return nil
 }
 
-func (sm *BotStateManager) GetCurrentState(ctx context.Context, userID int64) (*fsm.FSM, error) {
-	if _, exists := sm.UserFSMs[userID]; !exists {
-		return nil, fmt.Errorf("user %d has no state machine", userID)
+func (sm *BotStateManager) GetCurrentState(ctx context.Context, conv Conversation) (*fsm.FSM, error) {
+	if _, exists := sm.UserFSMs[conv]; !exists {
+		return nil, fmt.Errorf("%v has no state machine", conv)
 	}
-	userFSM := sm.UserFSMs[userID]
+	userFSM := sm.UserFSMs[conv]
 
 	return userFSM, nil
 }
 
-func (sm *BotStateManager) SetIdleState(ctx context.Context, userID int64) {
-	sm.InitializeUserFSM(ctx, userID)
+func (sm *BotStateManager) SetIdleState(ctx context.Context, conv Conversation) {
+	sm.InitializeUserFSM(ctx, conv)
 }
 
-func (sm *BotStateManager) leaveState(e *fsm.Event, userID int64) {
+func (sm *BotStateManager) leaveState(e *fsm.Event, conv Conversation) {
 	var (
 		updatedData map[string]interface{}
 		err         error
 	)
 
 	if e.Dst != "Idle" {
-		updatedData, err = sm.getStateData(userID)
+		updatedData, err = sm.getStateData(conv)
 
 		if updatedData == nil {
 			updatedData = make(map[string]interface{})
 		}
 
-		updatedData[e.Event] = sm.UserValues[userID]
+		updatedData[e.Event] = sm.UserValues[conv]
 	}
 
 	dataJSON, err := json.Marshal(updatedData)
 	if err != nil {
-		log.Printf("[error] Failed to marshal updated state data to JSON for user %d: %v", userID, err)
+		log.Printf("[error] Failed to marshal updated state data to JSON for %v: %v", conv, err)
 		return
 	}
 
 	stateInfo := storage.UserStateInfo{
-		UserID:   userID,
+		ChatID:   conv.ChatID,
+		UserID:   conv.UserID,
 		State:    e.Event,
 		DataJSON: string(dataJSON),
 	}
 
 	if err := sm.UserState.Write(stateInfo); err != nil {
-		log.Printf("[error] Failed to save updated user state for user %d: %v", userID, err)
+		log.Printf("[error] Failed to save updated user state for %v: %v", conv, err)
 		return
 	}
 
-	log.Printf("[info] User %d entered state %s, data: %s", userID, e.Dst, dataJSON)
-	delete(sm.UserValues, userID)
+	log.Printf("[info] %v entered state %s, data: %s", conv, e.Dst, dataJSON)
+	delete(sm.UserValues, conv)
 }
 
-func (sm *BotStateManager) promptEnterIdle(userID int64) {
-	err := sm.sendBotResponse(userID, "Choose an option:", sm.TbKeyboards.GetMainKeyboard())
+// checkCanEdit cancels flows that add records when the user is a viewer of the active ledger.
+func (sm *BotStateManager) checkCanEdit(e *fsm.Event, conv Conversation) {
+	member, err := sm.Ledgers.LedgerFor(conv)
+	if err != nil {
+		e.Cancel(err)
+		return
+	}
+
+	if !canEdit(member) {
+		e.Cancel(ErrPermissionDenied)
+
+		if err := sm.sendBotResponse(conv, sm.text(conv.UserID, "ledger.read_only"), sm.TbKeyboards.GetMainKeyboard(sm.Language(conv.UserID))); err != nil {
+			log.Printf("[warn] error sending read-only ledger message: %v", err)
+		}
+	}
+}
+
+func (sm *BotStateManager) promptEnterIdle(conv Conversation) {
+	err := sm.sendBotResponse(conv, sm.text(conv.UserID, "main.choose_option"), sm.TbKeyboards.GetMainKeyboard(sm.Language(conv.UserID)))
 	if err != nil {
 		log.Printf("[warn] error sending main message: %v", err)
 	}
 }
 
-func (sm *BotStateManager) promptCategorySelection(userID int64) {
-	text := "Please select a category:"
-	keyboard := sm.TbKeyboards.GetCategoryKeyboard(userID)
+func (sm *BotStateManager) promptCategorySelection(conv Conversation) {
+	member, err := sm.Ledgers.LedgerFor(conv)
+	if err != nil {
+		log.Printf("[warn] error fetching active ledger: %v", err)
+		return
+	}
+
+	text := sm.text(conv.UserID, "category.select")
+	keyboard := sm.TbKeyboards.GetCategoryKeyboard(sm.Language(conv.UserID), member.LedgerID, conv.UserID, 0, 0)
 
-	err := sm.sendBotResponse(userID, text, &keyboard)
+	err = sm.sendBotResponse(conv, text, &keyboard)
 	if err != nil {
 		log.Printf("[warn] error sending category selection prompt: %v", err)
 		return
 	}
 }
 
-func (sm *BotStateManager) promptNewCategoryName(userID int64) {
-	text := "Please enter the name of the new category:"
+func (sm *BotStateManager) promptNewCategoryName(conv Conversation) {
+	text := sm.text(conv.UserID, "category.enter_name")
 
-	err := sm.sendBotResponse(userID, text, nil)
+	err := sm.sendBotResponse(conv, text, nil)
 	if err != nil {
 		log.Printf("[warn] error sending new category name prompt: %v", err)
 		return
 	}
 }
 
-func (sm *BotStateManager) promptNewCategoryEmoji(userID int64) {
-	text := "Please enter the emoji for the new category:"
+func (sm *BotStateManager) promptNewCategoryEmoji(conv Conversation) {
+	text := sm.text(conv.UserID, "category.enter_emoji")
 
-	err := sm.sendBotResponse(userID, text, nil)
+	err := sm.sendBotResponse(conv, text, nil)
 	if err != nil {
 		log.Printf("[warn] error sending new category emoji prompt: %v", err)
 		return
 	}
 }
 
-func (sm *BotStateManager) promptSaveNewCategory(ctx context.Context, userID int64) {
-	stateData, err := sm.getStateData(userID)
+func (sm *BotStateManager) promptSaveNewCategory(ctx context.Context, conv Conversation) {
+	stateData, err := sm.getStateData(conv)
 	if err != nil {
 		log.Printf("[warn] error fetching state data: %v", err)
 		return
 	}
 
+	member, err := sm.Ledgers.LedgerFor(conv)
+	if err != nil {
+		log.Printf("[warn] error fetching active ledger: %v", err)
+		return
+	}
+
 	category := storage.CategoryInfo{
-		UserID: userID,
-		Name:   stateData["NewCategoryNameEntered"].(string),
-		Emoji:  stateData["NewCategoryEmojiEntered"].(string),
+		UserID:   conv.UserID,
+		LedgerID: member.LedgerID,
+		Name:     stateData["NewCategoryNameEntered"].(string),
+		Emoji:    stateData["NewCategoryEmojiEntered"].(string),
+	}
+
+	// "Food / Groceries" makes a sub-category of Food, which is created when missing
+	if parentName, name, ok := strings.Cut(category.Name, "/"); ok && strings.TrimSpace(parentName) != "" && strings.TrimSpace(name) != "" {
+		category.Name = strings.TrimSpace(name)
+		if category.ParentID, err = sm.parentCategory(category, strings.TrimSpace(parentName)); err != nil {
+			log.Printf("[warn] error resolving parent category: %v", err)
+			return
+		}
 	}
 
 	err = sm.Categories.AddOrUpdateCategory(category)
@@ -200,20 +332,54 @@ func (sm *BotStateManager) promptSaveNewCategory(ctx context.Context, userID int
 		return
 	}
 
-	text := "Category saved!"
-	err = sm.sendBotResponse(userID, text, nil)
+	text := sm.text(conv.UserID, "category.saved")
+	err = sm.sendBotResponse(conv, text, nil)
 	if err != nil {
 		log.Printf("[warn] error sending new category save prompt: %v", err)
 		return
 	}
 
-	if err := sm.UserFSMs[userID].Event(ctx, "SaveNewCategory"); err != nil {
-		log.Printf("[error] Failed to transition to Idle state for user %d: %v", userID, err)
+	if err := sm.UserFSMs[conv].Event(ctx, "SaveNewCategory"); err != nil {
+		log.Printf("[error] Failed to transition to Idle state for %v: %v", conv, err)
+	}
+}
+
+// parentCategory returns the ID of the top-level category named parentName, creating it with the emoji
+// of the new sub-category when missing. Categories are two levels deep, so a sub-category parent
+// gives its own parent.
+func (sm *BotStateManager) parentCategory(category storage.CategoryInfo, parentName string) (int64, error) {
+	categories, err := sm.Categories.ListCategories(category.LedgerID)
+	if err != nil {
+		return 0, err
+	}
+
+	if parent, ok := findCategory(categories, parentName); ok {
+		if parent.ParentID != 0 {
+			return parent.ParentID, nil
+		}
+		return parent.ID, nil
+	}
+
+	parent := storage.CategoryInfo{UserID: category.UserID, LedgerID: category.LedgerID, Name: parentName, Emoji: category.Emoji}
+	if err = sm.Categories.AddOrUpdateCategory(parent); err != nil {
+		return 0, err
 	}
+
+	if categories, err = sm.Categories.ListCategories(category.LedgerID); err != nil {
+		return 0, err
+	}
+	created, _ := findCategory(categories, parentName)
+	return created.ID, nil
+}
+
+func (sm *BotStateManager) sendBotResponse(conv Conversation, text string, keyboard interface{}) error {
+	_, err := sm.sendBotMessage(conv, text, keyboard)
+	return err
 }
 
-func (sm *BotStateManager) sendBotResponse(chatID int64, text string, keyboard interface{}) error {
-	tbMsg := tbapi.NewMessage(chatID, text)
+// sendBotMessage sends a response the way sendBotResponse does and returns the sent message.
+func (sm *BotStateManager) sendBotMessage(conv Conversation, text string, keyboard interface{}) (tbapi.Message, error) {
+	tbMsg := tbapi.NewMessage(conv.ChatID, text)
 	tbMsg.ParseMode = tbapi.ModeMarkdown
 	tbMsg.DisableWebPagePreview = true
 	tbMsg.ReplyMarkup = keyboard
@@ -223,16 +389,92 @@ func (sm *BotStateManager) sendBotResponse(chatID int64, text string, keyboard i
 		tbMsg.ReplyMarkup = removeKeyboard
 	}
 
-	if err := send(tbMsg, sm.TbAPI); err != nil {
-		return fmt.Errorf("can't send message to telegram %s, %d: %w", text, chatID, err)
+	// in groups answer in-thread, so reply keyboards are shown only to the member the bot talks to
+	if conv.IsGroup() {
+		tbMsg.ReplyToMessageID = sm.ReplyTo[conv]
+		if replyKeyboard, ok := tbMsg.ReplyMarkup.(tbapi.ReplyKeyboardMarkup); ok {
+			replyKeyboard.Selective = true
+			tbMsg.ReplyMarkup = replyKeyboard
+		}
+	}
+
+	sent, err := sendMessage(tbMsg, sm.TbAPI)
+	if err != nil {
+		return sent, fmt.Errorf("can't send message to telegram %s, %v: %w", text, conv, err)
+	}
+	return sent, nil
+}
+
+// AttachReceipt keeps a receipt sent while a spending is being added, it's attached once the spending is saved.
+func (sm *BotStateManager) AttachReceipt(conv Conversation, receipt storage.ReceiptInfo) error {
+	userFSM, exists := sm.UserFSMs[conv]
+	if !exists || !addingSpending[userFSM.Current()] {
+		return fmt.Errorf("%v: %w", conv, ErrNotAddingSpending)
+	}
+
+	return sm.setStateValue(conv, receiptKey, receipt.Kind+":"+receipt.FileID)
+}
+
+// EnterParsedSpending starts a quick entry pre-filled from a bank message or a receipt QR code,
+// it awaits the category confirmation like a typed one. The spending is recorded for the parsed date, if any.
+func (sm *BotStateManager) EnterParsedSpending(ctx context.Context, conv Conversation, spending parsers.Spending) error {
+	if !spending.Date.IsZero() {
+		if err := sm.setStateValue(conv, "DateSelected", spending.Date.Format(keyboards.DateLayout)); err != nil {
+			return err
+		}
+	}
+
+	value := strings.TrimSpace(fmt.Sprintf("%.2f %s", spending.Amount, spending.Merchant))
+	if err := sm.TriggerStateChange(ctx, conv, "AmountEntered", value); err != nil {
+		// don't let the date leak into the next quick entry
+		if clearErr := sm.setStateValue(conv, "DateSelected", ""); clearErr != nil {
+			log.Printf("[warn] error clearing parsed date for %v: %v", conv, clearErr)
+		}
+		return err
 	}
 	return nil
 }
 
-func (sm *BotStateManager) getStateData(userID int64) (map[string]interface{}, error) {
-	currentStateInfo, err := sm.UserState.Read(userID)
+// setStateValue sets a value of the conversation state data outside of transitions, an empty value removes it.
+func (sm *BotStateManager) setStateValue(conv Conversation, key, value string) error {
+	stateInfo, err := sm.UserState.Read(conv.ChatID, conv.UserID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch current state data for user %d: %w", userID, err)
+		return fmt.Errorf("failed to fetch current state data for %v: %w", conv, err)
+	}
+
+	data, err := unmarshalUserData(stateInfo.DataJSON)
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+	if value == "" {
+		delete(data, key)
+	} else {
+		data[key] = value
+	}
+
+	dataJSON, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal state data for %v: %w", conv, err)
+	}
+	stateInfo.DataJSON = string(dataJSON)
+
+	return sm.UserState.Write(*stateInfo)
+}
+
+// TrackMessage remembers the latest message of a conversation, bot responses in groups reply to it.
+func (sm *BotStateManager) TrackMessage(conv Conversation, messageID int) {
+	if conv.IsGroup() {
+		sm.ReplyTo[conv] = messageID
+	}
+}
+
+func (sm *BotStateManager) getStateData(conv Conversation) (map[string]interface{}, error) {
+	currentStateInfo, err := sm.UserState.Read(conv.ChatID, conv.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch current state data for %v: %w", conv, err)
 	}
 
 	data, err := unmarshalUserData(currentStateInfo.DataJSON)
@@ -243,18 +485,130 @@ func (sm *BotStateManager) getStateData(userID int64) (map[string]interface{}, e
 	return data, nil
 }
 
-func (sm *BotStateManager) promptAmountInput(userID int64) {
-	text := "Please enter the amount:"
+// promptAmountInput asks for the amount, the keyboard opens the date step for spendings of earlier days.
+func (sm *BotStateManager) promptAmountInput(conv Conversation) {
+	text := sm.text(conv.UserID, "amount.enter")
+
+	var date time.Time // not picked yet
+	if stateData, err := sm.getStateData(conv); err == nil && stringValue(stateData, "DateSelected") != "" {
+		date, _ = parseSpendingDate(stringValue(stateData, "DateSelected"), time.Now())
+	}
+	keyboard := sm.TbKeyboards.GetAmountKeyboard(sm.Language(conv.UserID), date)
 
-	err := sm.sendBotResponse(userID, text, nil)
+	err := sm.sendBotResponse(conv, text, &keyboard)
 	if err != nil {
 		log.Printf("[warn] error sending amount prompt: %v", err)
 		return
 	}
 }
 
-func (sm *BotStateManager) saveSpending(ctx context.Context, userID int64) {
-	stateData, err := sm.getStateData(userID)
+// validateCategory cancels the category transition when something other than a category button is sent,
+// so the user stays on the category choice.
+func (sm *BotStateManager) validateCategory(e *fsm.Event, conv Conversation) {
+	value := sm.UserValues[conv]
+	if _, err := strconv.ParseInt(strings.TrimPrefix(value, "category_"), 10, 64); err != nil || !strings.HasPrefix(value, "category_") {
+		e.Cancel(fmt.Errorf("invalid category %q", value))
+
+		if err := sm.sendBotResponse(conv, sm.text(conv.UserID, "category.select"), nil); err != nil {
+			log.Printf("[warn] error sending category selection reminder: %v", err)
+		}
+	}
+}
+
+// promptCategoryConfirmation offers the category suggested for a quick entry,
+// or the category keyboard when there's no suggestion.
+func (sm *BotStateManager) promptCategoryConfirmation(conv Conversation) {
+	stateData, err := sm.getStateData(conv)
+	if err != nil {
+		log.Printf("[warn] error fetching state data: %v", err)
+		return
+	}
+
+	member, err := sm.Ledgers.LedgerFor(conv)
+	if err != nil {
+		log.Printf("[warn] error fetching active ledger: %v", err)
+		return
+	}
+
+	lang := sm.Language(conv.UserID)
+	amount, description, _ := parseAmountInput(stringValue(stateData, "AmountEntered"))
+	date, err := time.Parse(keyboards.DateLayout, stringValue(stateData, "DateSelected"))
+	if day, rest, ok := cutSpendingDate(description, time.Now()); ok {
+		date, description, err = day, rest, nil
+	}
+	summary := fmt.Sprintf("*%.2f*", amount)
+	if description != "" {
+		summary += " · " + description
+	}
+	if err == nil {
+		summary += " · " + date.Format("02.01.06")
+	}
+
+	categoryID, err := sm.Rules.Suggest(member.LedgerID, description)
+	if err != nil {
+		log.Printf("[warn] error suggesting category for %v: %v", conv, err)
+	}
+	if categoryID != 0 {
+		if category, err := sm.Categories.GetCategory(categoryID); err == nil {
+			keyboard := sm.TbKeyboards.GetSuggestionKeyboard(lang, *category)
+			if err = sm.sendBotResponse(conv, sm.text(conv.UserID, "quick.confirm", summary), &keyboard); err != nil {
+				log.Printf("[warn] error sending category suggestion: %v", err)
+			}
+			return
+		}
+	}
+
+	keyboard := sm.TbKeyboards.GetCategoryKeyboard(lang, member.LedgerID, conv.UserID, 0, 0)
+	if err = sm.sendBotResponse(conv, sm.text(conv.UserID, "quick.choose", summary), &keyboard); err != nil {
+		log.Printf("[warn] error sending category selection prompt: %v", err)
+	}
+}
+
+// validateAmount cancels the amount transition when the input doesn't start with a positive amount,
+// so the user stays in the amount input state and can try again.
+func (sm *BotStateManager) validateAmount(e *fsm.Event, conv Conversation) {
+	if e.Src == "Idle" {
+		sm.checkCanEdit(e, conv)
+		if e.Err != nil {
+			return
+		}
+	}
+
+	if _, _, err := parseAmountInput(sm.UserValues[conv]); err != nil {
+		e.Cancel(err)
+
+		if err := sm.sendBotResponse(conv, sm.text(conv.UserID, "amount.invalid"), nil); err != nil {
+			log.Printf("[warn] error sending invalid amount message: %v", err)
+		}
+	}
+}
+
+func (sm *BotStateManager) promptDateSelection(conv Conversation) {
+	text := sm.text(conv.UserID, "date.prompt")
+	keyboard := sm.TbKeyboards.GetDateKeyboard(sm.Language(conv.UserID))
+
+	err := sm.sendBotResponse(conv, text, &keyboard)
+	if err != nil {
+		log.Printf("[warn] error sending date selection prompt: %v", err)
+		return
+	}
+}
+
+// validateDate cancels the date transition when the entered date can't be parsed,
+// so the user stays in the date selection state and can try again.
+func (sm *BotStateManager) validateDate(e *fsm.Event, conv Conversation) {
+	if _, err := parseSpendingDate(sm.UserValues[conv], time.Now()); err != nil {
+		e.Cancel(err)
+
+		text := sm.text(conv.UserID, "date.invalid")
+		if err := sm.sendBotResponse(conv, text, nil); err != nil {
+			log.Printf("[warn] error sending invalid date message: %v", err)
+		}
+	}
+}
+
+func (sm *BotStateManager) saveSpending(ctx context.Context, conv Conversation) {
+	stateData, err := sm.getStateData(conv)
 	if err != nil {
 		log.Printf("[warn] error fetching state data: %v", err)
 		return
@@ -269,37 +623,103 @@ func (sm *BotStateManager) saveSpending(ctx context.Context, userID int64) {
 		return
 	}
 
-	amount := stateData["AmountEntered"].(string)
-	amountFloat, err := strconv.ParseFloat(amount, 64)
+	amountFloat, description, err := parseAmountInput(stateData["AmountEntered"].(string))
 	if err != nil {
 		log.Printf("[warn] error converting amount to float: %v", err)
 		return
 	}
 
-	// TODO: Validate amount and if it's not a number, return an error message and stay in the same state
+	// spendings are recorded for today unless a date was picked or typed along with the amount
+	spendingDate, err := parseSpendingDate(stringValue(stateData, "DateSelected"), time.Now())
+	if err != nil {
+		log.Printf("[warn] error parsing spending date: %v", err)
+		return
+	}
+	if day, rest, ok := cutSpendingDate(description, time.Now()); ok {
+		spendingDate, description = day, rest
+	}
+
+	member, err := sm.Ledgers.LedgerFor(conv)
+	if err != nil {
+		log.Printf("[warn] error fetching active ledger: %v", err)
+		return
+	}
+
+	accountID, err := sm.spendingAccount(conv, stateData)
+	if err != nil {
+		log.Printf("[warn] error resolving spending account: %v", err)
+		return
+	}
+
+	payerID, _ := strconv.ParseInt(strings.TrimPrefix(stringValue(stateData, "PayerSelected"), keyboards.PayerPrefix), 10, 64)
+	shares, err := sm.spendingShares(member.LedgerID, stateData, amountFloat)
+	if err != nil {
+		log.Printf("[warn] error splitting spending: %v", err)
+		return
+	}
+
 	spending := storage.SpendingInfo{
-		UserID:      userID,
+		UserID:      conv.UserID,
+		PayerID:     payerID,
+		LedgerID:    member.LedgerID,
+		AccountID:   accountID,
 		CategoryID:  int64(categoryID),
 		Amount:      amountFloat,
-		Description: "",
-		Timestamp:   time.Now(),
+		Description: description,
+		Timestamp:   spendingDate,
+		Shares:      shares,
+		Tags:        parseTags(description),
 	}
 
-	if err := sm.Spendings.AddSpending(spending); err != nil {
-		log.Printf("[warn] error saving spending for user %d: %v", userID, err)
+	spendingID, err := sm.Spendings.AddSpending(spending)
+	if err != nil {
+		log.Printf("[warn] error saving spending for %v: %v", conv, err)
 		return
 	}
 
-	text := "Spending saved!"
-	err = sm.sendBotResponse(userID, text, nil)
+	text := sm.text(conv.UserID, "spending.saved")
+	if conv.IsGroup() {
+		text = sm.text(conv.UserID, "spending.saved_by", member.Name)
+	}
+
+	if kind, fileID, ok := strings.Cut(stringValue(stateData, receiptKey), ":"); ok {
+		receipt := storage.ReceiptInfo{Kind: kind, FileID: fileID}
+		if err = sm.Receipts.Attach(conv, spendingID, receipt); err != nil {
+			log.Printf("[warn] error attaching receipt to spending %d: %v", spendingID, err)
+		} else {
+			text += " 📎"
+		}
+	}
+
+	sent, err := sm.sendBotMessage(conv, text, nil)
 	if err != nil {
 		log.Printf("[warn] error sending spending save prompt: %v", err)
 		return
 	}
+	if err = sm.Receipts.LinkMessage(conv, sent.MessageID, spendingID); err != nil {
+		log.Printf("[warn] error linking saved spending message: %v", err)
+	}
 
-	if err := sm.UserFSMs[userID].Event(ctx, "SpendingSaved"); err != nil {
-		log.Printf("[warn] error transitioning to Idle after saving spending for user %d: %v", userID, err)
+	spending.ID = spendingID
+	sm.checkUnusual(conv, spending)
+
+	if err := sm.UserFSMs[conv].Event(ctx, "SpendingSaved"); err != nil {
+		log.Printf("[warn] error transitioning to Idle after saving spending for %v: %v", conv, err)
+	}
+}
+
+// parseAmountInput splits the amount step input into the amount and an optional description following it,
+// like "12,50 lunch with the team #work".
+func parseAmountInput(text string) (float64, string, error) {
+	amountText, description, _ := strings.Cut(strings.TrimSpace(text), " ")
+	amount, err := strconv.ParseFloat(strings.ReplaceAll(amountText, ",", "."), 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid amount %q: %w", amountText, err)
+	}
+	if amount <= 0 || math.IsInf(amount, 0) || math.IsNaN(amount) {
+		return 0, "", fmt.Errorf("amount %q is not positive", amountText)
 	}
+	return amount, strings.TrimSpace(description), nil
 }
 
 func unmarshalUserData(jsonData string) (map[string]interface{}, error) {
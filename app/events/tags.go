@@ -0,0 +1,121 @@
+package events
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"github.com/nyanyamaga/finance-tracker-bot/app/i18n"
+	"github.com/nyanyamaga/finance-tracker-bot/app/storage"
+	"regexp"
+	"strings"
+)
+
+// tagPattern matches hashtags like #vacation2026 or #work-reimbursable.
+var tagPattern = regexp.MustCompile(`#([\p{L}\p{N}_-]+)`)
+
+// parseTags returns the distinct hashtags of a description, lowercased and without the #.
+func parseTags(description string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, match := range tagPattern.FindAllStringSubmatch(description, -1) {
+		tag := strings.ToLower(match[1])
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// Tags sums all spendings of the conversation's ledger per tag, or, when a tag is given,
+// the spendings carrying it per category.
+func (r *BotReporter) Tags(lang string, conv Conversation, tag string) (string, error) {
+	member, err := r.Ledgers.LedgerFor(conv)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	tag = strings.ToLower(strings.TrimPrefix(tag, "#"))
+	if tag == "" {
+		totals, err := r.Spendings.SummarizeByTag(storage.SpendingFilter{LedgerID: member.LedgerID})
+		if err != nil {
+			return "", err
+		}
+		if len(totals) == 0 {
+			return i18n.Text(lang, "tags.empty"), nil
+		}
+
+		fmt.Fprintf(&sb, "🏷 *%s*\n\n", i18n.Text(lang, "tags.title"))
+		for _, t := range totals {
+			fmt.Fprintf(&sb, "#%s — %.2f (%d)\n", t.Tag, t.Total, t.Count)
+		}
+		return sb.String(), nil
+	}
+
+	categories, err := r.Categories.ListCategories(member.LedgerID)
+	if err != nil {
+		return "", err
+	}
+
+	totals, err := r.Spendings.SummarizeByCategory(storage.SpendingFilter{LedgerID: member.LedgerID, Tags: []string{tag}})
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Fprintf(&sb, "🏷 *#%s*\n\n", tag)
+	if len(totals) == 0 {
+		sb.WriteString(i18n.Text(lang, "tags.not_found"))
+		return sb.String(), nil
+	}
+
+	var total float64
+	for _, t := range rollUpTotals(totals, categories, 0) {
+		fmt.Fprintf(&sb, "%s %s — %.2f (%d)\n", t.Emoji, t.Name, t.Total, t.Count)
+		total += t.Total
+	}
+	fmt.Fprintf(&sb, "\n*%s: %.2f*", i18n.Text(lang, "report.total"), total)
+	return sb.String(), nil
+}
+
+// Export writes all spendings of the conversation's ledger as CSV, only the ones carrying the tag when given.
+func (r *BotReporter) Export(conv Conversation, tag string) ([]byte, error) {
+	member, err := r.Ledgers.LedgerFor(conv)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := storage.SpendingFilter{LedgerID: member.LedgerID}
+	if tag = strings.ToLower(strings.TrimPrefix(tag, "#")); tag != "" {
+		filter.Tags = []string{tag}
+	}
+
+	records, err := r.Spendings.FindSpendings(filter, -1, 0) // a negative limit lifts it
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err = w.Write([]string{"date", "category", "amount", "description", "tags"}); err != nil {
+		return nil, fmt.Errorf("failed to write export header: %w", err)
+	}
+	for _, record := range records {
+		row := []string{
+			record.Timestamp.Format("2006-01-02"),
+			record.CategoryName,
+			fmt.Sprintf("%.2f", record.Amount),
+			record.Description,
+			strings.Join(parseTags(record.Description), " "),
+		}
+		if err = w.Write(row); err != nil {
+			return nil, fmt.Errorf("failed to write export row: %w", err)
+		}
+	}
+	w.Flush()
+	if err = w.Error(); err != nil {
+		return nil, fmt.Errorf("failed to write export: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,112 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"github.com/nyanyamaga/finance-tracker-bot/app/keyboards"
+	"github.com/nyanyamaga/finance-tracker-bot/app/storage"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Unusual spending detection
+const (
+	unusualFactor     = 3  // spendings larger than this many category medians are unusual
+	unusualMinSamples = 5  // categories with fewer earlier spendings have no usual amount yet
+	unusualSamples    = 50 // latest spendings of the category the median is taken over
+)
+
+// checkUnusual asks to confirm a saved spending much larger than the usual spending of its category,
+// offering to fix the amount in case of a typo.
+func (sm *BotStateManager) checkUnusual(conv Conversation, spending storage.SpendingInfo) {
+	amounts, err := sm.Spendings.CategoryAmounts(spending.LedgerID, spending.CategoryID, spending.ID, unusualSamples)
+	if err != nil {
+		log.Printf("[warn] error fetching category amounts: %v", err)
+		return
+	}
+	if len(amounts) < unusualMinSamples {
+		return
+	}
+
+	usual := median(amounts)
+	if usual <= 0 || spending.Amount <= unusualFactor*usual {
+		return
+	}
+
+	text := sm.text(conv.UserID, "unusual.prompt", spending.Amount, spending.Amount/usual, usual)
+	keyboard := sm.TbKeyboards.GetUnusualKeyboard(sm.Language(conv.UserID), spending.ID)
+	if err = sm.sendBotResponse(conv, text, &keyboard); err != nil {
+		log.Printf("[warn] error sending unusual spending prompt: %v", err)
+	}
+}
+
+// promptAmountFix asks for the correct amount of the spending being fixed.
+func (sm *BotStateManager) promptAmountFix(conv Conversation) {
+	if err := sm.sendBotResponse(conv, sm.text(conv.UserID, "unusual.enter_amount"), nil); err != nil {
+		log.Printf("[warn] error sending amount fix prompt: %v", err)
+	}
+}
+
+// saveAmountFix changes the amount of the spending being fixed, then returns to the main menu either way.
+func (sm *BotStateManager) saveAmountFix(ctx context.Context, conv Conversation) {
+	defer func() {
+		if err := sm.UserFSMs[conv].Event(ctx, "AmountFixSaved"); err != nil {
+			log.Printf("[warn] error transitioning to Idle after fixing amount for %v: %v", conv, err)
+		}
+	}()
+
+	stateData, err := sm.getStateData(conv)
+	if err != nil {
+		log.Printf("[warn] error fetching state data: %v", err)
+		return
+	}
+
+	spendingID, _ := strconv.ParseInt(strings.TrimPrefix(stringValue(stateData, "ChooseFixAmount"), keyboards.UnusualFixPrefix), 10, 64)
+	amount, _, err := parseAmountInput(stringValue(stateData, "AmountFixEntered"))
+	if err != nil {
+		log.Printf("[warn] error converting fixed amount to float: %v", err)
+		return
+	}
+
+	text := sm.text(conv.UserID, "error.generic")
+	if err = sm.fixAmount(conv, spendingID, amount); err != nil {
+		log.Printf("[warn] error fixing amount of spending %d for %v: %v", spendingID, conv, err)
+	} else {
+		text = sm.text(conv.UserID, "unusual.fixed", amount)
+	}
+	if err = sm.sendBotResponse(conv, text, nil); err != nil {
+		log.Printf("[warn] error sending amount fixed message: %v", err)
+	}
+}
+
+// fixAmount changes the amount of a spending of the conversation's ledger.
+func (sm *BotStateManager) fixAmount(conv Conversation, spendingID int64, amount float64) error {
+	member, err := sm.Ledgers.LedgerFor(conv)
+	if err != nil {
+		return err
+	}
+
+	spending, err := sm.Spendings.GetSpending(spendingID)
+	if err != nil {
+		return err
+	}
+	if spending.LedgerID != member.LedgerID || !canEdit(member) {
+		return fmt.Errorf("user %d can't change spending %d: %w", conv.UserID, spendingID, ErrPermissionDenied)
+	}
+
+	return sm.Spendings.UpdateAmount(spendingID, amount)
+}
+
+// median returns the middle value of the values, the mean of the two middle ones for an even count.
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
@@ -0,0 +1,289 @@
+package i18n
+
+var english = map[string]string{
+	"language.name":   "English",
+	"language.prompt": "Choose your language:",
+	"language.saved":  "Language set to English.",
+
+	"error.generic": "Something went wrong, please try again later.",
+
+	"start.welcome":      "Welcome! Choose an option.",
+	"main.choose_option": "Choose an option:",
+
+	"action.add_spending": "Add spending",
+	"action.new_category": "New spending category",
+
+	"category.select":      "Please select a category:",
+	"category.enter_name":  "Please enter the name of the new category, or `Parent / Name` for a sub-category:",
+	"category.enter_emoji": "Please enter the emoji for the new category:",
+	"category.saved":       "Category saved!",
+	"category.not_found":   "There's no category *%s* in this ledger.",
+	"category.whole":       "All of %s",
+	"category.back":        "Back",
+	"favorite.usage":       "Pin a category to the top of the category keyboard with `/favorite <category>`, the same command unpins it.",
+	"favorite.added":       "%s %s is pinned to the top.",
+	"favorite.removed":     "%s %s is unpinned.",
+
+	"amount.enter":   "Please enter the amount, optionally followed by a description with #tags, like `12.50 lunch #work`. It's recorded for today unless you add a date like `yesterday` or `12.03`, or pick one with 📅:",
+	"amount.invalid": "That doesn't look like an amount. Please enter a positive number, like `12.50`:",
+
+	"date.prompt":    "When was it? Pick a day or type a date like `yesterday`, `fri` or `12.03`:",
+	"date.invalid":   "Sorry, I couldn't understand that date. Try `yesterday`, `fri`, `12.03` or pick one from the buttons.",
+	"date.choose":    "📅 Earlier date",
+	"date.today":     "Today",
+	"date.yesterday": "Yesterday",
+	"date.pick":      "Pick date",
+
+	"spending.saved":    "Spending saved!",
+	"spending.saved_by": "Spending by %s saved to the group ledger!",
+
+	"payer.select": "Who paid?",
+	"payer.me":     "Me",
+
+	"split.select":          "How should it be split between ledger members?",
+	"split.none":            "Don't split",
+	"split.equal":           "Equally",
+	"split.percent":         "By percentage",
+	"split.exact":           "By exact amounts",
+	"split.enter_percent":   "Enter each member's share in percent, separated by spaces, in this order: %[2]s. For example: `%[3]s`",
+	"split.enter_exact":     "Enter each member's share of %[1].2f, separated by spaces, in this order: %[2]s. For example: `%[3]s`",
+	"split.invalid_percent": "Enter one percentage per member (%[2]s) adding up to 100, for example: `%[3]s`",
+	"split.invalid_exact":   "Enter one amount per member (%[2]s) adding up to %[1].2f, for example: `%[3]s`",
+
+	"ledger.read_only":      "You can only view this ledger. Ask its owner for editor access.",
+	"ledger.list":           "Your ledgers, tap one to make it active. Create a shared one with `/newledger <name>`.",
+	"ledger.switched":       "Switched to ledger *%s*.",
+	"ledger.new_usage":      "Usage: `/newledger <name>`",
+	"ledger.created":        "Ledger *%s* is created and active now. Use /invite to add members.",
+	"ledger.owner_only":     "Only the ledger owner can invite members.",
+	"ledger.invite_usage":   "Usage: `/invite [editor|viewer]`",
+	"ledger.invite":         "Send this one-time link to the person you want to join *%s* as %s. It is valid for 7 days:\n`%s`",
+	"ledger.invite_button":  "Join %s",
+	"ledger.invite_invalid": "This invite link is invalid, expired or already used.",
+	"ledger.joined":         "You joined *%s*, it is your active ledger now.",
+	"ledger.members":        "Members of *%s*:",
+
+	"role.owner":  "owner",
+	"role.editor": "editor",
+	"role.viewer": "viewer",
+
+	"report.empty":          "No spendings yet this month.",
+	"report.total":          "Total",
+	"report.all_members":    "All members",
+	"report.all_categories": "All categories",
+
+	"settings.title":          "Settings, tap to change:",
+	"settings.saved":          "Saved.",
+	"settings.weekly_digest":  "Weekly digest on Mondays",
+	"settings.monthly_digest": "Monthly digest on the 1st",
+
+	"reminder.text":           "You haven't logged any spendings today. Anything to add?",
+	"reminder.snooze":         "⏰ In %d h",
+	"reminder.snoozed":        "I'll remind you in %d h.",
+	"reminder.set":            "Done, I'll remind you at that time if you log nothing during the day.",
+	"reminder.disabled":       "Daily reminder is off.",
+	"reminder.quiet_set":      "Quiet hours saved, no reminders will come in them.",
+	"reminder.quiet_disabled": "Quiet hours removed.",
+	"reminder.off_value":      "off",
+	"reminder.status":         "Daily reminder: *%s*\nQuiet hours: *%s*\nTime zone: *%s*\n\nChange with `/remind 21:00`, `/remind off`, `/remind quiet 23:00-08:00` or `/timezone`.",
+	"reminder.usage":          "Usage: `/remind 21:00`, `/remind off`, `/remind quiet 23:00-08:00` or `/remind quiet off`",
+
+	"timezone.usage": "Usage: `/timezone Europe/Berlin` or `/timezone +3`",
+	"timezone.set":   "Time zone set to *%s*, it's %s there now.",
+
+	"budget.none":           "No budgets yet. Set a monthly one with `/budget <amount>` or `/budget <amount> <category>`.",
+	"budget.usage":          "Usage: `/budget <amount> [category]`, an amount of 0 removes the budget. `/budget rollover [category]` switches carrying over what's left, `/budget cover <category> from <category>` pulls overspending from another envelope.",
+	"budget.hint":           "🔁 rolls over · ↪ carried over · ⇄ moved between envelopes\n`/budget rollover <category>` carries what's left over to the next month, `/budget cover <category> from <category>` pulls overspending from another envelope.",
+	"budget.not_found":      "There's no such budget, set one with `/budget <amount> [category]` first.",
+	"budget.rollover_on":    "What's left of this budget will carry over to the next month.",
+	"budget.rollover_off":   "This budget starts from scratch every month.",
+	"budget.covered":        "Covered %.2f of overspending.",
+	"budget.not_overspent":  "This envelope isn't overspent.",
+	"budget.envelope_empty": "There's nothing left in that envelope.",
+
+	"digest.title_weekly":   "Weekly digest",
+	"digest.title_monthly":  "Monthly digest",
+	"digest.empty":          "No spendings in this period.\n",
+	"digest.spent":          "Spent",
+	"digest.change_weekly":  "%s vs the previous week",
+	"digest.change_monthly": "%s vs the previous month",
+	"digest.top_categories": "Top categories",
+	"digest.largest":        "Largest spendings",
+	"digest.budgets":        "Budgets",
+
+	"chart.kind_categories": "Categories",
+	"chart.kind_trend":      "Trend",
+	"chart.period_week":     "Week",
+	"chart.period_month":    "Month",
+	"chart.period_year":     "Year",
+	"chart.title_week":      "last 7 days",
+	"chart.title_year":      "last 12 months",
+	"chart.other":           "Other",
+	"chart.empty":           "No spendings in this period.",
+
+	"find.usage":   "Search spendings with /find followed by words from the description or category name and any of the filters:\n`>100`, `<=50` — amount\n`cat:Food` — category\n`#vacation` — tag\n`from:01.09.2026 to:30.09.2026` — dates\n\nFor example: `/find coffee >5 from:monday`",
+	"find.empty":   "Nothing found.",
+	"find.summary": "Found %d, total %.2f",
+
+	"tags.title":     "Tags",
+	"tags.empty":     "No tagged spendings yet. Add #tags to the description when entering the amount, like `12.50 taxi #work`.",
+	"tags.not_found": "No spendings with this tag.",
+
+	"quick.confirm":        "%s\nSave to this category?",
+	"quick.choose":         "%s\nPlease select a category:",
+	"quick.other_category": "Another category",
+
+	"rules.title":   "Auto-categorization rules",
+	"rules.empty":   "No rules yet. Categories are still suggested from your past spendings.",
+	"rules.usage":   "Add a rule with `/rules add <keyword> <category>`, like `/rules add uber Transport`. Put keywords of several words in double quotes.",
+	"rules.deleted": "Rule removed",
+
+	"history.title":          "History",
+	"history.empty":          "No spendings here.",
+	"history.newer":          "Newer",
+	"history.older":          "Older",
+	"history.period_all":     "All time",
+	"history.scope_everyone": "Everyone",
+	"history.scope_mine":     "Mine",
+	"history.receipt":        "receipt",
+
+	"receipt.attached": "📎 Receipt attached.",
+	"receipt.pending":  "📎 Receipt received, it will be attached to the spending once it's saved.",
+	"receipt.hint":     "To attach a receipt, send it while adding a spending or reply with it to the saved spending message.",
+	"receipt.missing":  "The receipt is no longer available.",
+
+	"goals.title":              "Goals",
+	"goals.empty":              "No goals yet.",
+	"goals.usage":              "Add a goal with the button below or `/goals add <name>: <target> [by <date>]`, like `/goals add Vacation: 2000 by June`.",
+	"goals.new":                "🎯 New goal",
+	"goals.deadline":           "by %s",
+	"goals.reached":            "Reached!",
+	"goals.no_rate":            "No contributions yet",
+	"goals.projected":          "At this rate, reached by %s",
+	"goals.enter_details":      "Please enter the goal like `Vacation: 2000 by June`, the deadline is optional:",
+	"goals.edit_details":       "Currently `%s`. Please enter the new name, target and deadline:",
+	"goals.invalid":            "Please enter the goal like `Vacation: 2000 by June` or `Car: 15000 by 12.2027`.",
+	"goals.saved":              "Goal saved!",
+	"goals.enter_contribution": "How much do you put aside?",
+	"goals.contributed":        "%.2f put aside!",
+	"goals.closed":             "Goal closed",
+
+	"accounts.title": "Accounts",
+	"accounts.total": "Total",
+	"accounts.empty": "No accounts yet.",
+	"accounts.usage": "Add an account with `/accounts add <name> [cash|card|savings] [opening balance]`, like `/accounts add Card card 1500`. Move money between accounts with `/transfer <amount> <from> <to>`, transfers don't count as spendings.",
+	"account.select": "Which account did you pay from?",
+	"transfer.usage": "Please enter the transfer like `/transfer 200 Card Cash`, or `/transfer 200 Main card > Cash` when names have spaces. See your accounts with /accounts.",
+	"transfer.done":  "Transfer recorded.",
+
+	"compare.title":  "%s–%s compared",
+	"compare.legend": "this month · last month · ⌀ 3 months average, same days",
+	"compare.new":    "new",
+
+	"forecast.title":   "Month-end forecast",
+	"forecast.legend":  "spent so far → projected by the end of the month",
+	"forecast.budgets": "Forecast against budgets",
+	"forecast.over":    "over by %.2f",
+
+	"unusual.prompt":       "That's *%.2f*, %.1f× the usual %.2f in this category. Is it correct?",
+	"unusual.confirm":      "✅ Yes, correct",
+	"unusual.fix":          "✏️ Fix it",
+	"unusual.confirmed":    "Kept as is.",
+	"unusual.enter_amount": "Please enter the correct amount:",
+	"unusual.fixed":        "Amount changed to %.2f.",
+
+	"inline.hint":      "Type an amount and a description, like 12.50 coffee",
+	"inline.read_only": "You can only view your active ledger",
+	"inline.result":    "💸 %.2f · %s",
+
+	"command.start":     "Start over with the main menu",
+	"command.help":      "List the commands",
+	"command.report":    "This month's spendings by category",
+	"command.compare":   "This month against the previous ones",
+	"command.chart":     "Charts of spendings",
+	"command.history":   "Latest spendings",
+	"command.find":      "Search spendings",
+	"command.tags":      "Spendings per tag",
+	"command.export":    "Export spendings as CSV",
+	"command.budget":    "Budgets and envelopes",
+	"command.goals":     "Savings goals",
+	"command.accounts":  "Accounts and their balances",
+	"command.transfer":  "Move money between accounts",
+	"command.balances":  "Who owes whom in the ledger",
+	"command.rules":     "Category rules for quick entries",
+	"command.favorite":  "Pin a category on top",
+	"command.ledgers":   "Switch between ledgers",
+	"command.newledger": "Create a shared ledger",
+	"command.invite":    "Invite a member to the ledger",
+	"command.members":   "Members of the ledger",
+	"command.remind":    "Daily reminder to log spendings",
+	"command.timezone":  "Set your time zone",
+	"command.settings":  "Digests and other settings",
+	"command.language":  "Change the language",
+
+	"command.admin": "Usage statistics and broadcasts",
+
+	"admin.denied":            "Sorry, this command is only available to the bot's admins.",
+	"admin.usage":             "Usage: `/admin` for statistics, `/admin broadcast <text>` to message every user.",
+	"admin.title":             "Bot statistics",
+	"admin.users":             "Users: %d",
+	"admin.active":            "Active users: %d in 24h · %d in 7 days · %d in 30 days",
+	"admin.records":           "Records: %d ledgers · %d categories · %d spendings",
+	"admin.database":          "Database size: %.1f MB",
+	"admin.errors":            "Errors in the last 24h: %d",
+	"admin.broadcast_started": "Sending the message to every user, this may take a while…",
+	"admin.broadcast_done":    "Broadcast finished: %d delivered, %d failed.",
+
+	"command.access": "Who may use the bot",
+
+	"access.title":             "Access",
+	"access.mode_open":         "open to everyone",
+	"access.mode_allowlist":    "allowlisted users only",
+	"access.mode_invite":       "invite only",
+	"access.status_pending":    "⏳",
+	"access.status_approved":   "✅",
+	"access.status_revoked":    "⛔",
+	"access.empty":             "Nobody asked for access yet.",
+	"access.usage":             "Usage: `/access` for the list, `/access approve <id|@username>`, `/access revoke <id|@username>`, `/access invite` for a one-time invite link.",
+	"access.pending":           "This bot is private. Your request for access was sent to the admins, you'll get a message once it's approved.",
+	"access.invite_required":   "This bot is invite only. Ask an admin for an invite link.",
+	"access.revoked":           "Your access to this bot was revoked.",
+	"access.approved":          "Your access to the bot was approved, welcome! Send /start to begin.",
+	"access.requested":         "%s asked for access. Approve with `/access approve %d`.",
+	"access.approved_by_admin": "Access approved for %s.",
+	"access.revoked_by_admin":  "Access revoked for %s.",
+	"access.user_not_found":    "No user %s asked for access, approve them by their ID instead.",
+	"access.invite":            "One-time invite link, valid for 7 days:\n%s",
+
+	"help.title":      "Commands",
+	"help.footer":     "Send an amount with a description, like `12.50 coffee`, to log a spending right away.",
+	"command.unknown": "Unknown command, see /help for the list.",
+
+	"balances.title":        "Balances",
+	"balances.settled":      "Everyone is settled up.",
+	"balances.settle_up":    "To settle up:",
+	"balances.mark_settled": "✅ %s → %s: %.2f",
+	"balances.recorded":     "Marked as settled.",
+	"balances.outdated":     "This transfer is no longer needed, balances have changed.",
+
+	"month.1":  "January",
+	"month.2":  "February",
+	"month.3":  "March",
+	"month.4":  "April",
+	"month.5":  "May",
+	"month.6":  "June",
+	"month.7":  "July",
+	"month.8":  "August",
+	"month.9":  "September",
+	"month.10": "October",
+	"month.11": "November",
+	"month.12": "December",
+
+	"weekday.0": "Su",
+	"weekday.1": "Mo",
+	"weekday.2": "Tu",
+	"weekday.3": "We",
+	"weekday.4": "Th",
+	"weekday.5": "Fr",
+	"weekday.6": "Sa",
+}
@@ -0,0 +1,81 @@
+package i18n
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Supported language codes
+const (
+	English = "en"
+	Russian = "ru"
+
+	DefaultLanguage = English
+)
+
+// catalog maps a language code to its translated messages.
+var catalog = map[string]map[string]string{
+	English: english,
+	Russian: russian,
+}
+
+// Languages returns the supported language codes in a stable order.
+func Languages() []string {
+	return []string{English, Russian}
+}
+
+// Normalize maps a Telegram language code (e.g. "ru-RU") to a supported language,
+// falling back to the default language for unknown codes.
+func Normalize(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if i := strings.IndexAny(code, "-_"); i >= 0 {
+		code = code[:i]
+	}
+
+	if _, ok := catalog[code]; ok {
+		return code
+	}
+	return DefaultLanguage
+}
+
+// Text returns the message for a key in the given language, formatted with args if any.
+// Missing translations fall back to the default language and then to the key itself.
+func Text(lang, key string, args ...interface{}) string {
+	msg, ok := catalog[Normalize(lang)][key]
+	if !ok {
+		if msg, ok = catalog[DefaultLanguage][key]; !ok {
+			msg = key
+		}
+	}
+
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// Match looks for the text among translations of the given keys in every language
+// and returns the matched key. It is used to recognize reply-keyboard buttons
+// regardless of the language they were rendered in.
+func Match(text string, keys ...string) (string, bool) {
+	text = strings.TrimSpace(text)
+	for _, messages := range catalog {
+		for _, key := range keys {
+			if msg, ok := messages[key]; ok && strings.EqualFold(msg, text) {
+				return key, true
+			}
+		}
+	}
+	return "", false
+}
+
+// MonthName returns the localized name of a month.
+func MonthName(lang string, month time.Month) string {
+	return Text(lang, fmt.Sprintf("month.%d", month))
+}
+
+// WeekdayShort returns the localized two-letter weekday name.
+func WeekdayShort(lang string, weekday time.Weekday) string {
+	return Text(lang, fmt.Sprintf("weekday.%d", weekday))
+}
@@ -0,0 +1,289 @@
+package i18n
+
+var russian = map[string]string{
+	"language.name":   "Русский",
+	"language.prompt": "Выберите язык:",
+	"language.saved":  "Язык изменён на русский.",
+
+	"error.generic": "Что-то пошло не так, попробуйте позже.",
+
+	"start.welcome":      "Добро пожаловать! Выберите действие.",
+	"main.choose_option": "Выберите действие:",
+
+	"action.add_spending": "Добавить трату",
+	"action.new_category": "Новая категория трат",
+
+	"category.select":      "Выберите категорию:",
+	"category.enter_name":  "Введите название новой категории или `Родитель / Название` для подкатегории:",
+	"category.enter_emoji": "Введите эмодзи для новой категории:",
+	"category.saved":       "Категория сохранена!",
+	"category.not_found":   "В этом журнале нет категории *%s*.",
+	"category.whole":       "Всё в %s",
+	"category.back":        "Назад",
+	"favorite.usage":       "Закрепите категорию вверху списка командой `/favorite <категория>`, та же команда открепляет её.",
+	"favorite.added":       "%s %s закреплена вверху.",
+	"favorite.removed":     "%s %s откреплена.",
+
+	"amount.enter":   "Введите сумму, можно с описанием и #тегами, например `12.50 обед #работа`. Трата запишется на сегодня, если не добавить дату, например `вчера` или `12.03`, или не выбрать её кнопкой 📅:",
+	"amount.invalid": "Это не похоже на сумму. Введите положительное число, например `12.50`:",
+
+	"date.prompt":    "Когда это было? Выберите день или введите дату, например `вчера`, `пт` или `12.03`:",
+	"date.invalid":   "Не удалось распознать дату. Попробуйте `вчера`, `пт`, `12.03` или выберите день кнопками.",
+	"date.choose":    "📅 Другая дата",
+	"date.today":     "Сегодня",
+	"date.yesterday": "Вчера",
+	"date.pick":      "Выбрать дату",
+
+	"spending.saved":    "Трата сохранена!",
+	"spending.saved_by": "Трата участника %s сохранена в журнал группы!",
+
+	"payer.select": "Кто платил?",
+	"payer.me":     "Я",
+
+	"split.select":          "Как разделить трату между участниками журнала?",
+	"split.none":            "Не делить",
+	"split.equal":           "Поровну",
+	"split.percent":         "В процентах",
+	"split.exact":           "Точными суммами",
+	"split.enter_percent":   "Введите долю каждого участника в процентах через пробел в таком порядке: %[2]s. Например: `%[3]s`",
+	"split.enter_exact":     "Введите долю каждого участника от %[1].2f через пробел в таком порядке: %[2]s. Например: `%[3]s`",
+	"split.invalid_percent": "Введите по одному проценту на участника (%[2]s), в сумме 100, например: `%[3]s`",
+	"split.invalid_exact":   "Введите по одной сумме на участника (%[2]s), в сумме %[1].2f, например: `%[3]s`",
+
+	"ledger.read_only":      "Этот журнал доступен вам только для просмотра. Попросите владельца дать права редактора.",
+	"ledger.list":           "Ваши журналы, нажмите на журнал, чтобы сделать его активным. Общий журнал создаётся командой `/newledger <название>`.",
+	"ledger.switched":       "Активный журнал: *%s*.",
+	"ledger.new_usage":      "Использование: `/newledger <название>`",
+	"ledger.created":        "Журнал *%s* создан и выбран активным. Добавьте участников командой /invite.",
+	"ledger.owner_only":     "Приглашать участников может только владелец журнала.",
+	"ledger.invite_usage":   "Использование: `/invite [editor|viewer]`",
+	"ledger.invite":         "Отправьте эту одноразовую ссылку человеку, которого хотите добавить в *%s* как %s. Ссылка действует 7 дней:\n`%s`",
+	"ledger.invite_button":  "Присоединиться к %s",
+	"ledger.invite_invalid": "Ссылка-приглашение недействительна, устарела или уже использована.",
+	"ledger.joined":         "Вы присоединились к *%s*, теперь это ваш активный журнал.",
+	"ledger.members":        "Участники *%s*:",
+
+	"role.owner":  "владелец",
+	"role.editor": "редактор",
+	"role.viewer": "наблюдатель",
+
+	"report.empty":          "В этом месяце трат пока нет.",
+	"report.total":          "Итого",
+	"report.all_members":    "Все участники",
+	"report.all_categories": "Все категории",
+
+	"settings.title":          "Настройки, нажмите, чтобы изменить:",
+	"settings.saved":          "Сохранено.",
+	"settings.weekly_digest":  "Сводка за неделю по понедельникам",
+	"settings.monthly_digest": "Сводка за месяц 1-го числа",
+
+	"reminder.text":           "Сегодня вы ещё не записали ни одной траты. Есть что добавить?",
+	"reminder.snooze":         "⏰ Через %d ч",
+	"reminder.snoozed":        "Напомню через %d ч.",
+	"reminder.set":            "Готово, напомню в это время, если за день ничего не будет записано.",
+	"reminder.disabled":       "Ежедневное напоминание выключено.",
+	"reminder.quiet_set":      "Тихие часы сохранены, в это время напоминаний не будет.",
+	"reminder.quiet_disabled": "Тихие часы убраны.",
+	"reminder.off_value":      "выкл.",
+	"reminder.status":         "Ежедневное напоминание: *%s*\nТихие часы: *%s*\nЧасовой пояс: *%s*\n\nИзменить: `/remind 21:00`, `/remind off`, `/remind quiet 23:00-08:00` или `/timezone`.",
+	"reminder.usage":          "Использование: `/remind 21:00`, `/remind off`, `/remind quiet 23:00-08:00` или `/remind quiet off`",
+
+	"timezone.usage": "Использование: `/timezone Europe/Moscow` или `/timezone +3`",
+	"timezone.set":   "Часовой пояс: *%s*, сейчас там %s.",
+
+	"budget.none":           "Бюджетов пока нет. Задайте месячный командой `/budget <сумма>` или `/budget <сумма> <категория>`.",
+	"budget.usage":          "Использование: `/budget <сумма> [категория]`, сумма 0 удаляет бюджет. `/budget rollover [категория]` включает и выключает перенос остатка, `/budget cover <категория> из <категория>` покрывает перерасход из другого конверта.",
+	"budget.hint":           "🔁 остаток переносится · ↪ перенесено · ⇄ перемещено между конвертами\n`/budget rollover <категория>` переносит остаток на следующий месяц, `/budget cover <категория> из <категория>` покрывает перерасход из другого конверта.",
+	"budget.not_found":      "Такого бюджета нет, сначала задайте его командой `/budget <сумма> [категория]`.",
+	"budget.rollover_on":    "Остаток этого бюджета будет переноситься на следующий месяц.",
+	"budget.rollover_off":   "Этот бюджет каждый месяц начинается с нуля.",
+	"budget.covered":        "Перерасход покрыт на %.2f.",
+	"budget.not_overspent":  "В этом конверте нет перерасхода.",
+	"budget.envelope_empty": "В том конверте ничего не осталось.",
+
+	"digest.title_weekly":   "Сводка за неделю",
+	"digest.title_monthly":  "Сводка за месяц",
+	"digest.empty":          "За этот период трат нет.\n",
+	"digest.spent":          "Потрачено",
+	"digest.change_weekly":  "%s к прошлой неделе",
+	"digest.change_monthly": "%s к прошлому месяцу",
+	"digest.top_categories": "Главные категории",
+	"digest.largest":        "Крупнейшие траты",
+	"digest.budgets":        "Бюджеты",
+
+	"chart.kind_categories": "Категории",
+	"chart.kind_trend":      "Динамика",
+	"chart.period_week":     "Неделя",
+	"chart.period_month":    "Месяц",
+	"chart.period_year":     "Год",
+	"chart.title_week":      "последние 7 дней",
+	"chart.title_year":      "последние 12 месяцев",
+	"chart.other":           "Прочее",
+	"chart.empty":           "За этот период трат нет.",
+
+	"find.usage":   "Ищите траты командой /find со словами из описания или названия категории и любыми фильтрами:\n`>100`, `<=50` — сумма\n`cat:Еда` — категория\n`#отпуск` — тег\n`from:01.09.2026 to:30.09.2026` — даты\n\nНапример: `/find кофе >5 from:понедельник`",
+	"find.empty":   "Ничего не найдено.",
+	"find.summary": "Найдено %d, всего %.2f",
+
+	"tags.title":     "Теги",
+	"tags.empty":     "Трат с тегами пока нет. Добавьте #теги в описание при вводе суммы, например `12.50 такси #работа`.",
+	"tags.not_found": "Трат с этим тегом нет.",
+
+	"quick.confirm":        "%s\nСохранить в эту категорию?",
+	"quick.choose":         "%s\nВыберите категорию:",
+	"quick.other_category": "Другая категория",
+
+	"rules.title":   "Правила категорий",
+	"rules.empty":   "Правил пока нет. Категории всё равно подсказываются по прошлым тратам.",
+	"rules.usage":   "Добавьте правило командой `/rules add <слово> <категория>`, например `/rules add uber Транспорт`. Слова с пробелами берите в двойные кавычки.",
+	"rules.deleted": "Правило удалено",
+
+	"history.title":          "История",
+	"history.empty":          "Здесь трат нет.",
+	"history.newer":          "Новее",
+	"history.older":          "Старше",
+	"history.period_all":     "Всё время",
+	"history.scope_everyone": "Все",
+	"history.scope_mine":     "Мои",
+	"history.receipt":        "чек",
+
+	"receipt.attached": "📎 Чек прикреплён.",
+	"receipt.pending":  "📎 Чек получен, он будет прикреплён к трате после сохранения.",
+	"receipt.hint":     "Чтобы прикрепить чек, отправьте его во время добавления траты или ответом на сообщение о сохранённой трате.",
+	"receipt.missing":  "Чек больше недоступен.",
+
+	"goals.title":              "Цели",
+	"goals.empty":              "Целей пока нет.",
+	"goals.usage":              "Добавьте цель кнопкой ниже или командой `/goals add <название>: <сумма> [до <дата>]`, например `/goals add Отпуск: 2000 до июня`.",
+	"goals.new":                "🎯 Новая цель",
+	"goals.deadline":           "до %s",
+	"goals.reached":            "Цель достигнута!",
+	"goals.no_rate":            "Пока нет взносов",
+	"goals.projected":          "В этом темпе цель будет достигнута к %s",
+	"goals.enter_details":      "Введите цель в виде `Отпуск: 2000 до июня`, срок можно не указывать:",
+	"goals.edit_details":       "Сейчас `%s`. Введите новое название, сумму и срок:",
+	"goals.invalid":            "Введите цель в виде `Отпуск: 2000 до июня` или `Машина: 15000 до 12.2027`.",
+	"goals.saved":              "Цель сохранена!",
+	"goals.enter_contribution": "Сколько вы откладываете?",
+	"goals.contributed":        "Отложено %.2f!",
+	"goals.closed":             "Цель закрыта",
+
+	"accounts.title": "Счета",
+	"accounts.total": "Всего",
+	"accounts.empty": "Счетов пока нет.",
+	"accounts.usage": "Добавьте счёт командой `/accounts add <название> [cash|card|savings] [начальный остаток]`, например `/accounts add Карта card 1500`. Переводите деньги между счетами командой `/transfer <сумма> <откуда> <куда>`, переводы не считаются тратами.",
+	"account.select": "С какого счёта вы платили?",
+	"transfer.usage": "Введите перевод в виде `/transfer 200 Карта Наличные` или `/transfer 200 Основная карта > Наличные`, если в названиях есть пробелы. Список счетов — /accounts.",
+	"transfer.done":  "Перевод записан.",
+
+	"compare.title":  "Сравнение за %s–%s",
+	"compare.legend": "этот месяц · прошлый месяц · ⌀ среднее за 3 месяца, те же дни",
+	"compare.new":    "новое",
+
+	"forecast.title":   "Прогноз на конец месяца",
+	"forecast.legend":  "потрачено → ожидается к концу месяца",
+	"forecast.budgets": "Прогноз по бюджетам",
+	"forecast.over":    "превышение на %.2f",
+
+	"unusual.prompt":       "Это *%.2f*, в %.1f раза больше обычных %.2f в этой категории. Всё верно?",
+	"unusual.confirm":      "✅ Да, верно",
+	"unusual.fix":          "✏️ Исправить",
+	"unusual.confirmed":    "Оставлено как есть.",
+	"unusual.enter_amount": "Введите правильную сумму:",
+	"unusual.fixed":        "Сумма изменена на %.2f.",
+
+	"inline.hint":      "Введите сумму и описание, например 12.50 кофе",
+	"inline.read_only": "Активный журнал доступен только для просмотра",
+	"inline.result":    "💸 %.2f · %s",
+
+	"command.start":     "Начать заново с главного меню",
+	"command.help":      "Список команд",
+	"command.report":    "Траты за месяц по категориям",
+	"command.compare":   "Этот месяц в сравнении с прошлыми",
+	"command.chart":     "Графики трат",
+	"command.history":   "Последние траты",
+	"command.find":      "Поиск трат",
+	"command.tags":      "Траты по тегам",
+	"command.export":    "Выгрузить траты в CSV",
+	"command.budget":    "Бюджеты и конверты",
+	"command.goals":     "Цели накоплений",
+	"command.accounts":  "Счета и их остатки",
+	"command.transfer":  "Перевод между счетами",
+	"command.balances":  "Кто кому должен в журнале",
+	"command.rules":     "Правила категорий для быстрого ввода",
+	"command.favorite":  "Закрепить категорию наверху",
+	"command.ledgers":   "Переключить журнал",
+	"command.newledger": "Создать общий журнал",
+	"command.invite":    "Пригласить участника в журнал",
+	"command.members":   "Участники журнала",
+	"command.remind":    "Ежедневное напоминание о тратах",
+	"command.timezone":  "Указать часовой пояс",
+	"command.settings":  "Дайджесты и другие настройки",
+	"command.language":  "Сменить язык",
+
+	"command.admin": "Статистика и рассылки",
+
+	"admin.denied":            "Извините, эта команда доступна только администраторам бота.",
+	"admin.usage":             "Использование: `/admin` — статистика, `/admin broadcast <текст>` — сообщение всем пользователям.",
+	"admin.title":             "Статистика бота",
+	"admin.users":             "Пользователи: %d",
+	"admin.active":            "Активные пользователи: %d за 24 часа · %d за 7 дней · %d за 30 дней",
+	"admin.records":           "Записи: %d журналов · %d категорий · %d трат",
+	"admin.database":          "Размер базы данных: %.1f МБ",
+	"admin.errors":            "Ошибки за 24 часа: %d",
+	"admin.broadcast_started": "Отправляю сообщение всем пользователям, это может занять время…",
+	"admin.broadcast_done":    "Рассылка завершена: доставлено %d, не доставлено %d.",
+
+	"command.access": "Кто может пользоваться ботом",
+
+	"access.title":             "Доступ",
+	"access.mode_open":         "открыт всем",
+	"access.mode_allowlist":    "только разрешённые пользователи",
+	"access.mode_invite":       "только по приглашениям",
+	"access.status_pending":    "⏳",
+	"access.status_approved":   "✅",
+	"access.status_revoked":    "⛔",
+	"access.empty":             "Доступ ещё никто не запрашивал.",
+	"access.usage":             "Использование: `/access` — список, `/access approve <id|@username>`, `/access revoke <id|@username>`, `/access invite` — одноразовая ссылка-приглашение.",
+	"access.pending":           "Это закрытый бот. Запрос доступа отправлен администраторам, вы получите сообщение, когда его одобрят.",
+	"access.invite_required":   "Бот доступен только по приглашениям. Попросите ссылку-приглашение у администратора.",
+	"access.revoked":           "Ваш доступ к боту отозван.",
+	"access.approved":          "Доступ к боту одобрен, добро пожаловать! Отправьте /start, чтобы начать.",
+	"access.requested":         "%s запрашивает доступ. Одобрить: `/access approve %d`.",
+	"access.approved_by_admin": "Доступ для %s одобрен.",
+	"access.revoked_by_admin":  "Доступ для %s отозван.",
+	"access.user_not_found":    "Пользователь %s доступ не запрашивал, одобрите его по ID.",
+	"access.invite":            "Одноразовая ссылка-приглашение, действует 7 дней:\n%s",
+
+	"help.title":      "Команды",
+	"help.footer":     "Отправьте сумму с описанием, например `12.50 кофе`, чтобы сразу записать трату.",
+	"command.unknown": "Неизвестная команда, список команд — /help.",
+
+	"balances.title":        "Балансы",
+	"balances.settled":      "Все в расчёте.",
+	"balances.settle_up":    "Чтобы рассчитаться:",
+	"balances.mark_settled": "✅ %s → %s: %.2f",
+	"balances.recorded":     "Отмечено как оплаченное.",
+	"balances.outdated":     "Этот перевод больше не нужен, балансы изменились.",
+
+	"month.1":  "Январь",
+	"month.2":  "Февраль",
+	"month.3":  "Март",
+	"month.4":  "Апрель",
+	"month.5":  "Май",
+	"month.6":  "Июнь",
+	"month.7":  "Июль",
+	"month.8":  "Август",
+	"month.9":  "Сентябрь",
+	"month.10": "Октябрь",
+	"month.11": "Ноябрь",
+	"month.12": "Декабрь",
+
+	"weekday.0": "Вс",
+	"weekday.1": "Пн",
+	"weekday.2": "Вт",
+	"weekday.3": "Ср",
+	"weekday.4": "Чт",
+	"weekday.5": "Пт",
+	"weekday.6": "Сб",
+}
@@ -0,0 +1,54 @@
+package keyboards
+
+import (
+	"fmt"
+	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/nyanyamaga/finance-tracker-bot/app/storage"
+)
+
+// AccountPrefix is the callback data prefix of account buttons, followed by the account ID.
+const AccountPrefix = "account_"
+
+// accountEmojis are the icons of account kinds.
+var accountEmojis = map[string]string{
+	storage.AccountCash:    "💵",
+	storage.AccountCard:    "💳",
+	storage.AccountSavings: "🏦",
+}
+
+// AccountLabel returns the name of an account prefixed with the icon of its kind.
+func AccountLabel(account storage.AccountInfo) string {
+	if emoji, ok := accountEmojis[account.Kind]; ok {
+		return emoji + " " + account.Name
+	}
+	return account.Name
+}
+
+// GetAccountKeyboard generates an inline keyboard to pick the account a spending is paid from,
+// the default account first and checked.
+func (tbk *TbKeyboardProvider) GetAccountKeyboard(accounts []storage.AccountInfo, defaultID int64) tbapi.InlineKeyboardMarkup {
+	var rows [][]tbapi.InlineKeyboardButton
+	for _, account := range accounts {
+		if account.ID == defaultID {
+			button := tbapi.NewInlineKeyboardButtonData("✓ "+AccountLabel(account), fmt.Sprintf("%s%d", AccountPrefix, account.ID))
+			rows = append(rows, tbapi.NewInlineKeyboardRow(button))
+		}
+	}
+
+	row := make([]tbapi.InlineKeyboardButton, 0, 2)
+	for _, account := range accounts {
+		if account.ID == defaultID {
+			continue
+		}
+		row = append(row, tbapi.NewInlineKeyboardButtonData(AccountLabel(account), fmt.Sprintf("%s%d", AccountPrefix, account.ID)))
+		if len(row) == 2 {
+			rows = append(rows, row)
+			row = make([]tbapi.InlineKeyboardButton, 0, 2)
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+
+	return tbapi.NewInlineKeyboardMarkup(rows...)
+}
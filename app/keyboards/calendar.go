@@ -0,0 +1,108 @@
+package keyboards
+
+import (
+	"fmt"
+	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/nyanyamaga/finance-tracker-bot/app/i18n"
+	"time"
+)
+
+// Date selection callback data
+const (
+	DateToday         = "date_today"
+	DateYesterday     = "date_yesterday"
+	DatePick          = "date_pick"
+	DateChoose        = "date_choose" // opens the date step from the amount prompt
+	DatePrefix        = "date_"       // followed by a date in DateLayout
+	CalendarNavPrefix = "calendar_"   // followed by a month in CalendarMonthLayout
+	CalendarIgnore    = "calendar_ignore"
+)
+
+// Layouts used to encode dates and months into callback data.
+const (
+	DateLayout          = "2006-01-02"
+	CalendarMonthLayout = "2006-01"
+)
+
+// GetDateKeyboard generates the inline keyboard offered when a spending date is asked.
+func (tbk *TbKeyboardProvider) GetDateKeyboard(lang string) tbapi.InlineKeyboardMarkup {
+	return tbapi.NewInlineKeyboardMarkup(
+		tbapi.NewInlineKeyboardRow(
+			tbapi.NewInlineKeyboardButtonData(i18n.Text(lang, "date.today"), DateToday),
+			tbapi.NewInlineKeyboardButtonData(i18n.Text(lang, "date.yesterday"), DateYesterday),
+		),
+		tbapi.NewInlineKeyboardRow(
+			tbapi.NewInlineKeyboardButtonData(i18n.Text(lang, "date.pick"), DatePick),
+		),
+	)
+}
+
+// GetAmountKeyboard generates the inline keyboard of the amount prompt, opening the date step for earlier spendings.
+// The button shows the picked date, if any.
+func (tbk *TbKeyboardProvider) GetAmountKeyboard(lang string, date time.Time) tbapi.InlineKeyboardMarkup {
+	label := i18n.Text(lang, "date.choose")
+	if !date.IsZero() {
+		label = "📅 " + date.Format("02.01.06")
+	}
+	return tbapi.NewInlineKeyboardMarkup(
+		tbapi.NewInlineKeyboardRow(tbapi.NewInlineKeyboardButtonData(label, DateChoose)),
+	)
+}
+
+// GetCalendarKeyboard generates an inline calendar for the month of the given time.
+// Days after today are not selectable and navigation never goes past the current month.
+func (tbk *TbKeyboardProvider) GetCalendarKeyboard(lang string, month time.Time) tbapi.InlineKeyboardMarkup {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	first := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, now.Location())
+
+	rows := [][]tbapi.InlineKeyboardButton{
+		tbapi.NewInlineKeyboardRow(tbapi.NewInlineKeyboardButtonData(fmt.Sprintf("%s %d", i18n.MonthName(lang, first.Month()), first.Year()), CalendarIgnore)),
+	}
+
+	var header []tbapi.InlineKeyboardButton
+	for i := 1; i <= 7; i++ {
+		weekday := time.Weekday(i % 7) // weeks start on Monday
+		header = append(header, tbapi.NewInlineKeyboardButtonData(i18n.WeekdayShort(lang, weekday), CalendarIgnore))
+	}
+	rows = append(rows, header)
+
+	// weeks start on Monday, so shift Go's Sunday-based weekday
+	offset := (int(first.Weekday()) + 6) % 7
+	week := make([]tbapi.InlineKeyboardButton, 0, 7)
+	for i := 0; i < offset; i++ {
+		week = append(week, tbapi.NewInlineKeyboardButtonData(" ", CalendarIgnore))
+	}
+
+	for day := first; day.Month() == first.Month(); day = day.AddDate(0, 0, 1) {
+		if day.After(today) {
+			week = append(week, tbapi.NewInlineKeyboardButtonData("·", CalendarIgnore))
+		} else {
+			week = append(week, tbapi.NewInlineKeyboardButtonData(fmt.Sprint(day.Day()), DatePrefix+day.Format(DateLayout)))
+		}
+
+		if len(week) == 7 {
+			rows = append(rows, week)
+			week = make([]tbapi.InlineKeyboardButton, 0, 7)
+		}
+	}
+
+	if len(week) > 0 {
+		for len(week) < 7 {
+			week = append(week, tbapi.NewInlineKeyboardButtonData(" ", CalendarIgnore))
+		}
+		rows = append(rows, week)
+	}
+
+	prev := first.AddDate(0, -1, 0)
+	next := first.AddDate(0, 1, 0)
+	nav := tbapi.NewInlineKeyboardRow(tbapi.NewInlineKeyboardButtonData("«", CalendarNavPrefix+prev.Format(CalendarMonthLayout)))
+	if !next.After(today) {
+		nav = append(nav, tbapi.NewInlineKeyboardButtonData("»", CalendarNavPrefix+next.Format(CalendarMonthLayout)))
+	} else {
+		nav = append(nav, tbapi.NewInlineKeyboardButtonData(" ", CalendarIgnore))
+	}
+	rows = append(rows, nav)
+
+	return tbapi.NewInlineKeyboardMarkup(rows...)
+}
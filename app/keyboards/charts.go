@@ -0,0 +1,42 @@
+package keyboards
+
+import (
+	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/nyanyamaga/finance-tracker-bot/app/i18n"
+)
+
+// ChartPrefix is the callback data prefix of chart buttons, followed by <kind>_<period>.
+const ChartPrefix = "chart_"
+
+// Chart kinds
+const (
+	ChartCategories = "categories"
+	ChartTrend      = "trend"
+)
+
+// Chart periods
+const (
+	ChartWeek  = "week"
+	ChartMonth = "month"
+	ChartYear  = "year"
+)
+
+// GetChartKeyboard generates an inline keyboard switching the chart kind and period, the shown ones marked.
+func (tbk *TbKeyboardProvider) GetChartKeyboard(lang, kind, period string) tbapi.InlineKeyboardMarkup {
+	button := func(text, k, p string, selected bool) tbapi.InlineKeyboardButton {
+		if selected {
+			text = "• " + text
+		}
+		return tbapi.NewInlineKeyboardButtonData(text, ChartPrefix+k+"_"+p)
+	}
+
+	var periods, kinds []tbapi.InlineKeyboardButton
+	for _, p := range []string{ChartWeek, ChartMonth, ChartYear} {
+		periods = append(periods, button(i18n.Text(lang, "chart.period_"+p), kind, p, p == period))
+	}
+	for _, k := range []string{ChartCategories, ChartTrend} {
+		kinds = append(kinds, button(i18n.Text(lang, "chart.kind_"+k), k, period, k == kind))
+	}
+
+	return tbapi.NewInlineKeyboardMarkup(periods, kinds)
+}
@@ -0,0 +1,33 @@
+package keyboards
+
+import (
+	"fmt"
+	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/nyanyamaga/finance-tracker-bot/app/i18n"
+	"github.com/nyanyamaga/finance-tracker-bot/app/storage"
+)
+
+// Goal callback data, the action prefixes are followed by a goal ID.
+const (
+	GoalPrefix           = "goal_"
+	GoalNew              = "goal_new"
+	GoalContributePrefix = "goal_contribute_"
+	GoalEditPrefix       = "goal_edit_"
+	GoalClosePrefix      = "goal_close_"
+)
+
+// GetGoalsKeyboard generates a row of actions per goal, contributing, editing and closing it,
+// and a button adding a new goal.
+func (tbk *TbKeyboardProvider) GetGoalsKeyboard(lang string, goals []storage.GoalInfo) tbapi.InlineKeyboardMarkup {
+	var rows [][]tbapi.InlineKeyboardButton
+	for _, goal := range goals {
+		rows = append(rows, tbapi.NewInlineKeyboardRow(
+			tbapi.NewInlineKeyboardButtonData("➕ "+goal.Name, fmt.Sprintf("%s%d", GoalContributePrefix, goal.ID)),
+			tbapi.NewInlineKeyboardButtonData("✏️", fmt.Sprintf("%s%d", GoalEditPrefix, goal.ID)),
+			tbapi.NewInlineKeyboardButtonData("✖️", fmt.Sprintf("%s%d", GoalClosePrefix, goal.ID)),
+		))
+	}
+	rows = append(rows, tbapi.NewInlineKeyboardRow(tbapi.NewInlineKeyboardButtonData(i18n.Text(lang, "goals.new"), GoalNew)))
+
+	return tbapi.NewInlineKeyboardMarkup(rows...)
+}
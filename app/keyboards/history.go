@@ -0,0 +1,77 @@
+package keyboards
+
+import (
+	"fmt"
+	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/nyanyamaga/finance-tracker-bot/app/i18n"
+	"github.com/nyanyamaga/finance-tracker-bot/app/storage"
+)
+
+// HistoryPrefix is the callback data prefix of history buttons, followed by <period>_<scope>_<cursor>.
+// The cursor is 0 for the latest spendings, o<id> for spendings older than the spending and n<id> for newer ones.
+const HistoryPrefix = "history_"
+
+// ReceiptPrefix is the callback data prefix of buttons re-sending the receipt of a spending, followed by its ID.
+const ReceiptPrefix = "receipt_"
+
+// HistoryAll shows the history of all time, other history periods are the chart ones.
+const HistoryAll = "all"
+
+// History scopes
+const (
+	HistoryEveryone = "everyone"
+	HistoryMine     = "mine"
+)
+
+// GetHistoryKeyboard generates the history navigation and filters, the selected ones marked.
+// Non-zero newerID and olderID are the first and the last spendings shown when there are more pages around them.
+// The scope filter is offered in shared ledgers only, the listed spendings with receipts get buttons re-sending them.
+func (tbk *TbKeyboardProvider) GetHistoryKeyboard(lang, period, scope string, newerID, olderID int64, shared bool, receipts []storage.SpendingRecord) tbapi.InlineKeyboardMarkup {
+	button := func(text, p, s string, selected bool) tbapi.InlineKeyboardButton {
+		if selected {
+			text = "• " + text
+		}
+		return tbapi.NewInlineKeyboardButtonData(text, HistoryPrefix+p+"_"+s+"_0")
+	}
+
+	var rows [][]tbapi.InlineKeyboardButton
+	var row []tbapi.InlineKeyboardButton
+	for _, record := range receipts {
+		text := fmt.Sprintf("📎 %s %s %.2f", i18n.Text(lang, "history.receipt"), record.Timestamp.Format("02.01"), record.Amount)
+		row = append(row, tbapi.NewInlineKeyboardButtonData(text, fmt.Sprintf("%s%d", ReceiptPrefix, record.ID)))
+		if len(row) == 2 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+
+	if newerID != 0 || olderID != 0 {
+		nav := tbapi.NewInlineKeyboardRow(tbapi.NewInlineKeyboardButtonData(" ", Noop), tbapi.NewInlineKeyboardButtonData(" ", Noop))
+		if newerID != 0 {
+			nav[0] = tbapi.NewInlineKeyboardButtonData("« "+i18n.Text(lang, "history.newer"), fmt.Sprintf("%s%s_%s_n%d", HistoryPrefix, period, scope, newerID))
+		}
+		if olderID != 0 {
+			nav[1] = tbapi.NewInlineKeyboardButtonData(i18n.Text(lang, "history.older")+" »", fmt.Sprintf("%s%s_%s_o%d", HistoryPrefix, period, scope, olderID))
+		}
+		rows = append(rows, nav)
+	}
+
+	var periods []tbapi.InlineKeyboardButton
+	for _, p := range []string{ChartWeek, ChartMonth, ChartYear} {
+		periods = append(periods, button(i18n.Text(lang, "chart.period_"+p), p, scope, p == period))
+	}
+	periods = append(periods, button(i18n.Text(lang, "history.period_all"), HistoryAll, scope, period == HistoryAll))
+	rows = append(rows, periods)
+
+	if shared {
+		rows = append(rows, tbapi.NewInlineKeyboardRow(
+			button(i18n.Text(lang, "history.scope_everyone"), period, HistoryEveryone, scope != HistoryMine),
+			button(i18n.Text(lang, "history.scope_mine"), period, HistoryMine, scope == HistoryMine),
+		))
+	}
+
+	return tbapi.NewInlineKeyboardMarkup(rows...)
+}
@@ -3,23 +3,67 @@ package keyboards
 import (
 	"fmt"
 	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/nyanyamaga/finance-tracker-bot/app/i18n"
 	"log"
+	"time"
 )
 
-func (tbk *TbKeyboardProvider) GetCategoryKeyboard(userID int64) tbapi.InlineKeyboardMarkup {
-	categories, err := tbk.Storage.ListCategories(userID)
+// CategoryPagePrefix is the callback data prefix of category keyboard pages, followed by <parent ID>_<page>.
+// The parent ID is 0 for top-level categories.
+const CategoryPagePrefix = "catpage_"
+
+// categoryUsageWindow is how far back spendings count towards the category order.
+const categoryUsageWindow = 90 * 24 * time.Hour
+
+// GetCategoryKeyboard generates a page of the category keyboard, pages are numbered from 0.
+// Categories go favorites first and then by recent usage, laid out in CategoryColumns columns.
+// Categories with sub-categories open them, the parent itself can be picked from there as well.
+func (tbk *TbKeyboardProvider) GetCategoryKeyboard(lang string, ledgerID, userID, parentID int64, page int) tbapi.InlineKeyboardMarkup {
+	categories, err := tbk.Storage.ListCategoriesByUsage(ledgerID, userID, parentID, time.Now().Add(-categoryUsageWindow))
 	if err != nil {
 		log.Printf("Error retrieving categories: %v", err)
 		return tbapi.NewInlineKeyboardMarkup()
 	}
 
+	columns, pageSize := max(tbk.CategoryColumns, 1), max(tbk.CategoryPageSize, 1)
+	pages := (len(categories) + pageSize - 1) / pageSize
+	page = max(0, min(page, pages-1))
+	if pages > 1 {
+		categories = categories[page*pageSize : min((page+1)*pageSize, len(categories))]
+	}
+
 	var rows [][]tbapi.InlineKeyboardButton
-	for _, category := range categories {
+	if parentID != 0 {
+		parent, err := tbk.Storage.GetCategory(parentID)
+		if err != nil {
+			log.Printf("Error retrieving parent category: %v", err)
+			return tbapi.NewInlineKeyboardMarkup()
+		}
+		rows = append(rows, tbapi.NewInlineKeyboardRow(tbapi.NewInlineKeyboardButtonData(
+			i18n.Text(lang, "category.whole", parent.Emoji+" "+parent.Name), fmt.Sprintf("category_%d", parent.ID),
+		)))
+	}
+
+	for i, category := range categories {
 		buttonText := category.Emoji + " " + category.Name
+		if category.Favorite {
+			buttonText = "⭐ " + buttonText
+		}
 		callbackData := fmt.Sprintf("category_%d", category.ID)
+		if category.Children > 0 {
+			buttonText += " ›"
+			callbackData = fmt.Sprintf("%s%d_0", CategoryPagePrefix, category.ID)
+		}
+
+		if i%columns == 0 {
+			rows = append(rows, []tbapi.InlineKeyboardButton{})
+		}
+		rows[len(rows)-1] = append(rows[len(rows)-1], tbapi.NewInlineKeyboardButtonData(buttonText, callbackData))
+	}
 
-		row := []tbapi.InlineKeyboardButton{tbapi.NewInlineKeyboardButtonData(buttonText, callbackData)}
-		rows = append(rows, row)
+	rows = append(rows, tbk.GetPagerKeyboard(fmt.Sprintf("%s%d_", CategoryPagePrefix, parentID), page, pages).InlineKeyboard...)
+	if parentID != 0 {
+		rows = append(rows, tbapi.NewInlineKeyboardRow(tbapi.NewInlineKeyboardButtonData("« "+i18n.Text(lang, "category.back"), CategoryPagePrefix+"0_0")))
 	}
 
 	keyboard := tbapi.NewInlineKeyboardMarkup(rows...)
@@ -2,10 +2,22 @@ package keyboards
 
 import "github.com/nyanyamaga/finance-tracker-bot/app/storage"
 
+// Category keyboard layout defaults
+const (
+	DefaultCategoryColumns  = 2
+	DefaultCategoryPageSize = 12
+)
+
 type TbKeyboardProvider struct {
-	Storage *storage.Category
+	Storage          *storage.Category
+	CategoryColumns  int // Category buttons per row
+	CategoryPageSize int // Category buttons per page, more are split into pages
 }
 
 func NewTbKeyboardProvider(storage *storage.Category) *TbKeyboardProvider {
-	return &TbKeyboardProvider{Storage: storage}
+	return &TbKeyboardProvider{
+		Storage:          storage,
+		CategoryColumns:  DefaultCategoryColumns,
+		CategoryPageSize: DefaultCategoryPageSize,
+	}
 }
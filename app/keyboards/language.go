@@ -0,0 +1,19 @@
+package keyboards
+
+import (
+	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/nyanyamaga/finance-tracker-bot/app/i18n"
+)
+
+// LanguagePrefix is the callback data prefix of language buttons, followed by a language code.
+const LanguagePrefix = "lang_"
+
+// GetLanguageKeyboard generates an inline keyboard with every supported language.
+func (tbk *TbKeyboardProvider) GetLanguageKeyboard() tbapi.InlineKeyboardMarkup {
+	var row []tbapi.InlineKeyboardButton
+	for _, lang := range i18n.Languages() {
+		row = append(row, tbapi.NewInlineKeyboardButtonData(i18n.Text(lang, "language.name"), LanguagePrefix+lang))
+	}
+
+	return tbapi.NewInlineKeyboardMarkup(row)
+}
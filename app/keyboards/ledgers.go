@@ -0,0 +1,68 @@
+package keyboards
+
+import (
+	"fmt"
+	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/nyanyamaga/finance-tracker-bot/app/i18n"
+	"github.com/nyanyamaga/finance-tracker-bot/app/storage"
+)
+
+// Ledger related callback data prefixes
+const (
+	LedgerPrefix       = "ledger_" // followed by a ledger ID
+	ReportMemberPrefix = "report_" // followed by <member user ID>_<parent category ID>, 0 for all members and categories
+)
+
+// GetLedgerKeyboard generates an inline keyboard to switch between the user's ledgers.
+func (tbk *TbKeyboardProvider) GetLedgerKeyboard(ledgers []storage.LedgerInfo, activeID int64) tbapi.InlineKeyboardMarkup {
+	var rows [][]tbapi.InlineKeyboardButton
+	for _, ledger := range ledgers {
+		text := ledger.Name
+		if ledger.ID == activeID {
+			text = "✅ " + text
+		}
+		rows = append(rows, tbapi.NewInlineKeyboardRow(tbapi.NewInlineKeyboardButtonData(text, fmt.Sprintf("%s%d", LedgerPrefix, ledger.ID))))
+	}
+
+	return tbapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// GetReportKeyboard generates an inline keyboard filtering a report by ledger member and drilling down
+// into categories with sub-categories. A non-zero parentID is the category drilled into.
+func (tbk *TbKeyboardProvider) GetReportKeyboard(lang string, members []storage.LedgerMemberInfo, selectedID int64, parents []storage.CategoryTotal, parentID int64) tbapi.InlineKeyboardMarkup {
+	button := func(text string, userID int64) tbapi.InlineKeyboardButton {
+		if userID == selectedID {
+			text = "• " + text
+		}
+		return tbapi.NewInlineKeyboardButtonData(text, fmt.Sprintf("%s%d_%d", ReportMemberPrefix, userID, parentID))
+	}
+
+	var rows [][]tbapi.InlineKeyboardButton
+	for _, parent := range parents {
+		text := fmt.Sprintf("🔍 %s %s", parent.Emoji, parent.Name)
+		rows = append(rows, tbapi.NewInlineKeyboardRow(tbapi.NewInlineKeyboardButtonData(text, fmt.Sprintf("%s%d_%d", ReportMemberPrefix, selectedID, parent.CategoryID))))
+	}
+	if parentID != 0 {
+		text := "« " + i18n.Text(lang, "report.all_categories")
+		rows = append(rows, tbapi.NewInlineKeyboardRow(tbapi.NewInlineKeyboardButtonData(text, fmt.Sprintf("%s%d_0", ReportMemberPrefix, selectedID))))
+	}
+
+	if len(members) < 2 {
+		return tbapi.NewInlineKeyboardMarkup(rows...)
+	}
+
+	rows = append(rows, tbapi.NewInlineKeyboardRow(button(i18n.Text(lang, "report.all_members"), 0)))
+	row := make([]tbapi.InlineKeyboardButton, 0, 2)
+	for _, member := range members {
+		row = append(row, button(member.Name, member.UserID))
+		if len(row) == 2 {
+			rows = append(rows, row)
+			row = make([]tbapi.InlineKeyboardButton, 0, 2)
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+
+	return tbapi.NewInlineKeyboardMarkup(rows...)
+}
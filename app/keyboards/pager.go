@@ -0,0 +1,33 @@
+package keyboards
+
+import (
+	"fmt"
+	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Noop is the callback data of buttons that only show information.
+const Noop = "noop"
+
+// FindPagePrefix is the callback data prefix of search result pages, followed by a page number.
+const FindPagePrefix = "find_"
+
+// GetPagerKeyboard generates prev/next buttons for a paginated list, pages are numbered from 0.
+// Callback data of a page is the prefix followed by the page number.
+func (tbk *TbKeyboardProvider) GetPagerKeyboard(prefix string, page, pages int) tbapi.InlineKeyboardMarkup {
+	if pages < 2 {
+		return tbapi.InlineKeyboardMarkup{InlineKeyboard: [][]tbapi.InlineKeyboardButton{}}
+	}
+
+	button := func(text string, target int) tbapi.InlineKeyboardButton {
+		if target < 0 || target >= pages {
+			return tbapi.NewInlineKeyboardButtonData(" ", Noop)
+		}
+		return tbapi.NewInlineKeyboardButtonData(text, fmt.Sprintf("%s%d", prefix, target))
+	}
+
+	return tbapi.NewInlineKeyboardMarkup(tbapi.NewInlineKeyboardRow(
+		button("«", page-1),
+		tbapi.NewInlineKeyboardButtonData(fmt.Sprintf("%d / %d", page+1, pages), Noop),
+		button("»", page+1),
+	))
+}
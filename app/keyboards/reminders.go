@@ -0,0 +1,31 @@
+package keyboards
+
+import (
+	"fmt"
+	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/nyanyamaga/finance-tracker-bot/app/i18n"
+)
+
+// Reminder callback data
+const (
+	ReminderPrefix       = "remind_"
+	ReminderAdd          = "remind_add"
+	ReminderSnoozePrefix = "remind_snooze_" // followed by minutes
+)
+
+// ReminderSnoozes lists snooze durations offered under a reminder, in minutes.
+var ReminderSnoozes = []int{60, 180}
+
+// GetReminderKeyboard generates the inline keyboard of a logging reminder.
+func (tbk *TbKeyboardProvider) GetReminderKeyboard(lang string) tbapi.InlineKeyboardMarkup {
+	var snoozes []tbapi.InlineKeyboardButton
+	for _, minutes := range ReminderSnoozes {
+		text := i18n.Text(lang, "reminder.snooze", minutes/60)
+		snoozes = append(snoozes, tbapi.NewInlineKeyboardButtonData(text, fmt.Sprintf("%s%d", ReminderSnoozePrefix, minutes)))
+	}
+
+	return tbapi.NewInlineKeyboardMarkup(
+		tbapi.NewInlineKeyboardRow(tbapi.NewInlineKeyboardButtonData("➕ "+i18n.Text(lang, ActionAddSpending), ReminderAdd)),
+		snoozes,
+	)
+}
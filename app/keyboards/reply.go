@@ -1,26 +1,32 @@
 package keyboards
 
-import tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+import (
+	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/nyanyamaga/finance-tracker-bot/app/i18n"
+)
 
-// Action identifiers
+// Action identifiers, also used as message catalog keys for the button text.
 const (
-	ActionAddSpending         = "ADD_SPENDING"
-	ActionNewSpendingCategory = "NEW_SPENDING_CATEGORY"
+	ActionAddSpending         = "action.add_spending"
+	ActionNewSpendingCategory = "action.new_category"
 )
 
-// ActionMessages maps action identifiers to user-facing text.
-var ActionMessages = map[string]string{
-	ActionAddSpending:         "Add spending",
-	ActionNewSpendingCategory: "New spending category",
+var mainActions = []string{ActionAddSpending, ActionNewSpendingCategory}
+
+// MatchAction returns the action identifier for a main keyboard button text in any language.
+func MatchAction(text string) (string, bool) {
+	return i18n.Match(text, mainActions...)
 }
 
 // GetMainKeyboard generates the main keyboard with dynamic actions.
-func (tbk *TbKeyboardProvider) GetMainKeyboard() tbapi.ReplyKeyboardMarkup {
+func (tbk *TbKeyboardProvider) GetMainKeyboard(lang string) tbapi.ReplyKeyboardMarkup {
+	var rows [][]tbapi.KeyboardButton
+	for _, action := range mainActions {
+		rows = append(rows, tbapi.NewKeyboardButtonRow(tbapi.NewKeyboardButton(i18n.Text(lang, action))))
+	}
+
 	return tbapi.ReplyKeyboardMarkup{
-		Keyboard: [][]tbapi.KeyboardButton{
-			{{Text: ActionMessages[ActionAddSpending]}},
-			{{Text: ActionMessages[ActionNewSpendingCategory]}},
-		},
+		Keyboard:       rows,
 		ResizeKeyboard: true,
 	}
 }
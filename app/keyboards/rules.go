@@ -0,0 +1,38 @@
+package keyboards
+
+import (
+	"fmt"
+	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/nyanyamaga/finance-tracker-bot/app/i18n"
+	"github.com/nyanyamaga/finance-tracker-bot/app/storage"
+)
+
+// RuleDeletePrefix is the callback data prefix of rule removal buttons, followed by a rule ID.
+const RuleDeletePrefix = "rule_del_"
+
+// GetRulesKeyboard generates buttons removing auto-categorization rules, two per row.
+func (tbk *TbKeyboardProvider) GetRulesKeyboard(rules []storage.RuleInfo) tbapi.InlineKeyboardMarkup {
+	var rows [][]tbapi.InlineKeyboardButton
+	for i, rule := range rules {
+		if i%2 == 0 {
+			rows = append(rows, []tbapi.InlineKeyboardButton{})
+		}
+		button := tbapi.NewInlineKeyboardButtonData("❌ "+rule.Keyword, fmt.Sprintf("%s%d", RuleDeletePrefix, rule.ID))
+		rows[len(rows)-1] = append(rows[len(rows)-1], button)
+	}
+
+	return tbapi.InlineKeyboardMarkup{InlineKeyboard: append([][]tbapi.InlineKeyboardButton{}, rows...)}
+}
+
+// GetSuggestionKeyboard generates the confirmation of a suggested category,
+// the other button swaps it to the full category keyboard.
+func (tbk *TbKeyboardProvider) GetSuggestionKeyboard(lang string, category storage.CategoryInfo) tbapi.InlineKeyboardMarkup {
+	return tbapi.NewInlineKeyboardMarkup(
+		tbapi.NewInlineKeyboardRow(
+			tbapi.NewInlineKeyboardButtonData(fmt.Sprintf("✅ %s %s", category.Emoji, category.Name), fmt.Sprintf("category_%d", category.ID)),
+		),
+		tbapi.NewInlineKeyboardRow(
+			tbapi.NewInlineKeyboardButtonData(i18n.Text(lang, "quick.other_category"), CategoryPagePrefix+"0_0"),
+		),
+	)
+}
@@ -0,0 +1,31 @@
+package keyboards
+
+import (
+	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/nyanyamaga/finance-tracker-bot/app/i18n"
+	"github.com/nyanyamaga/finance-tracker-bot/app/storage"
+)
+
+// Settings callback data
+const (
+	SettingsPrefix       = "settings_"
+	SettingsLanguage     = "settings_language"
+	SettingsDigestPrefix = "settings_digest_" // followed by a digest kind
+)
+
+// GetSettingsKeyboard generates an inline keyboard toggling the user's settings.
+func (tbk *TbKeyboardProvider) GetSettingsKeyboard(lang string, settings storage.UserSettingsInfo) tbapi.InlineKeyboardMarkup {
+	toggle := func(key string, enabled bool, data string) []tbapi.InlineKeyboardButton {
+		mark := "🔕 "
+		if enabled {
+			mark = "🔔 "
+		}
+		return tbapi.NewInlineKeyboardRow(tbapi.NewInlineKeyboardButtonData(mark+i18n.Text(lang, key), data))
+	}
+
+	return tbapi.NewInlineKeyboardMarkup(
+		toggle("settings.weekly_digest", settings.WeeklyDigest, SettingsDigestPrefix+storage.DigestWeekly),
+		toggle("settings.monthly_digest", settings.MonthlyDigest, SettingsDigestPrefix+storage.DigestMonthly),
+		tbapi.NewInlineKeyboardRow(tbapi.NewInlineKeyboardButtonData("🌐 "+i18n.Text(lang, "language.name"), SettingsLanguage)),
+	)
+}
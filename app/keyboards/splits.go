@@ -0,0 +1,75 @@
+package keyboards
+
+import (
+	"fmt"
+	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/nyanyamaga/finance-tracker-bot/app/i18n"
+	"github.com/nyanyamaga/finance-tracker-bot/app/storage"
+	"math"
+	"strings"
+)
+
+// Split related callback data
+const (
+	PayerPrefix  = "payer_" // followed by the paying member user ID
+	SplitPrefix  = "split_" // followed by the split mode
+	SplitNone    = "split_none"
+	SplitEqual   = "split_equal"
+	SplitPercent = "split_percent"
+	SplitExact   = "split_exact"
+	SettlePrefix = "settle_" // followed by <from user ID>_<to user ID>_<amount in cents>
+)
+
+// SplitModes lists the ways a spending can be split, in keyboard order.
+var SplitModes = []string{SplitNone, SplitEqual, SplitPercent, SplitExact}
+
+// GetPayerKeyboard generates an inline keyboard to pick the ledger member who paid, the current user first.
+func (tbk *TbKeyboardProvider) GetPayerKeyboard(lang string, members []storage.LedgerMemberInfo, userID int64) tbapi.InlineKeyboardMarkup {
+	rows := [][]tbapi.InlineKeyboardButton{
+		tbapi.NewInlineKeyboardRow(tbapi.NewInlineKeyboardButtonData(i18n.Text(lang, "payer.me"), fmt.Sprintf("%s%d", PayerPrefix, userID))),
+	}
+
+	row := make([]tbapi.InlineKeyboardButton, 0, 2)
+	for _, member := range members {
+		if member.UserID == userID {
+			continue
+		}
+		row = append(row, tbapi.NewInlineKeyboardButtonData(member.Name, fmt.Sprintf("%s%d", PayerPrefix, member.UserID)))
+		if len(row) == 2 {
+			rows = append(rows, row)
+			row = make([]tbapi.InlineKeyboardButton, 0, 2)
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+
+	return tbapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// GetSplitKeyboard generates an inline keyboard to choose how a spending is split between members.
+func (tbk *TbKeyboardProvider) GetSplitKeyboard(lang string) tbapi.InlineKeyboardMarkup {
+	var rows [][]tbapi.InlineKeyboardButton
+	for _, mode := range SplitModes {
+		rows = append(rows, tbapi.NewInlineKeyboardRow(tbapi.NewInlineKeyboardButtonData(i18n.Text(lang, "split."+strings.TrimPrefix(mode, SplitPrefix)), mode)))
+	}
+
+	return tbapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// GetSettleKeyboard generates an inline keyboard marking suggested settle-up transfers as paid.
+func (tbk *TbKeyboardProvider) GetSettleKeyboard(lang string, transfers []storage.SettlementInfo, members []storage.LedgerMemberInfo) tbapi.InlineKeyboardMarkup {
+	names := make(map[int64]string, len(members))
+	for _, member := range members {
+		names[member.UserID] = member.Name
+	}
+
+	var rows [][]tbapi.InlineKeyboardButton
+	for _, t := range transfers {
+		text := i18n.Text(lang, "balances.mark_settled", names[t.FromUserID], names[t.ToUserID], t.Amount)
+		data := fmt.Sprintf("%s%d_%d_%d", SettlePrefix, t.FromUserID, t.ToUserID, int64(math.Round(t.Amount*100)))
+		rows = append(rows, tbapi.NewInlineKeyboardRow(tbapi.NewInlineKeyboardButtonData(text, data)))
+	}
+
+	return tbapi.NewInlineKeyboardMarkup(rows...)
+}
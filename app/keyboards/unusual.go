@@ -0,0 +1,22 @@
+package keyboards
+
+import (
+	"fmt"
+	tbapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/nyanyamaga/finance-tracker-bot/app/i18n"
+)
+
+// Unusual spending callback data, the action prefixes are followed by a spending ID.
+const (
+	UnusualPrefix        = "unusual_"
+	UnusualConfirmPrefix = "unusual_ok_"
+	UnusualFixPrefix     = "unusual_fix_"
+)
+
+// GetUnusualKeyboard generates an inline keyboard confirming an unusually large spending or fixing its amount.
+func (tbk *TbKeyboardProvider) GetUnusualKeyboard(lang string, spendingID int64) tbapi.InlineKeyboardMarkup {
+	return tbapi.NewInlineKeyboardMarkup(tbapi.NewInlineKeyboardRow(
+		tbapi.NewInlineKeyboardButtonData(i18n.Text(lang, "unusual.confirm"), fmt.Sprintf("%s%d", UnusualConfirmPrefix, spendingID)),
+		tbapi.NewInlineKeyboardButtonData(i18n.Text(lang, "unusual.fix"), fmt.Sprintf("%s%d", UnusualFixPrefix, spendingID)),
+	))
+}
@@ -7,11 +7,16 @@ import (
 	"github.com/jmoiron/sqlx"
 	"github.com/nyanyamaga/finance-tracker-bot/app/events"
 	"github.com/nyanyamaga/finance-tracker-bot/app/keyboards"
+	"github.com/nyanyamaga/finance-tracker-bot/app/parsers"
 	"github.com/nyanyamaga/finance-tracker-bot/app/storage"
 	"log"
 	"os"
 	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
 	"syscall"
+	_ "time/tzdata" // user time zones must load on hosts without zoneinfo
 )
 
 var revision = "local"
@@ -36,6 +41,17 @@ func main() {
 func execute(ctx context.Context) error {
 	dataFilePath := os.Getenv("DATA_FILE_PATH")
 	telegramToken := os.Getenv("TELEGRAM_TOKEN")
+	adminIDs := parseUserIDs(os.Getenv("ADMIN_IDS"))
+	accessMode := os.Getenv("ACCESS_MODE")
+	if accessMode == "" {
+		accessMode = events.AccessOpen
+	}
+	if !slices.Contains(events.AccessModes, accessMode) {
+		return fmt.Errorf("unsupported access mode %q, use one of %s", accessMode, strings.Join(events.AccessModes, ", "))
+	}
+
+	errorLog := &events.ErrorLog{Out: os.Stderr}
+	log.SetOutput(errorLog)
 
 	dataDB, err := storage.NewSqliteDB(dataFilePath)
 	if err != nil {
@@ -44,7 +60,7 @@ func execute(ctx context.Context) error {
 	defer func(dataDB *sqlx.DB) {
 		err = dataDB.Close()
 		if err != nil {
-			log.Printf("[warn] error closing sqlite database: %v", err)
+			log.Printf("[error] error closing sqlite database: %v", err)
 		}
 	}(dataDB)
 
@@ -58,11 +74,76 @@ func execute(ctx context.Context) error {
 		return fmt.Errorf("failed to initialize user state storage: %v", err)
 	}
 
+	userSettingsDB, err := storage.NewUserSettings(dataDB)
+	if err != nil {
+		return fmt.Errorf("failed to initialize user settings storage: %v", err)
+	}
+
 	spendingDB, err := storage.NewSpending(dataDB)
 	if err != nil {
 		return fmt.Errorf("failed to initialize spending storage: %v", err)
 	}
 
+	ledgerDB, err := storage.NewLedger(dataDB)
+	if err != nil {
+		return fmt.Errorf("failed to initialize ledger storage: %v", err)
+	}
+
+	settlementDB, err := storage.NewSettlement(dataDB)
+	if err != nil {
+		return fmt.Errorf("failed to initialize settlement storage: %v", err)
+	}
+
+	budgetDB, err := storage.NewBudget(dataDB)
+	if err != nil {
+		return fmt.Errorf("failed to initialize budget storage: %v", err)
+	}
+
+	digestDB, err := storage.NewDigest(dataDB)
+	if err != nil {
+		return fmt.Errorf("failed to initialize digest storage: %v", err)
+	}
+
+	reminderDB, err := storage.NewReminder(dataDB)
+	if err != nil {
+		return fmt.Errorf("failed to initialize reminder storage: %v", err)
+	}
+
+	ruleDB, err := storage.NewRule(dataDB)
+	if err != nil {
+		return fmt.Errorf("failed to initialize rule storage: %v", err)
+	}
+
+	receiptDB, err := storage.NewReceipt(dataDB)
+	if err != nil {
+		return fmt.Errorf("failed to initialize receipt storage: %v", err)
+	}
+
+	goalDB, err := storage.NewGoal(dataDB)
+	if err != nil {
+		return fmt.Errorf("failed to initialize goal storage: %v", err)
+	}
+
+	accountDB, err := storage.NewAccount(dataDB)
+	if err != nil {
+		return fmt.Errorf("failed to initialize account storage: %v", err)
+	}
+
+	accessDB, err := storage.NewAccess(dataDB)
+	if err != nil {
+		return fmt.Errorf("failed to initialize access storage: %v", err)
+	}
+
+	statsDB, err := storage.NewStats(dataDB)
+	if err != nil {
+		return fmt.Errorf("failed to initialize stats storage: %v", err)
+	}
+
+	messageParser, err := parsers.NewParser(os.Getenv("BANK_TEMPLATES_FILE"))
+	if err != nil {
+		return fmt.Errorf("failed to initialize message parser: %v", err)
+	}
+
 	tbAPI, err := tbapi.NewBotAPI(telegramToken)
 	if err != nil {
 		return fmt.Errorf("can't make telegram bot, %w", err)
@@ -70,21 +151,132 @@ func execute(ctx context.Context) error {
 	tbAPI.Debug = false
 
 	botKeyboardProvider := keyboards.NewTbKeyboardProvider(categoryDB)
-	botStateManager := events.NewBotStateManager(tbAPI, botKeyboardProvider, userStateDB, categoryDB, spendingDB)
+	if columns, err := strconv.Atoi(os.Getenv("CATEGORY_COLUMNS")); err == nil && columns > 0 {
+		botKeyboardProvider.CategoryColumns = columns
+	}
+	if pageSize, err := strconv.Atoi(os.Getenv("CATEGORY_PAGE_SIZE")); err == nil && pageSize > 0 {
+		botKeyboardProvider.CategoryPageSize = pageSize
+	}
+	ledgerManager := events.NewBotLedgerManager(ledgerDB, userSettingsDB)
+	ruleManager := &events.BotRuleManager{
+		TbKeyboards: botKeyboardProvider,
+		Ledgers:     ledgerManager,
+		Categories:  categoryDB,
+		Spendings:   spendingDB,
+		Rules:       ruleDB,
+	}
+	receiptManager := &events.BotReceiptManager{
+		TbAPI:     tbAPI,
+		Ledgers:   ledgerManager,
+		Spendings: spendingDB,
+		Receipts:  receiptDB,
+		Dir:       os.Getenv("RECEIPTS_DIR"),
+	}
+	goalManager := &events.BotGoalManager{
+		TbKeyboards: botKeyboardProvider,
+		Ledgers:     ledgerManager,
+		Goals:       goalDB,
+	}
+	accountManager := &events.BotAccountManager{
+		Ledgers:  ledgerManager,
+		Accounts: accountDB,
+	}
+	botStateManager := events.NewBotStateManager(tbAPI, botKeyboardProvider, userStateDB, userSettingsDB, categoryDB, spendingDB, ledgerManager, ruleManager, receiptManager, goalManager, accountManager)
+	reporter := &events.BotReporter{
+		TbKeyboards: botKeyboardProvider,
+		Ledgers:     ledgerManager,
+		Categories:  categoryDB,
+		Spendings:   spendingDB,
+		Budgets:     budgetDB,
+	}
+	balanceManager := &events.BotBalanceManager{
+		TbKeyboards: botKeyboardProvider,
+		Ledgers:     ledgerManager,
+		Spendings:   spendingDB,
+		Settlements: settlementDB,
+		Accounts:    accountManager,
+	}
+	budgetManager := &events.BotBudgetManager{
+		Ledgers:    ledgerManager,
+		Categories: categoryDB,
+		Spendings:  spendingDB,
+		Budgets:    budgetDB,
+	}
+	settingsManager := &events.BotSettingsManager{
+		UserSettings: userSettingsDB,
+		Digests:      digestDB,
+		Reminders:    reminderDB,
+	}
+
+	adminManager := &events.BotAdminManager{
+		TbAPI:        tbAPI,
+		StateManager: botStateManager,
+		Storage:      statsDB,
+		Errors:       errorLog,
+		AdminIDs:     adminIDs,
+	}
+
+	allowedIDs, allowedUsernames := parseAllowlist(os.Getenv("ALLOWED_USERS"))
+	accessManager := &events.BotAccessManager{
+		TbAPI:            tbAPI,
+		Access:           accessDB,
+		Admins:           adminManager,
+		StateManager:     botStateManager,
+		Mode:             accessMode,
+		AllowedIDs:       allowedIDs,
+		AllowedUsernames: allowedUsernames,
+		BotUsername:      tbAPI.Self.UserName,
+	}
 
 	commandHandler := &events.BotCommandHandler{
 		TbAPI:        tbAPI,
 		TbKeyboards:  botKeyboardProvider,
 		StateManager: botStateManager,
+		Ledgers:      ledgerManager,
+		Categories:   categoryDB,
+		Reporter:     reporter,
+		Balances:     balanceManager,
+		Budgets:      budgetManager,
+		Settings:     settingsManager,
+		Rules:        ruleManager,
+		Goals:        goalManager,
+		Accounts:     accountManager,
+		Admins:       adminManager,
+		Access:       accessManager,
+		BotUsername:  tbAPI.Self.UserName,
+	}
+	if err = commandHandler.RegisterCommands(adminIDs); err != nil {
+		log.Printf("[error] error registering bot commands: %v", err)
 	}
 
 	messageHandler := &events.BotMessageHandler{
 		TbAPI:        tbAPI,
 		StateManager: botStateManager,
+		Receipts:     receiptManager,
+		Parser:       messageParser,
 	}
 
 	callbackQueryHandler := &events.BotCallbackQueryHandler{
+		TbAPI:        tbAPI,
+		TbKeyboards:  botKeyboardProvider,
+		StateManager: botStateManager,
+		Ledgers:      ledgerManager,
+		Reporter:     reporter,
+		Balances:     balanceManager,
+		Settings:     settingsManager,
+		Rules:        ruleManager,
+		Receipts:     receiptManager,
+		Goals:        goalManager,
+	}
+
+	inlineHandler := &events.BotInlineHandler{
+		TbAPI:        tbAPI,
 		StateManager: botStateManager,
+		Ledgers:      ledgerManager,
+		Categories:   categoryDB,
+		Spendings:    spendingDB,
+		Rules:        ruleManager,
+		Accounts:     accountManager,
 	}
 
 	listener := events.TelegramListener{
@@ -92,7 +284,39 @@ func execute(ctx context.Context) error {
 		CommandHandler:       commandHandler,
 		MessageHandler:       messageHandler,
 		CallbackQueryHandler: callbackQueryHandler,
+		InlineHandler:        inlineHandler,
+		Access:               accessManager,
+		Admins:               adminManager,
+		StateManager:         botStateManager,
+		Ledgers:              ledgerManager,
+	}
+
+	digestScheduler := &events.BotDigestScheduler{
+		TbAPI:        tbAPI,
+		UserSettings: userSettingsDB,
+		Digests:      digestDB,
+		Ledgers:      ledgerDB,
+		Categories:   categoryDB,
+		Spendings:    spendingDB,
+		Budgets:      budgetDB,
+	}
+	go digestScheduler.Run(ctx)
+
+	envelopeScheduler := &events.BotEnvelopeScheduler{
+		Categories: categoryDB,
+		Spendings:  spendingDB,
+		Budgets:    budgetDB,
+	}
+	go envelopeScheduler.Run(ctx)
+
+	reminderScheduler := &events.BotReminderScheduler{
+		TbAPI:        tbAPI,
+		TbKeyboards:  botKeyboardProvider,
+		UserSettings: userSettingsDB,
+		Reminders:    reminderDB,
+		Spendings:    spendingDB,
 	}
+	go reminderScheduler.Run(ctx)
 
 	err = listener.StartListening(ctx)
 	if err != nil {
@@ -101,3 +325,33 @@ func execute(ctx context.Context) error {
 
 	return nil
 }
+
+// parseUserIDs parses a comma separated list of Telegram user IDs, skipping invalid ones.
+func parseUserIDs(list string) []int64 {
+	var ids []int64
+	for _, field := range strings.Split(list, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			log.Printf("[warn] invalid user ID %q in configuration", field)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// parseAllowlist parses a comma separated list of Telegram user IDs and @usernames.
+func parseAllowlist(list string) (ids []int64, usernames []string) {
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if username, ok := strings.CutPrefix(field, "@"); ok {
+			usernames = append(usernames, username)
+			continue
+		}
+		ids = append(ids, parseUserIDs(field)...)
+	}
+	return ids, usernames
+}
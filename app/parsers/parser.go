@@ -0,0 +1,128 @@
+// Package parsers extracts spendings from texts users forward to the bot:
+// bank purchase notifications and fiscal receipt QR codes.
+package parsers
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Spending is a spending extracted from a text, a zero Date means the text didn't tell.
+type Spending struct {
+	Amount   float64
+	Merchant string
+	Date     time.Time
+	Source   string // Name of the template or SourceQR
+}
+
+// Parser extracts spendings from bank messages by templates and from fiscal QR payloads.
+type Parser struct {
+	Templates []Template
+}
+
+// NewParser creates a parser with the templates of the file, if set, tried before the default ones.
+func NewParser(templatesFile string) (*Parser, error) {
+	var templates []Template
+	if templatesFile != "" {
+		loaded, err := LoadTemplates(templatesFile)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, loaded...)
+	}
+
+	for _, t := range defaultTemplates {
+		if err := t.compile(); err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	return &Parser{Templates: templates}, nil
+}
+
+// Parse extracts a spending from the text, dates after now are dropped.
+func (p *Parser) Parse(text string, now time.Time) (Spending, bool) {
+	spending, ok := parseFiscalQR(text, now.Location())
+	if !ok {
+		spending, ok = p.parseTemplates(text, now)
+	}
+	if !ok {
+		return Spending{}, false
+	}
+
+	if spending.Date.After(now) {
+		spending.Date = time.Time{}
+	}
+	return spending, true
+}
+
+// parseTemplates returns the spending extracted by the first matching template.
+func (p *Parser) parseTemplates(text string, now time.Time) (Spending, bool) {
+	for _, t := range p.Templates {
+		match := t.re.FindStringSubmatch(text)
+		if match == nil {
+			continue
+		}
+
+		group := func(name string) string {
+			if i := t.re.SubexpIndex(name); i >= 0 {
+				return strings.TrimSpace(match[i])
+			}
+			return ""
+		}
+
+		amount, err := parseAmount(group("amount"))
+		if err != nil {
+			continue
+		}
+
+		spending := Spending{Amount: amount, Merchant: group("merchant"), Source: t.Name}
+		if date := group("date"); date != "" && t.DateLayout != "" {
+			if spending.Date, err = time.ParseInLocation(t.DateLayout, date, now.Location()); err == nil && spending.Date.Year() == 0 {
+				// layouts without a year mean the latest such day
+				spending.Date = spending.Date.AddDate(now.Year(), 0, 0)
+				if spending.Date.After(now) {
+					spending.Date = spending.Date.AddDate(-1, 0, 0)
+				}
+			}
+		}
+		return spending, true
+	}
+	return Spending{}, false
+}
+
+// parseAmount parses amounts the way banks write them: 1 234,56, 1,234.56 or 1234.56.
+// A lone comma followed by three digits separates thousands.
+func parseAmount(text string) (float64, error) {
+	text = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, text)
+
+	comma, dot := strings.LastIndex(text, ","), strings.LastIndex(text, ".")
+	switch {
+	case comma >= 0 && dot >= 0 && comma > dot:
+		text = strings.ReplaceAll(strings.ReplaceAll(text, ".", ""), ",", ".")
+	case comma >= 0 && dot >= 0:
+		text = strings.ReplaceAll(text, ",", "")
+	case comma >= 0 && strings.Count(text, ",") == 1 && len(text)-comma-1 != 3:
+		text = strings.ReplaceAll(text, ",", ".")
+	case comma >= 0:
+		text = strings.ReplaceAll(text, ",", "")
+	}
+
+	amount, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q: %w", text, err)
+	}
+	if amount <= 0 || math.IsInf(amount, 0) || math.IsNaN(amount) {
+		return 0, fmt.Errorf("amount %q is not positive", text)
+	}
+	return amount, nil
+}
@@ -0,0 +1,42 @@
+package parsers
+
+import (
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SourceQR is the source of spendings decoded from fiscal receipt QR codes.
+const SourceQR = "qr"
+
+// fiscalQRLayouts are the layouts of the receipt time in fiscal QR codes, with and without seconds.
+var fiscalQRLayouts = []string{"20060102T150405", "20060102T1504"}
+
+// parseFiscalQR decodes the query string of a fiscal receipt QR code,
+// like t=20260915T1830&s=1250.00&fn=9999078900004312&i=12345&fp=1234567890&n=1.
+// The code tells the time and the total of the receipt, but not the merchant.
+func parseFiscalQR(text string, loc *time.Location) (Spending, bool) {
+	text = strings.TrimSpace(text)
+	if strings.ContainsAny(text, " \n") || !strings.Contains(text, "&") {
+		return Spending{}, false
+	}
+
+	values, err := url.ParseQuery(text)
+	if err != nil || values.Get("t") == "" || values.Get("s") == "" || values.Get("fn") == "" {
+		return Spending{}, false
+	}
+
+	amount, err := parseAmount(values.Get("s"))
+	if err != nil {
+		return Spending{}, false
+	}
+
+	spending := Spending{Amount: amount, Source: SourceQR}
+	for _, layout := range fiscalQRLayouts {
+		if date, err := time.ParseInLocation(layout, values.Get("t"), loc); err == nil {
+			spending.Date = date
+			break
+		}
+	}
+	return spending, true
+}
@@ -0,0 +1,65 @@
+package parsers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// Template extracts spendings from bank messages of one format. The pattern names the groups it extracts:
+// amount is required, merchant and date are optional, the date is parsed with DateLayout.
+type Template struct {
+	Name       string `json:"name"`
+	Pattern    string `json:"pattern"`
+	DateLayout string `json:"date_layout"` // Go time layout, like 02.01.2006
+
+	re *regexp.Regexp
+}
+
+// defaultTemplates cover common card purchase notifications, templates from the file are tried before them.
+var defaultTemplates = []Template{
+	{
+		Name:    "purchase-ru",
+		Pattern: `(?im)(?:покупка|оплата|списание).{0,40}?(?P<amount>\d[\d\s]*(?:[.,]\d{1,2})?)\s*(?:р|руб|₽|rub)\.?\s+(?:в\s+(?:магазине\s+)?)?(?P<merchant>[^.\n]+?)(?:\.|\s+(?:баланс|доступно|остаток)|$)`,
+	},
+	{
+		Name:       "purchase-en",
+		Pattern:    `(?im)(?:purchase|payment|spent|paid).{0,20}?(?P<amount>\d[\d,]*(?:\.\d{1,2})?)\s*(?:[a-z]{3})?\s+(?:at|@)\s+(?P<merchant>.+?)(?:\s+on\s+(?P<date>\d{4}-\d{2}-\d{2}))?\.?\s*$`,
+		DateLayout: "2006-01-02",
+	},
+}
+
+// LoadTemplates reads templates from a JSON file holding an array of them.
+func LoadTemplates(file string) ([]Template, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read templates file %s: %w", file, err)
+	}
+
+	var templates []Template
+	if err = json.Unmarshal(data, &templates); err != nil {
+		return nil, fmt.Errorf("failed to parse templates file %s: %w", file, err)
+	}
+
+	for i := range templates {
+		if err = templates[i].compile(); err != nil {
+			return nil, err
+		}
+	}
+	return templates, nil
+}
+
+// compile compiles the pattern, which must have an amount group.
+func (t *Template) compile() error {
+	re, err := regexp.Compile(t.Pattern)
+	if err != nil {
+		return fmt.Errorf("invalid pattern of template %q: %w", t.Name, err)
+	}
+	if re.SubexpIndex("amount") < 0 {
+		return fmt.Errorf("pattern of template %q has no amount group", t.Name)
+	}
+
+	t.re = re
+	return nil
+}
@@ -0,0 +1,185 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Access statuses of users
+const (
+	AccessPending  = "pending"
+	AccessApproved = "approved"
+	AccessRevoked  = "revoked"
+)
+
+// Access represents storage of who may use the bot when it isn't open to everyone.
+type Access struct {
+	db *sqlx.DB
+}
+
+// AccessInfo is the access status of a user.
+type AccessInfo struct {
+	UserID    int64     `db:"user_id"`
+	Username  string    `db:"username"` // Telegram username without @, empty if the user has none
+	Name      string    `db:"name"`     // Display name shown to admins
+	Language  string    `db:"language"` // Language code Telegram reported when the user asked for access, may be empty
+	Status    string    `db:"status"`
+	Timestamp time.Time `db:"timestamp"` // When the status last changed
+}
+
+// AccessInviteInfo is a one-time code letting a user in when the bot is invite-only.
+type AccessInviteInfo struct {
+	Code      string    `db:"code"`
+	CreatedBy int64     `db:"created_by"`
+	UsedBy    int64     `db:"used_by"`
+	ExpiresAt time.Time `db:"expires_at"`
+	Timestamp time.Time `db:"timestamp"`
+}
+
+// NewAccess creates a new Access storage
+func NewAccess(db *sqlx.DB) (*Access, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS user_access (
+		user_id INTEGER PRIMARY KEY,
+		username TEXT NOT NULL DEFAULT '',
+		name TEXT NOT NULL DEFAULT '',
+		language TEXT NOT NULL DEFAULT '',
+		status TEXT NOT NULL,
+		timestamp DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user_access table: %w", err)
+	}
+	if err = addColumnIfMissing(db, "user_access", "language", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS access_invites (
+		code TEXT PRIMARY KEY,
+		created_by INTEGER NOT NULL,
+		used_by INTEGER NOT NULL DEFAULT 0,
+		expires_at DATETIME NOT NULL,
+		timestamp DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create access_invites table: %w", err)
+	}
+
+	return &Access{db: db}, nil
+}
+
+// GetAccess returns the access status of the user, nil if the user never asked for access.
+func (a *Access) GetAccess(userID int64) (*AccessInfo, error) {
+	var access AccessInfo
+	err := a.db.Get(&access, "SELECT * FROM user_access WHERE user_id = ?", userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access of user_id: %d: %w", userID, err)
+	}
+
+	return &access, nil
+}
+
+// FindAccess returns the access status of the user with the username, ignoring case, nil if there's none.
+func (a *Access) FindAccess(username string) (*AccessInfo, error) {
+	var access AccessInfo
+	err := a.db.Get(&access, "SELECT * FROM user_access WHERE username = ? COLLATE NOCASE", username)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find access of username: %s: %w", username, err)
+	}
+
+	return &access, nil
+}
+
+// ListAccess returns the access statuses of all users, pending requests first, the latest first within a status.
+func (a *Access) ListAccess() ([]AccessInfo, error) {
+	var access []AccessInfo
+	query := `SELECT * FROM user_access
+		ORDER BY CASE status WHEN 'pending' THEN 0 WHEN 'approved' THEN 1 ELSE 2 END, timestamp DESC`
+	if err := a.db.Select(&access, query); err != nil {
+		return nil, fmt.Errorf("failed to list access: %w", err)
+	}
+
+	return access, nil
+}
+
+// RequestAccess records a pending access request, reporting false when the user has a status already.
+func (a *Access) RequestAccess(info AccessInfo) (bool, error) {
+	query := `INSERT INTO user_access (user_id, username, name, language, status) VALUES (?, ?, ?, ?, ?) ON CONFLICT(user_id) DO NOTHING`
+	res, err := a.db.Exec(query, info.UserID, info.Username, info.Name, info.Language, AccessPending)
+	if err != nil {
+		return false, fmt.Errorf("failed to request access for user_id: %d: %w", info.UserID, err)
+	}
+
+	added, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to check access request of user_id: %d: %w", info.UserID, err)
+	}
+	return added > 0, nil
+}
+
+// SetStatus sets the access status of the user, keeping the known username, name and language when they're empty.
+func (a *Access) SetStatus(info AccessInfo) error {
+	query := `INSERT INTO user_access (user_id, username, name, language, status) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET status = excluded.status, timestamp = CURRENT_TIMESTAMP,
+			username = CASE WHEN excluded.username = '' THEN username ELSE excluded.username END,
+			name = CASE WHEN excluded.name = '' THEN name ELSE excluded.name END,
+			language = CASE WHEN excluded.language = '' THEN language ELSE excluded.language END`
+	if _, err := a.db.Exec(query, info.UserID, info.Username, info.Name, info.Language, info.Status); err != nil {
+		return fmt.Errorf("failed to set access of user_id: %d: %w", info.UserID, err)
+	}
+
+	log.Printf("[info] Access of user_id: %d set to %s", info.UserID, info.Status)
+	return nil
+}
+
+// CreateInvite stores a new access invitation code.
+func (a *Access) CreateInvite(invite AccessInviteInfo) error {
+	query := `INSERT INTO access_invites (code, created_by, expires_at) VALUES (?, ?, ?)`
+	if _, err := a.db.Exec(query, invite.Code, invite.CreatedBy, invite.ExpiresAt); err != nil {
+		return fmt.Errorf("failed to insert access invite: %w", err)
+	}
+
+	log.Printf("[info] Access invite created by user_id: %d", invite.CreatedBy)
+	return nil
+}
+
+// UseInvite redeems an access invitation code, approving the user. The code can't be used again.
+func (a *Access) UseInvite(code string, info AccessInfo, now time.Time) error {
+	tx, err := a.db.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to start access invite transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // rollback after commit is a no-op
+
+	query := `UPDATE access_invites SET used_by = ? WHERE code = ? AND used_by = 0 AND expires_at > ?`
+	res, err := tx.Exec(query, info.UserID, code, now)
+	if err != nil {
+		return fmt.Errorf("failed to mark access invite as used: %w", err)
+	}
+	if used, err := res.RowsAffected(); err != nil || used == 0 {
+		return ErrInviteNotFound
+	}
+
+	query = `INSERT INTO user_access (user_id, username, name, language, status) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET status = excluded.status, timestamp = CURRENT_TIMESTAMP`
+	if _, err = tx.Exec(query, info.UserID, info.Username, info.Name, info.Language, AccessApproved); err != nil {
+		return fmt.Errorf("failed to approve access of user_id: %d: %w", info.UserID, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit access invite: %w", err)
+	}
+
+	log.Printf("[info] User_id: %d got access with an invite", info.UserID)
+	return nil
+}
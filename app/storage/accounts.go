@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Account kinds
+const (
+	AccountCash    = "cash"
+	AccountCard    = "card"
+	AccountSavings = "savings"
+)
+
+// AccountKinds lists the kinds of accounts, the default one first.
+var AccountKinds = []string{AccountCash, AccountCard, AccountSavings}
+
+// Account represents storage of ledger accounts and transfers between them.
+type Account struct {
+	db *sqlx.DB
+}
+
+// AccountInfo is a place money of a ledger is kept in, like a wallet or a card.
+type AccountInfo struct {
+	ID             int64     `db:"id"`
+	LedgerID       int64     `db:"ledger_id"`
+	Name           string    `db:"name"`
+	Kind           string    `db:"kind"` // One of AccountKinds
+	OpeningBalance float64   `db:"opening_balance"`
+	CreatedBy      int64     `db:"created_by"`
+	Timestamp      time.Time `db:"timestamp"`
+
+	Balance float64 `db:"balance"` // Opening balance less spendings, plus transfers in, less transfers out, read only
+}
+
+// AccountTransferInfo is money moved between two accounts of a ledger, it's not a spending.
+type AccountTransferInfo struct {
+	ID            int64     `db:"id"`
+	LedgerID      int64     `db:"ledger_id"`
+	FromAccountID int64     `db:"from_account_id"`
+	ToAccountID   int64     `db:"to_account_id"`
+	Amount        float64   `db:"amount"`
+	UserID        int64     `db:"user_id"`
+	Timestamp     time.Time `db:"timestamp"`
+}
+
+const accountColumns = `a.*, a.opening_balance
+	- COALESCE((SELECT SUM(s.amount) FROM spendings s WHERE s.account_id = a.id), 0)
+	- COALESCE((SELECT SUM(t.amount) FROM account_transfers t WHERE t.from_account_id = a.id), 0)
+	+ COALESCE((SELECT SUM(t.amount) FROM account_transfers t WHERE t.to_account_id = a.id), 0) AS balance`
+
+// NewAccount creates a new Account storage
+func NewAccount(db *sqlx.DB) (*Account, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS accounts (
+		id INTEGER PRIMARY KEY,
+		ledger_id INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		opening_balance REAL NOT NULL DEFAULT 0,
+		created_by INTEGER NOT NULL,
+		timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (ledger_id, name),
+		FOREIGN KEY (ledger_id) REFERENCES ledgers(id)
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create accounts table: %w", err)
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS account_transfers (
+		id INTEGER PRIMARY KEY,
+		ledger_id INTEGER NOT NULL,
+		from_account_id INTEGER NOT NULL,
+		to_account_id INTEGER NOT NULL,
+		amount REAL NOT NULL,
+		user_id INTEGER NOT NULL,
+		timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (ledger_id) REFERENCES ledgers(id),
+		FOREIGN KEY (from_account_id) REFERENCES accounts(id),
+		FOREIGN KEY (to_account_id) REFERENCES accounts(id)
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create account_transfers table: %w", err)
+	}
+
+	return &Account{db: db}, nil
+}
+
+// AddAccount adds an account to a ledger, an account with the same name gets the new kind and opening balance.
+func (a *Account) AddAccount(info AccountInfo) error {
+	query := `INSERT INTO accounts (ledger_id, name, kind, opening_balance, created_by) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(ledger_id, name) DO UPDATE SET kind = excluded.kind, opening_balance = excluded.opening_balance`
+	if _, err := a.db.Exec(query, info.LedgerID, info.Name, info.Kind, info.OpeningBalance, info.CreatedBy); err != nil {
+		return fmt.Errorf("failed to insert account: %w", err)
+	}
+
+	log.Printf("[info] Account %q (%s) with %f added to ledger_id: %d by user_id: %d", info.Name, info.Kind, info.OpeningBalance, info.LedgerID, info.CreatedBy)
+	return nil
+}
+
+// ListAccounts returns the accounts of a ledger with their current balances, the oldest first.
+func (a *Account) ListAccounts(ledgerID int64) ([]AccountInfo, error) {
+	var accounts []AccountInfo
+	query := `SELECT ` + accountColumns + ` FROM accounts a WHERE a.ledger_id = ? ORDER BY a.id ASC`
+	if err := a.db.Select(&accounts, query, ledgerID); err != nil {
+		return nil, fmt.Errorf("failed to list accounts for ledger_id: %d: %w", ledgerID, err)
+	}
+
+	return accounts, nil
+}
+
+// AddTransfer records money moved between two accounts.
+func (a *Account) AddTransfer(info AccountTransferInfo) error {
+	query := `INSERT INTO account_transfers (ledger_id, from_account_id, to_account_id, amount, user_id) VALUES (?, ?, ?, ?, ?)`
+	if _, err := a.db.Exec(query, info.LedgerID, info.FromAccountID, info.ToAccountID, info.Amount, info.UserID); err != nil {
+		return fmt.Errorf("failed to insert account transfer: %w", err)
+	}
+
+	log.Printf("[info] Transfer of %f from account %d to account %d in ledger_id: %d by user_id: %d", info.Amount, info.FromAccountID, info.ToAccountID, info.LedgerID, info.UserID)
+	return nil
+}
+
+// LastUsedAccount returns the account of the latest spending the user recorded to the ledger from an account,
+// 0 if there's none.
+func (a *Account) LastUsedAccount(ledgerID, userID int64) (int64, error) {
+	var accountID int64
+	query := `SELECT account_id FROM spendings WHERE ledger_id = ? AND user_id = ? AND account_id != 0 ORDER BY id DESC LIMIT 1`
+	if err := a.db.Get(&accountID, query, ledgerID, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to get last used account of user_id: %d: %w", userID, err)
+	}
+
+	return accountID, nil
+}
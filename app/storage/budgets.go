@@ -0,0 +1,199 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// EnvelopeMonthLayout is the layout of envelope months.
+const EnvelopeMonthLayout = "2006-01"
+
+// Budget represents storage of monthly spending limits and the envelopes allocated from them every month.
+type Budget struct {
+	db *sqlx.DB
+}
+
+// BudgetInfo is a monthly spending limit of a ledger, or of one of its categories.
+// It's the template the envelope of every month is allocated from.
+type BudgetInfo struct {
+	LedgerID   int64     `db:"ledger_id"`
+	CategoryID int64     `db:"category_id"` // 0 for the whole ledger
+	Amount     float64   `db:"amount"`
+	Rollover   bool      `db:"rollover"` // Whether the unspent amount is carried over to the next month
+	Timestamp  time.Time `db:"timestamp"`
+}
+
+// EnvelopeInfo is the money allocated to a budget for a month.
+// Spendings of the month may use up to Allocated + Carried + Moved.
+type EnvelopeInfo struct {
+	LedgerID   int64   `db:"ledger_id"`
+	CategoryID int64   `db:"category_id"` // 0 for the whole ledger
+	Month      string  `db:"month"`       // In EnvelopeMonthLayout
+	Allocated  float64 `db:"allocated"`   // Budget amount at the start of the month
+	Carried    float64 `db:"carried"`     // Unspent amount rolled over from the previous month
+	Moved      float64 `db:"moved"`       // Pulled from other envelopes to cover overspending, negative for the source
+}
+
+// Available returns the amount the envelope allows to spend.
+func (e EnvelopeInfo) Available() float64 {
+	return e.Allocated + e.Carried + e.Moved
+}
+
+// NewBudget creates a new Budget storage
+func NewBudget(db *sqlx.DB) (*Budget, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS budgets (
+		ledger_id INTEGER NOT NULL,
+		category_id INTEGER NOT NULL DEFAULT 0,
+		amount REAL NOT NULL,
+		timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (ledger_id, category_id),
+		FOREIGN KEY (ledger_id) REFERENCES ledgers(id)
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create budgets table: %w", err)
+	}
+
+	if err = addColumnIfMissing(db, "budgets", "rollover", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS budget_envelopes (
+		ledger_id INTEGER NOT NULL,
+		category_id INTEGER NOT NULL DEFAULT 0,
+		month TEXT NOT NULL,
+		allocated REAL NOT NULL,
+		carried REAL NOT NULL DEFAULT 0,
+		moved REAL NOT NULL DEFAULT 0,
+		PRIMARY KEY (ledger_id, category_id, month),
+		FOREIGN KEY (ledger_id) REFERENCES ledgers(id)
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create budget_envelopes table: %w", err)
+	}
+
+	return &Budget{db: db}, nil
+}
+
+// SetBudget adds or updates a budget, a non-positive amount removes it.
+func (b *Budget) SetBudget(info BudgetInfo) error {
+	if info.Amount <= 0 {
+		if _, err := b.db.Exec("DELETE FROM budgets WHERE ledger_id = ? AND category_id = ?", info.LedgerID, info.CategoryID); err != nil {
+			return fmt.Errorf("failed to delete budget: %w", err)
+		}
+		log.Printf("[info] Budget removed for ledger_id: %d, category_id: %d", info.LedgerID, info.CategoryID)
+		return nil
+	}
+
+	query := `INSERT INTO budgets (ledger_id, category_id, amount) VALUES (?, ?, ?)
+		ON CONFLICT(ledger_id, category_id) DO UPDATE SET amount = excluded.amount, timestamp = CURRENT_TIMESTAMP`
+	if _, err := b.db.Exec(query, info.LedgerID, info.CategoryID, info.Amount); err != nil {
+		return fmt.Errorf("failed to insert or update budget: %w", err)
+	}
+
+	log.Printf("[info] Budget of %f set for ledger_id: %d, category_id: %d", info.Amount, info.LedgerID, info.CategoryID)
+	return nil
+}
+
+// ListBudgets returns all budgets of a ledger, the whole ledger budget first.
+func (b *Budget) ListBudgets(ledgerID int64) ([]BudgetInfo, error) {
+	var budgets []BudgetInfo
+	if err := b.db.Select(&budgets, "SELECT * FROM budgets WHERE ledger_id = ? ORDER BY category_id ASC", ledgerID); err != nil {
+		return nil, fmt.Errorf("failed to list budgets for ledger_id: %d: %w", ledgerID, err)
+	}
+
+	return budgets, nil
+}
+
+// ListBudgetLedgers returns the IDs of the ledgers having budgets.
+func (b *Budget) ListBudgetLedgers() ([]int64, error) {
+	var ledgerIDs []int64
+	if err := b.db.Select(&ledgerIDs, "SELECT DISTINCT ledger_id FROM budgets ORDER BY ledger_id ASC"); err != nil {
+		return nil, fmt.Errorf("failed to list ledgers with budgets: %w", err)
+	}
+
+	return ledgerIDs, nil
+}
+
+// SetRollover sets whether the unspent amount of a budget is carried over to the next month.
+// It returns sql.ErrNoRows when there's no such budget.
+func (b *Budget) SetRollover(ledgerID, categoryID int64, rollover bool) error {
+	res, err := b.db.Exec("UPDATE budgets SET rollover = ? WHERE ledger_id = ? AND category_id = ?", rollover, ledgerID, categoryID)
+	if err != nil {
+		return fmt.Errorf("failed to update budget rollover: %w", err)
+	}
+	if count, err := res.RowsAffected(); err != nil || count == 0 {
+		return fmt.Errorf("no budget for ledger_id: %d, category_id: %d: %w", ledgerID, categoryID, sql.ErrNoRows)
+	}
+
+	log.Printf("[info] Budget rollover set to %t for ledger_id: %d, category_id: %d", rollover, ledgerID, categoryID)
+	return nil
+}
+
+// OpenEnvelope adds the envelope of a month unless it's already there.
+func (b *Budget) OpenEnvelope(info EnvelopeInfo) error {
+	query := `INSERT OR IGNORE INTO budget_envelopes (ledger_id, category_id, month, allocated, carried) VALUES (?, ?, ?, ?, ?)`
+	res, err := b.db.Exec(query, info.LedgerID, info.CategoryID, info.Month, info.Allocated, info.Carried)
+	if err != nil {
+		return fmt.Errorf("failed to insert budget envelope: %w", err)
+	}
+
+	if count, err := res.RowsAffected(); err == nil && count > 0 {
+		log.Printf("[info] Envelope of %f (+%f carried) opened for ledger_id: %d, category_id: %d, month: %s", info.Allocated, info.Carried, info.LedgerID, info.CategoryID, info.Month)
+	}
+	return nil
+}
+
+// SetAllocation changes the amount allocated to an envelope, a non-positive amount removes it.
+func (b *Budget) SetAllocation(ledgerID, categoryID int64, month string, amount float64) error {
+	if amount <= 0 {
+		query := "DELETE FROM budget_envelopes WHERE ledger_id = ? AND category_id = ? AND month = ?"
+		if _, err := b.db.Exec(query, ledgerID, categoryID, month); err != nil {
+			return fmt.Errorf("failed to delete budget envelope: %w", err)
+		}
+		return nil
+	}
+
+	query := "UPDATE budget_envelopes SET allocated = ? WHERE ledger_id = ? AND category_id = ? AND month = ?"
+	if _, err := b.db.Exec(query, amount, ledgerID, categoryID, month); err != nil {
+		return fmt.Errorf("failed to update budget envelope: %w", err)
+	}
+	return nil
+}
+
+// ListEnvelopes returns the envelopes of a ledger for a month, the whole ledger envelope first.
+func (b *Budget) ListEnvelopes(ledgerID int64, month string) ([]EnvelopeInfo, error) {
+	var envelopes []EnvelopeInfo
+	query := "SELECT * FROM budget_envelopes WHERE ledger_id = ? AND month = ? ORDER BY category_id ASC"
+	if err := b.db.Select(&envelopes, query, ledgerID, month); err != nil {
+		return nil, fmt.Errorf("failed to list envelopes for ledger_id: %d: %w", ledgerID, err)
+	}
+
+	return envelopes, nil
+}
+
+// MoveAllocation moves an amount between two envelopes of the same month.
+func (b *Budget) MoveAllocation(ledgerID int64, month string, fromCategoryID, toCategoryID int64, amount float64) error {
+	tx, err := b.db.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to start envelope transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // rollback after commit is a no-op
+
+	query := "UPDATE budget_envelopes SET moved = moved + ? WHERE ledger_id = ? AND category_id = ? AND month = ?"
+	for categoryID, delta := range map[int64]float64{fromCategoryID: -amount, toCategoryID: amount} {
+		if _, err = tx.Exec(query, delta, ledgerID, categoryID, month); err != nil {
+			return fmt.Errorf("failed to move envelope allocation: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit envelope move: %w", err)
+	}
+
+	log.Printf("[info] Moved %f from envelope %d to envelope %d of ledger_id: %d, month: %s", amount, fromCategoryID, toCategoryID, ledgerID, month)
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 
 	"github.com/jmoiron/sqlx"
+	"time"
 )
 
 // Category represents information about a user's category entry.
@@ -14,51 +15,136 @@ type Category struct {
 
 // CategoryInfo represents the structure of a category.
 type CategoryInfo struct {
-	ID     int64  `db:"id"`
-	UserID int64  `db:"user_id"`
-	Name   string `db:"name"`
-	Emoji  string `db:"emoji"` // Optional, can be used for UI representation
+	ID       int64  `db:"id"`
+	UserID   int64  `db:"user_id"`   // Member who created the category
+	LedgerID int64  `db:"ledger_id"` // Ledger the category belongs to
+	Name     string `db:"name"`
+	Emoji    string `db:"emoji"`     // Optional, can be used for UI representation
+	ParentID int64  `db:"parent_id"` // Category this one is a sub-category of, 0 for top-level categories
 }
 
-// NewCategory creates a new Category storage handler.
-func NewCategory(db *sqlx.DB) (*Category, error) {
-	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS categories (
+// CategoryUsage is a category along with how often it's used, as offered for selection.
+type CategoryUsage struct {
+	CategoryInfo
+	Uses     int  `db:"uses"`     // Spendings recorded in the category and its sub-categories recently
+	Favorite bool `db:"favorite"` // Pinned by the user
+	Children int  `db:"children"` // Number of sub-categories
+}
+
+const categoriesTable = `CREATE TABLE IF NOT EXISTS categories (
 		id INTEGER PRIMARY KEY,
-		user_id INTEGER UNIQUE,
+		user_id INTEGER,
+		ledger_id INTEGER NOT NULL DEFAULT 0,
 		name TEXT,
 		emoji TEXT,
-		UNIQUE(user_id, name) ON CONFLICT REPLACE
-	)`)
-	if err != nil {
+		parent_id INTEGER NOT NULL DEFAULT 0
+	)`
+
+// NewCategory creates a new Category storage handler.
+func NewCategory(db *sqlx.DB) (*Category, error) {
+	if _, err := db.Exec(categoriesTable); err != nil {
 		return nil, fmt.Errorf("failed to create categories table: %w", err)
 	}
 
-	// Add index on user_id for faster lookup of user-specific categories
-	if _, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_categories_user_id ON categories(user_id)`); err != nil {
-		return nil, fmt.Errorf("failed to create index on user_id: %w", err)
+	// older versions allowed a single category per user, drop the constraint
+	legacyColumns := []string{"id", "user_id", "name", "emoji"}
+	if err := rebuildLegacyTable(db, "categories", "user_id INTEGER UNIQUE", categoriesTable, legacyColumns); err != nil {
+		return nil, err
+	}
+
+	if err := addColumnIfMissing(db, "categories", "parent_id", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return nil, err
+	}
+
+	// Add index on ledger_id for faster lookup of ledger-specific categories
+	if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_categories_ledger_id ON categories(ledger_id)`); err != nil {
+		return nil, fmt.Errorf("failed to create index on ledger_id: %w", err)
+	}
+
+	// category names are unique within a ledger, records not yet claimed by a ledger are left alone
+	query := `CREATE UNIQUE INDEX IF NOT EXISTS idx_categories_ledger_name ON categories(ledger_id, name) WHERE ledger_id != 0`
+	if _, err := db.Exec(query); err != nil {
+		return nil, fmt.Errorf("failed to create unique index on ledger_id and name: %w", err)
+	}
+
+	favoritesTable := `CREATE TABLE IF NOT EXISTS category_favorites (
+		user_id INTEGER NOT NULL,
+		category_id INTEGER NOT NULL,
+		PRIMARY KEY (user_id, category_id),
+		FOREIGN KEY (category_id) REFERENCES categories (id)
+	)`
+	if _, err := db.Exec(favoritesTable); err != nil {
+		return nil, fmt.Errorf("failed to create category_favorites table: %w", err)
 	}
 
 	return &Category{db: db}, nil
 }
 
-// AddOrUpdateCategory adds a new category or updates an existing one for a specific user.
+// AddOrUpdateCategory adds a new category or updates an existing one in a ledger.
 func (c *Category) AddOrUpdateCategory(info CategoryInfo) error {
-	query := `INSERT INTO categories (user_id, name, emoji) VALUES (?, ?, ?) ON CONFLICT(user_id, name) DO UPDATE SET emoji = excluded.emoji`
-	if _, err := c.db.Exec(query, info.UserID, info.Name, info.Emoji); err != nil {
+	query := `INSERT INTO categories (user_id, ledger_id, name, emoji, parent_id) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(ledger_id, name) WHERE ledger_id != 0 DO UPDATE SET emoji = excluded.emoji, parent_id = excluded.parent_id`
+	if _, err := c.db.Exec(query, info.UserID, info.LedgerID, info.Name, info.Emoji, info.ParentID); err != nil {
 		return fmt.Errorf("failed to insert or update category: %w", err)
 	}
 
-	log.Printf("[info] Category '%s' updated for user_id: %d", info.Name, info.UserID)
+	log.Printf("[info] Category '%s' updated for ledger_id: %d by user_id: %d", info.Name, info.LedgerID, info.UserID)
 	return nil
 }
 
-// ListCategories returns all categories for a given user ID.
-func (c *Category) ListCategories(userID int64) ([]CategoryInfo, error) {
+// ListCategories returns all categories of a given ledger ID.
+func (c *Category) ListCategories(ledgerID int64) ([]CategoryInfo, error) {
 	var categories []CategoryInfo
-	query := "SELECT * FROM categories WHERE user_id = ? ORDER BY name ASC"
-	if err := c.db.Select(&categories, query, userID); err != nil {
-		return nil, fmt.Errorf("failed to list categories for user_id: %d, %w", userID, err)
+	query := "SELECT * FROM categories WHERE ledger_id = ? ORDER BY name ASC"
+	if err := c.db.Select(&categories, query, ledgerID); err != nil {
+		return nil, fmt.Errorf("failed to list categories for ledger_id: %d, %w", ledgerID, err)
 	}
 
 	return categories, nil
 }
+
+// ListCategoriesByUsage returns sub-categories of the parent, or top-level categories for a zero parentID,
+// in the order they are offered to the user: the user's favorites first, then the ones with the most spendings
+// since the given time, then by name.
+func (c *Category) ListCategoriesByUsage(ledgerID, userID, parentID int64, since time.Time) ([]CategoryUsage, error) {
+	var categories []CategoryUsage
+	query := `SELECT c.*,
+			(SELECT COUNT(*) FROM spendings s JOIN categories sc ON sc.id = s.category_id
+				WHERE (sc.id = c.id OR sc.parent_id = c.id) AND s.timestamp >= ?) AS uses,
+			EXISTS (SELECT 1 FROM category_favorites f WHERE f.category_id = c.id AND f.user_id = ?) AS favorite,
+			(SELECT COUNT(*) FROM categories cc WHERE cc.parent_id = c.id) AS children
+		FROM categories c WHERE c.ledger_id = ? AND c.parent_id = ?
+		ORDER BY favorite DESC, uses DESC, c.name ASC`
+	if err := c.db.Select(&categories, query, since, userID, ledgerID, parentID); err != nil {
+		return nil, fmt.Errorf("failed to list categories by usage for ledger_id: %d, %w", ledgerID, err)
+	}
+
+	return categories, nil
+}
+
+// ToggleFavorite pins a category to the top of the user's category keyboard or unpins it,
+// reporting whether the category is a favorite now.
+func (c *Category) ToggleFavorite(userID, categoryID int64) (bool, error) {
+	res, err := c.db.Exec(`DELETE FROM category_favorites WHERE user_id = ? AND category_id = ?`, userID, categoryID)
+	if err != nil {
+		return false, fmt.Errorf("failed to unpin category %d for user_id: %d: %w", categoryID, userID, err)
+	}
+	if removed, err := res.RowsAffected(); err == nil && removed > 0 {
+		return false, nil
+	}
+
+	if _, err = c.db.Exec(`INSERT INTO category_favorites (user_id, category_id) VALUES (?, ?)`, userID, categoryID); err != nil {
+		return false, fmt.Errorf("failed to pin category %d for user_id: %d: %w", categoryID, userID, err)
+	}
+	return true, nil
+}
+
+// GetCategory returns a category by its ID.
+func (c *Category) GetCategory(categoryID int64) (*CategoryInfo, error) {
+	var category CategoryInfo
+	if err := c.db.Get(&category, "SELECT * FROM categories WHERE id = ?", categoryID); err != nil {
+		return nil, fmt.Errorf("failed to get category %d: %w", categoryID, err)
+	}
+
+	return &category, nil
+}
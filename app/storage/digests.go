@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Digest kinds
+const (
+	DigestWeekly  = "weekly"
+	DigestMonthly = "monthly"
+)
+
+// Digest represents storage of digest deliveries, so restarts don't send a digest twice.
+type Digest struct {
+	db *sqlx.DB
+}
+
+// NewDigest creates a new Digest storage
+func NewDigest(db *sqlx.DB) (*Digest, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS digests (
+		user_id INTEGER NOT NULL,
+		kind TEXT NOT NULL,
+		sent_at DATETIME NOT NULL,
+		PRIMARY KEY (user_id, kind)
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create digests table: %w", err)
+	}
+
+	return &Digest{db: db}, nil
+}
+
+// LastSent returns when the user got a digest of the kind last time, zero time if never.
+func (d *Digest) LastSent(userID int64, kind string) (time.Time, error) {
+	var sentAt time.Time
+	err := d.db.Get(&sentAt, "SELECT sent_at FROM digests WHERE user_id = ? AND kind = ?", userID, kind)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get last %s digest of user_id: %d: %w", kind, userID, err)
+	}
+
+	return sentAt, nil
+}
+
+// MarkSent records a digest delivery.
+func (d *Digest) MarkSent(userID int64, kind string, sentAt time.Time) error {
+	query := `INSERT INTO digests (user_id, kind, sent_at) VALUES (?, ?, ?)
+		ON CONFLICT(user_id, kind) DO UPDATE SET sent_at = excluded.sent_at`
+	if _, err := d.db.Exec(query, userID, kind, sentAt); err != nil {
+		return fmt.Errorf("failed to mark %s digest of user_id: %d as sent: %w", kind, userID, err)
+	}
+
+	return nil
+}
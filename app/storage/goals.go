@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Goal represents storage of savings goals and contributions to them.
+type Goal struct {
+	db *sqlx.DB
+}
+
+// GoalInfo is a savings goal of a ledger.
+type GoalInfo struct {
+	ID        int64     `db:"id"`
+	LedgerID  int64     `db:"ledger_id"`
+	Name      string    `db:"name"`
+	Target    float64   `db:"target"`
+	Deadline  time.Time `db:"deadline"` // Zero if the goal has no deadline
+	CreatedBy int64     `db:"created_by"`
+	Closed    bool      `db:"closed"`
+	Timestamp time.Time `db:"timestamp"`
+
+	Saved float64 `db:"saved"` // Sum of the contributions, read only
+}
+
+// GoalContributionInfo is an amount put aside for a goal.
+type GoalContributionInfo struct {
+	ID        int64     `db:"id"`
+	GoalID    int64     `db:"goal_id"`
+	UserID    int64     `db:"user_id"`
+	Amount    float64   `db:"amount"`
+	Timestamp time.Time `db:"timestamp"`
+}
+
+const goalColumns = `g.*, COALESCE((SELECT SUM(c.amount) FROM goal_contributions c WHERE c.goal_id = g.id), 0) AS saved`
+
+// NewGoal creates a new Goal storage
+func NewGoal(db *sqlx.DB) (*Goal, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS goals (
+		id INTEGER PRIMARY KEY,
+		ledger_id INTEGER NOT NULL,
+		name TEXT NOT NULL,
+		target REAL NOT NULL,
+		deadline DATETIME NOT NULL,
+		created_by INTEGER NOT NULL,
+		closed INTEGER NOT NULL DEFAULT 0,
+		timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (ledger_id) REFERENCES ledgers(id)
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create goals table: %w", err)
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS goal_contributions (
+		id INTEGER PRIMARY KEY,
+		goal_id INTEGER NOT NULL,
+		user_id INTEGER NOT NULL,
+		amount REAL NOT NULL,
+		timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (goal_id) REFERENCES goals(id)
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create goal_contributions table: %w", err)
+	}
+
+	return &Goal{db: db}, nil
+}
+
+// AddGoal adds a goal and returns its ID.
+func (g *Goal) AddGoal(info GoalInfo) (int64, error) {
+	query := `INSERT INTO goals (ledger_id, name, target, deadline, created_by) VALUES (?, ?, ?, ?, ?)`
+	res, err := g.db.Exec(query, info.LedgerID, info.Name, info.Target, info.Deadline, info.CreatedBy)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert goal: %w", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get goal id: %w", err)
+	}
+
+	log.Printf("[info] Goal %q of %f added to ledger_id: %d by user_id: %d", info.Name, info.Target, info.LedgerID, info.CreatedBy)
+	return id, nil
+}
+
+// UpdateGoal changes the name, the target and the deadline of a goal.
+func (g *Goal) UpdateGoal(info GoalInfo) error {
+	query := `UPDATE goals SET name = ?, target = ?, deadline = ? WHERE id = ? AND ledger_id = ?`
+	if _, err := g.db.Exec(query, info.Name, info.Target, info.Deadline, info.ID, info.LedgerID); err != nil {
+		return fmt.Errorf("failed to update goal %d: %w", info.ID, err)
+	}
+	return nil
+}
+
+// CloseGoal closes a goal of a ledger, closed goals are no longer listed.
+func (g *Goal) CloseGoal(ledgerID, goalID int64) error {
+	if _, err := g.db.Exec(`UPDATE goals SET closed = 1 WHERE id = ? AND ledger_id = ?`, goalID, ledgerID); err != nil {
+		return fmt.Errorf("failed to close goal %d: %w", goalID, err)
+	}
+
+	log.Printf("[info] Goal %d of ledger_id: %d closed", goalID, ledgerID)
+	return nil
+}
+
+// GetGoal returns a goal by its ID.
+func (g *Goal) GetGoal(goalID int64) (*GoalInfo, error) {
+	var goal GoalInfo
+	if err := g.db.Get(&goal, `SELECT `+goalColumns+` FROM goals g WHERE g.id = ?`, goalID); err != nil {
+		return nil, fmt.Errorf("failed to get goal %d: %w", goalID, err)
+	}
+
+	return &goal, nil
+}
+
+// ListGoals returns the open goals of a ledger, the oldest first.
+func (g *Goal) ListGoals(ledgerID int64) ([]GoalInfo, error) {
+	var goals []GoalInfo
+	query := `SELECT ` + goalColumns + ` FROM goals g WHERE g.ledger_id = ? AND g.closed = 0 ORDER BY g.id ASC`
+	if err := g.db.Select(&goals, query, ledgerID); err != nil {
+		return nil, fmt.Errorf("failed to list goals for ledger_id: %d: %w", ledgerID, err)
+	}
+
+	return goals, nil
+}
+
+// AddContribution puts an amount aside for a goal.
+func (g *Goal) AddContribution(info GoalContributionInfo) error {
+	query := `INSERT INTO goal_contributions (goal_id, user_id, amount) VALUES (?, ?, ?)`
+	if _, err := g.db.Exec(query, info.GoalID, info.UserID, info.Amount); err != nil {
+		return fmt.Errorf("failed to insert goal contribution: %w", err)
+	}
+
+	log.Printf("[info] Contribution of %f to goal %d by user_id: %d", info.Amount, info.GoalID, info.UserID)
+	return nil
+}
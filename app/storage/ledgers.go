@@ -0,0 +1,262 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Member roles within a ledger
+const (
+	RoleOwner  = "owner"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+// ErrInviteNotFound is returned when an invite code is unknown, already used or expired.
+var ErrInviteNotFound = errors.New("invite not found")
+
+// Ledger represents storage of ledgers shared between members.
+type Ledger struct {
+	db *sqlx.DB
+}
+
+// LedgerInfo represents the structure of a ledger.
+type LedgerInfo struct {
+	ID        int64     `db:"id"`
+	Name      string    `db:"name"`
+	OwnerID   int64     `db:"owner_id"`
+	Personal  bool      `db:"personal"` // Created automatically for every user
+	ChatID    int64     `db:"chat_id"`  // Group chat the ledger belongs to, 0 for ledgers used in private chats
+	Timestamp time.Time `db:"timestamp"`
+}
+
+// LedgerMemberInfo represents a user's membership in a ledger.
+type LedgerMemberInfo struct {
+	LedgerID  int64     `db:"ledger_id"`
+	UserID    int64     `db:"user_id"`
+	Role      string    `db:"role"`
+	Name      string    `db:"name"` // Display name used for attribution
+	Timestamp time.Time `db:"timestamp"`
+}
+
+// LedgerInviteInfo represents a one-time invitation to join a ledger.
+type LedgerInviteInfo struct {
+	Code      string    `db:"code"`
+	LedgerID  int64     `db:"ledger_id"`
+	Role      string    `db:"role"`
+	CreatedBy int64     `db:"created_by"`
+	UsedBy    int64     `db:"used_by"`
+	ExpiresAt time.Time `db:"expires_at"`
+	Timestamp time.Time `db:"timestamp"`
+}
+
+// NewLedger creates a new Ledger storage
+func NewLedger(db *sqlx.DB) (*Ledger, error) {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS ledgers (
+			id INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			owner_id INTEGER NOT NULL,
+			personal INTEGER NOT NULL DEFAULT 0,
+			chat_id INTEGER NOT NULL DEFAULT 0,
+			timestamp DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS ledger_members (
+			ledger_id INTEGER NOT NULL,
+			user_id INTEGER NOT NULL,
+			role TEXT NOT NULL,
+			name TEXT NOT NULL DEFAULT '',
+			timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (ledger_id, user_id),
+			FOREIGN KEY (ledger_id) REFERENCES ledgers(id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS ledger_invites (
+			code TEXT PRIMARY KEY,
+			ledger_id INTEGER NOT NULL,
+			role TEXT NOT NULL,
+			created_by INTEGER NOT NULL,
+			used_by INTEGER NOT NULL DEFAULT 0,
+			expires_at DATETIME NOT NULL,
+			timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (ledger_id) REFERENCES ledgers(id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_ledger_members_user_id ON ledger_members(user_id)`,
+	}
+	for _, query := range queries {
+		if _, err := db.Exec(query); err != nil {
+			return nil, fmt.Errorf("failed to create ledger tables: %w", err)
+		}
+	}
+
+	if err := addColumnIfMissing(db, "ledgers", "chat_id", "INTEGER NOT NULL DEFAULT 0"); err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_ledgers_chat_id ON ledgers(chat_id)`); err != nil {
+		return nil, fmt.Errorf("failed to create index on chat_id: %w", err)
+	}
+
+	return &Ledger{db: db}, nil
+}
+
+// CreateLedger creates a ledger with its owner as the first member.
+// Personal ledgers also take over spendings and categories recorded before ledgers existed.
+func (l *Ledger) CreateLedger(info LedgerInfo, ownerName string) (*LedgerInfo, error) {
+	tx, err := l.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start ledger transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // rollback after commit is a no-op
+
+	res, err := tx.Exec(`INSERT INTO ledgers (name, owner_id, personal, chat_id) VALUES (?, ?, ?, ?)`, info.Name, info.OwnerID, info.Personal, info.ChatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert ledger: %w", err)
+	}
+	if info.ID, err = res.LastInsertId(); err != nil {
+		return nil, fmt.Errorf("failed to get ledger id: %w", err)
+	}
+
+	query := `INSERT INTO ledger_members (ledger_id, user_id, role, name) VALUES (?, ?, ?, ?)`
+	if _, err = tx.Exec(query, info.ID, info.OwnerID, RoleOwner, ownerName); err != nil {
+		return nil, fmt.Errorf("failed to insert ledger owner: %w", err)
+	}
+
+	if info.Personal {
+		for _, table := range []string{"spendings", "categories"} {
+			query = fmt.Sprintf(`UPDATE %s SET ledger_id = ? WHERE user_id = ? AND ledger_id = 0`, table)
+			if _, err = tx.Exec(query, info.ID, info.OwnerID); err != nil {
+				return nil, fmt.Errorf("failed to move %s to personal ledger: %w", table, err)
+			}
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit ledger: %w", err)
+	}
+
+	log.Printf("[info] Ledger %d '%s' created by user_id: %d", info.ID, info.Name, info.OwnerID)
+	return &info, nil
+}
+
+// GetLedger returns a ledger by its ID.
+func (l *Ledger) GetLedger(ledgerID int64) (*LedgerInfo, error) {
+	var ledger LedgerInfo
+	if err := l.db.Get(&ledger, "SELECT * FROM ledgers WHERE id = ?", ledgerID); err != nil {
+		return nil, fmt.Errorf("failed to get ledger %d: %w", ledgerID, err)
+	}
+
+	return &ledger, nil
+}
+
+// GetChatLedger returns the ledger of a group chat.
+func (l *Ledger) GetChatLedger(chatID int64) (*LedgerInfo, error) {
+	var ledger LedgerInfo
+	if err := l.db.Get(&ledger, "SELECT * FROM ledgers WHERE chat_id = ? ORDER BY id LIMIT 1", chatID); err != nil {
+		return nil, fmt.Errorf("failed to get ledger of chat %d: %w", chatID, err)
+	}
+
+	return &ledger, nil
+}
+
+// ListUserLedgers returns all ledgers the user is a member of.
+func (l *Ledger) ListUserLedgers(userID int64) ([]LedgerInfo, error) {
+	var ledgers []LedgerInfo
+	query := `SELECT l.* FROM ledgers l JOIN ledger_members m ON m.ledger_id = l.id
+		WHERE m.user_id = ? ORDER BY l.personal DESC, l.name ASC`
+	if err := l.db.Select(&ledgers, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to list ledgers for user_id: %d: %w", userID, err)
+	}
+
+	return ledgers, nil
+}
+
+// GetMember returns the membership of a user in a ledger.
+func (l *Ledger) GetMember(ledgerID, userID int64) (*LedgerMemberInfo, error) {
+	var member LedgerMemberInfo
+	query := "SELECT * FROM ledger_members WHERE ledger_id = ? AND user_id = ?"
+	if err := l.db.Get(&member, query, ledgerID, userID); err != nil {
+		return nil, fmt.Errorf("failed to get member %d of ledger %d: %w", userID, ledgerID, err)
+	}
+
+	return &member, nil
+}
+
+// ListMembers returns all members of a ledger.
+func (l *Ledger) ListMembers(ledgerID int64) ([]LedgerMemberInfo, error) {
+	var members []LedgerMemberInfo
+	query := "SELECT * FROM ledger_members WHERE ledger_id = ? ORDER BY timestamp ASC"
+	if err := l.db.Select(&members, query, ledgerID); err != nil {
+		return nil, fmt.Errorf("failed to list members of ledger %d: %w", ledgerID, err)
+	}
+
+	return members, nil
+}
+
+// AddMember adds a user to a ledger, existing members keep their role.
+func (l *Ledger) AddMember(member LedgerMemberInfo) error {
+	query := `INSERT INTO ledger_members (ledger_id, user_id, role, name) VALUES (?, ?, ?, ?) ON CONFLICT(ledger_id, user_id) DO NOTHING`
+	if _, err := l.db.Exec(query, member.LedgerID, member.UserID, member.Role, member.Name); err != nil {
+		return fmt.Errorf("failed to add member %d to ledger %d: %w", member.UserID, member.LedgerID, err)
+	}
+
+	return nil
+}
+
+// UpdateMemberName updates the display name of a user in all their ledgers.
+func (l *Ledger) UpdateMemberName(userID int64, name string) error {
+	if _, err := l.db.Exec(`UPDATE ledger_members SET name = ? WHERE user_id = ?`, name, userID); err != nil {
+		return fmt.Errorf("failed to update member name for user_id: %d: %w", userID, err)
+	}
+
+	return nil
+}
+
+// CreateInvite stores a new invitation code.
+func (l *Ledger) CreateInvite(invite LedgerInviteInfo) error {
+	query := `INSERT INTO ledger_invites (code, ledger_id, role, created_by, expires_at) VALUES (?, ?, ?, ?, ?)`
+	if _, err := l.db.Exec(query, invite.Code, invite.LedgerID, invite.Role, invite.CreatedBy, invite.ExpiresAt); err != nil {
+		return fmt.Errorf("failed to insert ledger invite: %w", err)
+	}
+
+	log.Printf("[info] Invite to ledger %d as %s created by user_id: %d", invite.LedgerID, invite.Role, invite.CreatedBy)
+	return nil
+}
+
+// UseInvite redeems an invitation code, adding the user to the ledger with the invited role.
+// Users who are already members keep their current role. The code can't be used again.
+func (l *Ledger) UseInvite(code string, userID int64, userName string, now time.Time) (*LedgerInviteInfo, error) {
+	tx, err := l.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start invite transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // rollback after commit is a no-op
+
+	var invite LedgerInviteInfo
+	query := "SELECT * FROM ledger_invites WHERE code = ? AND used_by = 0 AND expires_at > ?"
+	if err = tx.Get(&invite, query, code, now); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInviteNotFound
+		}
+		return nil, fmt.Errorf("failed to get ledger invite: %w", err)
+	}
+
+	if _, err = tx.Exec(`UPDATE ledger_invites SET used_by = ? WHERE code = ?`, userID, code); err != nil {
+		return nil, fmt.Errorf("failed to mark invite as used: %w", err)
+	}
+
+	query = `INSERT INTO ledger_members (ledger_id, user_id, role, name) VALUES (?, ?, ?, ?) ON CONFLICT(ledger_id, user_id) DO NOTHING`
+	if _, err = tx.Exec(query, invite.LedgerID, userID, invite.Role, userName); err != nil {
+		return nil, fmt.Errorf("failed to add ledger member: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit invite: %w", err)
+	}
+
+	log.Printf("[info] User_id: %d joined ledger %d as %s", userID, invite.LedgerID, invite.Role)
+	return &invite, nil
+}
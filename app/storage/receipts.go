@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Receipt kinds, the Telegram media type a receipt was sent as.
+const (
+	ReceiptPhoto    = "photo"
+	ReceiptDocument = "document"
+)
+
+// Receipt represents storage of receipts attached to spendings.
+type Receipt struct {
+	db *sqlx.DB
+}
+
+// ReceiptInfo is a photo or a document attached to a spending.
+type ReceiptInfo struct {
+	ID         int64     `db:"id"`
+	SpendingID int64     `db:"spending_id"`
+	Kind       string    `db:"kind"`       // ReceiptPhoto or ReceiptDocument
+	FileID     string    `db:"file_id"`    // Telegram file_id, enough to send the file again
+	LocalPath  string    `db:"local_path"` // Empty unless a local copy is kept
+	Timestamp  time.Time `db:"timestamp"`
+}
+
+// NewReceipt creates a new Receipt storage
+func NewReceipt(db *sqlx.DB) (*Receipt, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS receipts (
+		id INTEGER PRIMARY KEY,
+		spending_id INTEGER NOT NULL,
+		kind TEXT NOT NULL,
+		file_id TEXT NOT NULL,
+		local_path TEXT NOT NULL DEFAULT '',
+		timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (spending_id) REFERENCES spendings(id)
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create receipts table: %w", err)
+	}
+
+	if _, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_receipts_spending_id ON receipts(spending_id)`); err != nil {
+		return nil, fmt.Errorf("failed to create index on spending_id: %w", err)
+	}
+
+	// bot messages confirming saved spendings, replying to one with a photo attaches it
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS spending_messages (
+		chat_id INTEGER NOT NULL,
+		message_id INTEGER NOT NULL,
+		spending_id INTEGER NOT NULL,
+		PRIMARY KEY (chat_id, message_id),
+		FOREIGN KEY (spending_id) REFERENCES spendings(id)
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spending_messages table: %w", err)
+	}
+
+	return &Receipt{db: db}, nil
+}
+
+// AddReceipt attaches a receipt to a spending and returns its ID.
+func (r *Receipt) AddReceipt(info ReceiptInfo) (int64, error) {
+	query := `INSERT INTO receipts (spending_id, kind, file_id, local_path) VALUES (?, ?, ?, ?)`
+	res, err := r.db.Exec(query, info.SpendingID, info.Kind, info.FileID, info.LocalPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert receipt: %w", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get receipt id: %w", err)
+	}
+
+	log.Printf("[info] Receipt %s attached to spending_id: %d", info.Kind, info.SpendingID)
+	return id, nil
+}
+
+// SetLocalPath records where the local copy of a receipt is kept.
+func (r *Receipt) SetLocalPath(receiptID int64, path string) error {
+	if _, err := r.db.Exec(`UPDATE receipts SET local_path = ? WHERE id = ?`, path, receiptID); err != nil {
+		return fmt.Errorf("failed to update local path of receipt %d: %w", receiptID, err)
+	}
+	return nil
+}
+
+// GetReceipt returns the latest receipt attached to a spending.
+func (r *Receipt) GetReceipt(spendingID int64) (*ReceiptInfo, error) {
+	var receipt ReceiptInfo
+	query := `SELECT * FROM receipts WHERE spending_id = ? ORDER BY id DESC LIMIT 1`
+	if err := r.db.Get(&receipt, query, spendingID); err != nil {
+		return nil, fmt.Errorf("failed to get receipt of spending_id: %d: %w", spendingID, err)
+	}
+
+	return &receipt, nil
+}
+
+// LinkMessage remembers the bot message confirming a saved spending.
+func (r *Receipt) LinkMessage(chatID int64, messageID int, spendingID int64) error {
+	query := `INSERT OR REPLACE INTO spending_messages (chat_id, message_id, spending_id) VALUES (?, ?, ?)`
+	if _, err := r.db.Exec(query, chatID, messageID, spendingID); err != nil {
+		return fmt.Errorf("failed to link message %d in chat %d to spending_id: %d: %w", messageID, chatID, spendingID, err)
+	}
+	return nil
+}
+
+// GetMessageSpending returns the ID of the spending a bot message confirmed.
+func (r *Receipt) GetMessageSpending(chatID int64, messageID int) (int64, error) {
+	var spendingID int64
+	query := `SELECT spending_id FROM spending_messages WHERE chat_id = ? AND message_id = ?`
+	if err := r.db.Get(&spendingID, query, chatID, messageID); err != nil {
+		return 0, fmt.Errorf("failed to get spending of message %d in chat %d: %w", messageID, chatID, err)
+	}
+	return spendingID, nil
+}
@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Reminder represents storage of daily logging reminder deliveries.
+type Reminder struct {
+	db *sqlx.DB
+}
+
+// ReminderInfo is the delivery state of a user's reminder.
+type ReminderInfo struct {
+	UserID       int64     `db:"user_id"`
+	SentAt       time.Time `db:"sent_at"`       // Zero if never sent
+	SnoozedUntil time.Time `db:"snoozed_until"` // Zero if not snoozed
+}
+
+// NewReminder creates a new Reminder storage
+func NewReminder(db *sqlx.DB) (*Reminder, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS reminders (
+		user_id INTEGER PRIMARY KEY,
+		sent_at DATETIME NOT NULL,
+		snoozed_until DATETIME NOT NULL
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create reminders table: %w", err)
+	}
+
+	return &Reminder{db: db}, nil
+}
+
+// Read returns the reminder state of a user, an empty one if no reminder was sent yet.
+func (r *Reminder) Read(userID int64) (*ReminderInfo, error) {
+	var entry ReminderInfo
+	err := r.db.Get(&entry, "SELECT * FROM reminders WHERE user_id = ?", userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &ReminderInfo{UserID: userID}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reminder of user_id: %d: %w", userID, err)
+	}
+
+	return &entry, nil
+}
+
+// Write adds or updates the reminder state of a user.
+func (r *Reminder) Write(entry ReminderInfo) error {
+	query := `INSERT INTO reminders (user_id, sent_at, snoozed_until) VALUES (?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET sent_at = excluded.sent_at, snoozed_until = excluded.snoozed_until`
+	if _, err := r.db.Exec(query, entry.UserID, entry.SentAt, entry.SnoozedUntil); err != nil {
+		return fmt.Errorf("failed to write reminder of user_id: %d: %w", entry.UserID, err)
+	}
+
+	return nil
+}
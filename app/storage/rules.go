@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Rule represents storage of auto-categorization rules.
+type Rule struct {
+	db *sqlx.DB
+}
+
+// RuleInfo files spendings whose description contains the keyword under the category.
+type RuleInfo struct {
+	ID         int64     `db:"id"`
+	LedgerID   int64     `db:"ledger_id"`
+	Keyword    string    `db:"keyword"` // Lowercase, matched as a substring of the lowercased description
+	CategoryID int64     `db:"category_id"`
+	CreatedBy  int64     `db:"created_by"`
+	Timestamp  time.Time `db:"timestamp"`
+}
+
+// NewRule creates a new Rule storage
+func NewRule(db *sqlx.DB) (*Rule, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS category_rules (
+		id INTEGER PRIMARY KEY,
+		ledger_id INTEGER NOT NULL,
+		keyword TEXT NOT NULL,
+		category_id INTEGER NOT NULL,
+		created_by INTEGER NOT NULL,
+		timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (ledger_id, keyword),
+		FOREIGN KEY (ledger_id) REFERENCES ledgers(id),
+		FOREIGN KEY (category_id) REFERENCES categories(id)
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create category_rules table: %w", err)
+	}
+
+	return &Rule{db: db}, nil
+}
+
+// AddRule adds a rule, or points an existing rule with the same keyword to another category.
+func (r *Rule) AddRule(info RuleInfo) error {
+	query := `INSERT INTO category_rules (ledger_id, keyword, category_id, created_by) VALUES (?, ?, ?, ?)
+		ON CONFLICT(ledger_id, keyword) DO UPDATE SET category_id = excluded.category_id, created_by = excluded.created_by`
+	if _, err := r.db.Exec(query, info.LedgerID, info.Keyword, info.CategoryID, info.CreatedBy); err != nil {
+		return fmt.Errorf("failed to insert or update rule: %w", err)
+	}
+
+	log.Printf("[info] Rule %q -> category_id: %d set for ledger_id: %d by user_id: %d", info.Keyword, info.CategoryID, info.LedgerID, info.CreatedBy)
+	return nil
+}
+
+// ListRules returns all rules of a ledger by keyword.
+func (r *Rule) ListRules(ledgerID int64) ([]RuleInfo, error) {
+	var rules []RuleInfo
+	if err := r.db.Select(&rules, "SELECT * FROM category_rules WHERE ledger_id = ? ORDER BY keyword ASC", ledgerID); err != nil {
+		return nil, fmt.Errorf("failed to list rules for ledger_id: %d: %w", ledgerID, err)
+	}
+
+	return rules, nil
+}
+
+// DeleteRule removes a rule of a ledger.
+func (r *Rule) DeleteRule(ledgerID, ruleID int64) error {
+	if _, err := r.db.Exec("DELETE FROM category_rules WHERE ledger_id = ? AND id = ?", ledgerID, ruleID); err != nil {
+		return fmt.Errorf("failed to delete rule %d: %w", ruleID, err)
+	}
+
+	log.Printf("[info] Rule %d removed from ledger_id: %d", ruleID, ledgerID)
+	return nil
+}
@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Settlement represents storage of settle-up transfers between ledger members.
+type Settlement struct {
+	db *sqlx.DB
+}
+
+// SettlementInfo is a transfer that settles debts between two members of a ledger.
+type SettlementInfo struct {
+	ID         int64     `db:"id"`
+	LedgerID   int64     `db:"ledger_id"`
+	FromUserID int64     `db:"from_user_id"` // Member who paid the debt back
+	ToUserID   int64     `db:"to_user_id"`
+	Amount     float64   `db:"amount"`
+	CreatedBy  int64     `db:"created_by"` // Member who marked the transfer as settled
+	Timestamp  time.Time `db:"timestamp"`
+}
+
+// NewSettlement creates a new Settlement storage
+func NewSettlement(db *sqlx.DB) (*Settlement, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS settlements (
+		id INTEGER PRIMARY KEY,
+		ledger_id INTEGER NOT NULL,
+		from_user_id INTEGER NOT NULL,
+		to_user_id INTEGER NOT NULL,
+		amount REAL NOT NULL,
+		created_by INTEGER NOT NULL,
+		timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (ledger_id) REFERENCES ledgers(id)
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create settlements table: %w", err)
+	}
+
+	if _, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_settlements_ledger_id ON settlements(ledger_id)`); err != nil {
+		return nil, fmt.Errorf("failed to create index on ledger_id: %w", err)
+	}
+
+	return &Settlement{db: db}, nil
+}
+
+// AddSettlement records a settle-up transfer.
+func (s *Settlement) AddSettlement(info SettlementInfo) error {
+	query := `INSERT INTO settlements (ledger_id, from_user_id, to_user_id, amount, created_by) VALUES (?, ?, ?, ?, ?)`
+	if _, err := s.db.Exec(query, info.LedgerID, info.FromUserID, info.ToUserID, info.Amount, info.CreatedBy); err != nil {
+		return fmt.Errorf("failed to insert settlement: %w", err)
+	}
+
+	log.Printf("[info] Settlement of %f from user_id: %d to user_id: %d recorded in ledger_id: %d", info.Amount, info.FromUserID, info.ToUserID, info.LedgerID)
+	return nil
+}
+
+// ListSettlements returns all settle-up transfers of a ledger.
+func (s *Settlement) ListSettlements(ledgerID int64) ([]SettlementInfo, error) {
+	var settlements []SettlementInfo
+	if err := s.db.Select(&settlements, "SELECT * FROM settlements WHERE ledger_id = ? ORDER BY timestamp ASC", ledgerID); err != nil {
+		return nil, fmt.Errorf("failed to list settlements for ledger_id: %d: %w", ledgerID, err)
+	}
+
+	return settlements, nil
+}
@@ -3,8 +3,10 @@ package storage
 import (
 	"fmt"
 	"log"
+	"math"
 
 	"github.com/jmoiron/sqlx"
+	"strings"
 	"time"
 )
 
@@ -16,50 +18,554 @@ type Spending struct {
 // SpendingInfo encapsulates details about a spending entry.
 type SpendingInfo struct {
 	ID          int64     `db:"id"`
-	UserID      int64     `db:"user_id"`
+	UserID      int64     `db:"user_id"`     // Member who recorded the spending
+	PayerID     int64     `db:"payer_id"`    // Member who paid, the recording member if not set
+	LedgerID    int64     `db:"ledger_id"`   // Ledger the spending belongs to
+	AccountID   int64     `db:"account_id"`  // Account the spending was paid from, 0 if not tracked
 	CategoryID  int64     `db:"category_id"` // Assuming category is recorded in the user_states.
 	Amount      float64   `db:"amount"`
 	Description string    `db:"description"` // Optional: More details about the spending
-	Timestamp   time.Time `db:"timestamp"`
+	Timestamp   time.Time `db:"timestamp"`   // When the spending was made, may be backdated
+	CreatedAt   time.Time `db:"created_at"`  // When the spending was recorded, now if not set
+
+	Shares []SpendingShareInfo `db:"-"` // Split between members, empty if the payer covers it alone
+	Tags   []string            `db:"-"` // Hashtags without the #, stored in spending_tags
 }
 
-// NewSpending initializes spending record management.
-func NewSpending(db *sqlx.DB) (*Spending, error) {
-	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS spendings (
+// PeriodTotal is the sum of spendings within a day or a month.
+type PeriodTotal struct {
+	Period string  `db:"period"` // Day in 2006-01-02 or month in 2006-01 format
+	Total  float64 `db:"total"`
+}
+
+// SpendingRecord is a spending along with its category, as listed to users.
+type SpendingRecord struct {
+	SpendingInfo
+	CategoryName  string `db:"category_name"`
+	CategoryEmoji string `db:"category_emoji"`
+	Receipts      int    `db:"receipts"` // Number of attached receipts
+}
+
+// SpendingFilter narrows down spendings of a ledger, zero fields don't filter.
+type SpendingFilter struct {
+	LedgerID    int64
+	UserID      int64             // Member who recorded the spendings
+	Terms       []string          // Each must be found in the description or the category name
+	Tags        []string          // Each must be on the spending
+	CategoryIDs []int64           // Any of them, a filter by a category missing in the ledger holds a 0 ID
+	Amounts     []AmountCondition // All must hold
+	From, To    time.Time         // Within [From, To)
+}
+
+// AmountCondition compares the spending amount with a value, Op is one of =, <, <=, > and >=.
+type AmountCondition struct {
+	Op    string
+	Value float64
+}
+
+// SpendingCursor marks where a page of spendings starts in the latest-first order:
+// right after the spending with the ID, or right before it when going backward.
+// A zero cursor starts from the latest spending.
+type SpendingCursor struct {
+	ID       int64
+	Backward bool
+}
+
+// SpendingSummary is the number and the sum of spendings matching a filter.
+type SpendingSummary struct {
+	Count int     `db:"count"`
+	Total float64 `db:"total"`
+}
+
+// TagTotal is the sum of spendings carrying a tag.
+type TagTotal struct {
+	Tag   string  `db:"tag"`
+	Total float64 `db:"total"`
+	Count int     `db:"count"`
+}
+
+// SpendingShareInfo is the part of a split spending owed by a single member.
+type SpendingShareInfo struct {
+	SpendingID int64   `db:"spending_id"`
+	UserID     int64   `db:"user_id"`
+	Amount     float64 `db:"amount"`
+}
+
+// CategoryDayTotal is the sum of spendings in a single category on a single day.
+type CategoryDayTotal struct {
+	CategoryID int64   `db:"category_id"`
+	Day        string  `db:"day"` // In 2006-01-02 format
+	Total      float64 `db:"total"`
+}
+
+// CategoryTotal is the sum of spendings in a single category.
+type CategoryTotal struct {
+	CategoryID int64   `db:"category_id"`
+	Name       string  `db:"name"`
+	Emoji      string  `db:"emoji"`
+	Total      float64 `db:"total"`
+	Count      int     `db:"count"`
+}
+
+const spendingsTable = `CREATE TABLE IF NOT EXISTS spendings (
 		id INTEGER PRIMARY KEY,
-		user_id INTEGER UNIQUE,
+		user_id INTEGER,
+		payer_id INTEGER NOT NULL DEFAULT 0,
+		ledger_id INTEGER NOT NULL DEFAULT 0,
+		account_id INTEGER NOT NULL DEFAULT 0,
 		category_id INTEGER,
 		amount REAL NOT NULL,
 		description TEXT,
 		timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY (user_id) REFERENCES user_states(user_id),
+		created_at DATETIME,
+		FOREIGN KEY (ledger_id) REFERENCES ledgers(id),
 		FOREIGN KEY (category_id) REFERENCES categories(id)
+	)`
+
+// NewSpending initializes spending record management.
+func NewSpending(db *sqlx.DB) (*Spending, error) {
+	if _, err := db.Exec(spendingsTable); err != nil {
+		return nil, fmt.Errorf("failed to create spendings table: %w", err)
+	}
+
+	// older versions allowed a single spending per user, drop the constraint
+	legacyColumns := []string{"id", "user_id", "category_id", "amount", "description", "timestamp"}
+	if err := rebuildLegacyTable(db, "spendings", "user_id INTEGER UNIQUE", spendingsTable, legacyColumns); err != nil {
+		return nil, err
+	}
+
+	for _, column := range []string{"payer_id", "account_id"} {
+		if err := addColumnIfMissing(db, "spendings", column, "INTEGER NOT NULL DEFAULT 0"); err != nil {
+			return nil, err
+		}
+	}
+
+	// spendings may be backdated, so when they were recorded is kept apart, older records assume their date
+	if err := addColumnIfMissing(db, "spendings", "created_at", "DATETIME"); err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(`UPDATE spendings SET created_at = timestamp WHERE created_at IS NULL`); err != nil {
+		return nil, fmt.Errorf("failed to fill in created_at of spendings: %w", err)
+	}
+
+	// Add index on ledger_id and timestamp for faster period lookups
+	if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_spendings_ledger_id_timestamp ON spendings(ledger_id, timestamp)`); err != nil {
+		return nil, fmt.Errorf("failed to create index on ledger_id and timestamp: %w", err)
+	}
+
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS spending_shares (
+		spending_id INTEGER NOT NULL,
+		user_id INTEGER NOT NULL,
+		amount REAL NOT NULL,
+		PRIMARY KEY (spending_id, user_id),
+		FOREIGN KEY (spending_id) REFERENCES spendings(id)
 	)`)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create spendings table: %w", err)
+		return nil, fmt.Errorf("failed to create spending_shares table: %w", err)
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS spending_tags (
+		spending_id INTEGER NOT NULL,
+		tag TEXT NOT NULL,
+		PRIMARY KEY (spending_id, tag),
+		FOREIGN KEY (spending_id) REFERENCES spendings(id)
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spending_tags table: %w", err)
+	}
+
+	if _, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_spending_tags_tag ON spending_tags(tag)`); err != nil {
+		return nil, fmt.Errorf("failed to create index on tag: %w", err)
 	}
 
 	return &Spending{db: db}, nil
 }
 
-// AddSpending adds a new spending record.
-func (s *Spending) AddSpending(info SpendingInfo) error {
-	query := `INSERT INTO spendings (user_id, category_id, amount, description, timestamp) VALUES (?, ?, ?, ?, ?)`
-	if _, err := s.db.Exec(query, info.UserID, info.CategoryID, info.Amount, info.Description, info.Timestamp); err != nil {
-		return fmt.Errorf("failed to insert spending record: %w", err)
+// AddSpending adds a new spending record together with its shares and returns its ID.
+func (s *Spending) AddSpending(info SpendingInfo) (int64, error) {
+	if info.PayerID == 0 {
+		info.PayerID = info.UserID
+	}
+	if info.CreatedAt.IsZero() {
+		info.CreatedAt = time.Now()
+	}
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return 0, fmt.Errorf("failed to start spending transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // rollback after commit is a no-op
+
+	query := `INSERT INTO spendings (user_id, payer_id, ledger_id, account_id, category_id, amount, description, timestamp, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	res, err := tx.Exec(query, info.UserID, info.PayerID, info.LedgerID, info.AccountID, info.CategoryID, info.Amount, info.Description, info.Timestamp, info.CreatedAt)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert spending record: %w", err)
+	}
+	if info.ID, err = res.LastInsertId(); err != nil {
+		return 0, fmt.Errorf("failed to get spending record id: %w", err)
+	}
+
+	for _, share := range info.Shares {
+		query = `INSERT INTO spending_shares (spending_id, user_id, amount) VALUES (?, ?, ?)`
+		if _, err = tx.Exec(query, info.ID, share.UserID, share.Amount); err != nil {
+			return 0, fmt.Errorf("failed to insert spending share: %w", err)
+		}
+	}
+
+	for _, tag := range info.Tags {
+		query = `INSERT OR IGNORE INTO spending_tags (spending_id, tag) VALUES (?, ?)`
+		if _, err = tx.Exec(query, info.ID, tag); err != nil {
+			return 0, fmt.Errorf("failed to insert spending tag: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit spending record: %w", err)
+	}
+
+	log.Printf("[info] New spending record added: %f for ledger_id: %d by user_id: %d, category_id: %d", info.Amount, info.LedgerID, info.UserID, info.CategoryID)
+	return info.ID, nil
+}
+
+// ListSpendings returns up to limit spendings matching the filter from the cursor on, the latest first.
+func (s *Spending) ListSpendings(filter SpendingFilter, cursor SpendingCursor, limit int) ([]SpendingRecord, error) {
+	where, args, err := filter.where()
+	if err != nil {
+		return nil, err
+	}
+
+	// keyset pagination over (timestamp, id), pages stay stable while new spendings are added
+	order := "DESC"
+	if cursor.ID != 0 {
+		op := "<"
+		if cursor.Backward {
+			op, order = ">", "ASC"
+		}
+		where += " AND (s.timestamp, s.id) " + op + " (SELECT timestamp, id FROM spendings WHERE id = ?)"
+		args = append(args, cursor.ID)
 	}
 
-	log.Printf("[info] New spending record added: %f for user_id: %d, category_id: %d", info.Amount, info.UserID, info.CategoryID)
+	var records []SpendingRecord
+	query := `SELECT s.*, COALESCE(c.name, '') AS category_name, COALESCE(c.emoji, '') AS category_emoji,
+		(SELECT COUNT(*) FROM receipts r WHERE r.spending_id = s.id) AS receipts
+		FROM spendings s LEFT JOIN categories c ON c.id = s.category_id
+		WHERE ` + where + ` ORDER BY s.timestamp ` + order + `, s.id ` + order + ` LIMIT ?`
+	if err = s.db.Select(&records, query, append(args, limit)...); err != nil {
+		return nil, fmt.Errorf("failed to list spending records for ledger_id: %d: %w", filter.LedgerID, err)
+	}
+
+	if cursor.Backward {
+		for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+			records[i], records[j] = records[j], records[i]
+		}
+	}
+	return records, nil
+}
+
+// GetSpending returns a spending by its ID.
+func (s *Spending) GetSpending(spendingID int64) (*SpendingInfo, error) {
+	var spending SpendingInfo
+	if err := s.db.Get(&spending, `SELECT * FROM spendings WHERE id = ?`, spendingID); err != nil {
+		return nil, fmt.Errorf("failed to get spending_id: %d: %w", spendingID, err)
+	}
+
+	return &spending, nil
+}
+
+// UpdateAmount changes the amount of a spending, scaling its shares to the new amount.
+func (s *Spending) UpdateAmount(spendingID int64, amount float64) error {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to start spending transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // rollback after commit is a no-op
+
+	var old float64
+	if err = tx.Get(&old, `SELECT amount FROM spendings WHERE id = ?`, spendingID); err != nil {
+		return fmt.Errorf("failed to get spending_id: %d: %w", spendingID, err)
+	}
+	if _, err = tx.Exec(`UPDATE spendings SET amount = ? WHERE id = ?`, amount, spendingID); err != nil {
+		return fmt.Errorf("failed to update spending_id: %d: %w", spendingID, err)
+	}
+
+	var shares []SpendingShareInfo
+	if err = tx.Select(&shares, `SELECT * FROM spending_shares WHERE spending_id = ? ORDER BY user_id ASC`, spendingID); err != nil {
+		return fmt.Errorf("failed to list shares of spending_id: %d: %w", spendingID, err)
+	}
+
+	// scale shares in cents, the rounding remainder goes to the first share so they still add up
+	cents := int64(math.Round(amount * 100))
+	scaled := make([]int64, len(shares))
+	remainder := cents
+	for i, share := range shares {
+		if old > 0 {
+			scaled[i] = int64(math.Round(share.Amount * amount / old * 100))
+		}
+		remainder -= scaled[i]
+	}
+	for i, share := range shares {
+		if i == 0 {
+			scaled[i] += remainder
+		}
+		query := `UPDATE spending_shares SET amount = ? WHERE spending_id = ? AND user_id = ?`
+		if _, err = tx.Exec(query, float64(scaled[i])/100, spendingID, share.UserID); err != nil {
+			return fmt.Errorf("failed to update spending share: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit spending update: %w", err)
+	}
+
+	log.Printf("[info] Spending_id: %d amount changed from %f to %f", spendingID, old, amount)
 	return nil
 }
 
-// ListSpendings retrieves spending records for a given user.
-func (s *Spending) ListSpendings(userID int64) ([]SpendingInfo, error) {
+// CategoryAmounts returns amounts of the latest spendings of a ledger category, up to limit,
+// leaving out the spending with excludeID.
+func (s *Spending) CategoryAmounts(ledgerID, categoryID, excludeID int64, limit int) ([]float64, error) {
+	var amounts []float64
+	query := `SELECT amount FROM spendings WHERE ledger_id = ? AND category_id = ? AND id != ? ORDER BY timestamp DESC, id DESC LIMIT ?`
+	if err := s.db.Select(&amounts, query, ledgerID, categoryID, excludeID, limit); err != nil {
+		return nil, fmt.Errorf("failed to list amounts of category_id: %d: %w", categoryID, err)
+	}
+
+	return amounts, nil
+}
+
+// ListDescribedSpendings returns the latest spendings of a ledger having a description, up to limit.
+func (s *Spending) ListDescribedSpendings(ledgerID int64, limit int) ([]SpendingInfo, error) {
+	var spendings []SpendingInfo
+	query := `SELECT * FROM spendings WHERE ledger_id = ? AND description != '' ORDER BY timestamp DESC LIMIT ?`
+	if err := s.db.Select(&spendings, query, ledgerID, limit); err != nil {
+		return nil, fmt.Errorf("failed to list described spendings for ledger_id: %d: %w", ledgerID, err)
+	}
+
+	return spendings, nil
+}
+
+// FindSpendings returns a page of spendings matching the filter, the latest first.
+func (s *Spending) FindSpendings(filter SpendingFilter, limit, offset int) ([]SpendingRecord, error) {
+	where, args, err := filter.where()
+	if err != nil {
+		return nil, err
+	}
+
+	var records []SpendingRecord
+	query := `SELECT s.*, COALESCE(c.name, '') AS category_name, COALESCE(c.emoji, '') AS category_emoji,
+		(SELECT COUNT(*) FROM receipts r WHERE r.spending_id = s.id) AS receipts
+		FROM spendings s LEFT JOIN categories c ON c.id = s.category_id
+		WHERE ` + where + ` ORDER BY s.timestamp DESC, s.id DESC LIMIT ? OFFSET ?`
+	if err = s.db.Select(&records, query, append(args, limit, offset)...); err != nil {
+		return nil, fmt.Errorf("failed to find spendings for ledger_id: %d: %w", filter.LedgerID, err)
+	}
+
+	return records, nil
+}
+
+// SummarizeSpendings counts and sums all spendings matching the filter.
+func (s *Spending) SummarizeSpendings(filter SpendingFilter) (SpendingSummary, error) {
+	where, args, err := filter.where()
+	if err != nil {
+		return SpendingSummary{}, err
+	}
+
+	var summary SpendingSummary
+	query := `SELECT COUNT(*) AS count, COALESCE(SUM(s.amount), 0) AS total
+		FROM spendings s LEFT JOIN categories c ON c.id = s.category_id WHERE ` + where
+	if err = s.db.Get(&summary, query, args...); err != nil {
+		return SpendingSummary{}, fmt.Errorf("failed to summarize spendings for ledger_id: %d: %w", filter.LedgerID, err)
+	}
+
+	return summary, nil
+}
+
+// SummarizeByCategory sums spendings matching the filter per category, the largest first.
+func (s *Spending) SummarizeByCategory(filter SpendingFilter) ([]CategoryTotal, error) {
+	where, args, err := filter.where()
+	if err != nil {
+		return nil, err
+	}
+
+	var totals []CategoryTotal
+	query := `SELECT s.category_id, COALESCE(c.name, '') AS name, COALESCE(c.emoji, '') AS emoji,
+			SUM(s.amount) AS total, COUNT(*) AS count
+		FROM spendings s LEFT JOIN categories c ON c.id = s.category_id
+		WHERE ` + where + ` GROUP BY s.category_id ORDER BY total DESC`
+	if err = s.db.Select(&totals, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to sum spendings by category for ledger_id: %d: %w", filter.LedgerID, err)
+	}
+
+	return totals, nil
+}
+
+// SummarizeByTag sums spendings matching the filter per tag, the largest first.
+// Spendings with several tags count towards each of them.
+func (s *Spending) SummarizeByTag(filter SpendingFilter) ([]TagTotal, error) {
+	where, args, err := filter.where()
+	if err != nil {
+		return nil, err
+	}
+
+	var totals []TagTotal
+	query := `SELECT st.tag, SUM(s.amount) AS total, COUNT(*) AS count
+		FROM spendings s JOIN spending_tags st ON st.spending_id = s.id LEFT JOIN categories c ON c.id = s.category_id
+		WHERE ` + where + ` GROUP BY st.tag ORDER BY total DESC`
+	if err = s.db.Select(&totals, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to sum spendings by tag for ledger_id: %d: %w", filter.LedgerID, err)
+	}
+
+	return totals, nil
+}
+
+// where builds the SQL condition of the filter over spendings aliased s joined with categories aliased c.
+func (f SpendingFilter) where() (string, []interface{}, error) {
+	conditions := []string{"s.ledger_id = ?"}
+	args := []interface{}{f.LedgerID}
+
+	if f.UserID != 0 {
+		conditions = append(conditions, "s.user_id = ?")
+		args = append(args, f.UserID)
+	}
+
+	for _, term := range f.Terms {
+		conditions = append(conditions, "(s.description LIKE ? OR c.name LIKE ?)")
+		pattern := "%" + term + "%"
+		args = append(args, pattern, pattern)
+	}
+
+	for _, tag := range f.Tags {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM spending_tags t WHERE t.spending_id = s.id AND t.tag = ?)")
+		args = append(args, tag)
+	}
+
+	if len(f.CategoryIDs) > 0 {
+		conditions = append(conditions, "s.category_id IN (?"+strings.Repeat(", ?", len(f.CategoryIDs)-1)+")")
+		for _, id := range f.CategoryIDs {
+			args = append(args, id)
+		}
+	}
+
+	for _, amount := range f.Amounts {
+		switch amount.Op {
+		case "=", "<", "<=", ">", ">=":
+			conditions = append(conditions, "s.amount "+amount.Op+" ?")
+			args = append(args, amount.Value)
+		default:
+			return "", nil, fmt.Errorf("unsupported amount operator %q", amount.Op)
+		}
+	}
+
+	if !f.From.IsZero() {
+		conditions = append(conditions, "s.timestamp >= ?")
+		args = append(args, f.From)
+	}
+	if !f.To.IsZero() {
+		conditions = append(conditions, "s.timestamp < ?")
+		args = append(args, f.To)
+	}
+
+	return strings.Join(conditions, " AND "), args, nil
+}
+
+// TotalsByCategory sums spendings of a ledger per category within [from, to).
+// A non-zero userID limits the totals to spendings recorded by that member.
+func (s *Spending) TotalsByCategory(ledgerID, userID int64, from, to time.Time) ([]CategoryTotal, error) {
+	var totals []CategoryTotal
+	query := `SELECT s.category_id, COALESCE(c.name, '') AS name, COALESCE(c.emoji, '') AS emoji,
+			SUM(s.amount) AS total, COUNT(*) AS count
+		FROM spendings s LEFT JOIN categories c ON c.id = s.category_id
+		WHERE s.ledger_id = ? AND (? = 0 OR s.user_id = ?) AND s.timestamp >= ? AND s.timestamp < ?
+		GROUP BY s.category_id ORDER BY total DESC`
+	if err := s.db.Select(&totals, query, ledgerID, userID, userID, from, to); err != nil {
+		return nil, fmt.Errorf("failed to sum spendings for ledger_id: %d: %w", ledgerID, err)
+	}
+
+	return totals, nil
+}
+
+// TotalsByCategoryDay sums spendings of a ledger per category and day within [from, to),
+// days without spendings in a category are omitted.
+func (s *Spending) TotalsByCategoryDay(ledgerID int64, from, to time.Time) ([]CategoryDayTotal, error) {
+	var totals []CategoryDayTotal
+	query := `SELECT category_id, substr(timestamp, 1, 10) AS day, SUM(amount) AS total FROM spendings
+		WHERE ledger_id = ? AND timestamp >= ? AND timestamp < ?
+		GROUP BY category_id, day ORDER BY day ASC`
+	if err := s.db.Select(&totals, query, ledgerID, from, to); err != nil {
+		return nil, fmt.Errorf("failed to sum spendings by category and day for ledger_id: %d: %w", ledgerID, err)
+	}
+
+	return totals, nil
+}
+
+// LargestSpendings returns the largest spendings of a ledger within [from, to), up to limit.
+func (s *Spending) LargestSpendings(ledgerID int64, from, to time.Time, limit int) ([]SpendingInfo, error) {
 	var spendings []SpendingInfo
-	query := "SELECT * FROM spendings WHERE user_id = ? ORDER BY timestamp DESC"
-	if err := s.db.Select(&spendings, query, userID); err != nil {
-		return nil, fmt.Errorf("failed to list spending records for user_id: %d: %w", userID, err)
+	query := `SELECT * FROM spendings WHERE ledger_id = ? AND timestamp >= ? AND timestamp < ? ORDER BY amount DESC LIMIT ?`
+	if err := s.db.Select(&spendings, query, ledgerID, from, to, limit); err != nil {
+		return nil, fmt.Errorf("failed to list largest spendings for ledger_id: %d: %w", ledgerID, err)
 	}
 
 	return spendings, nil
 }
+
+// CountUserSpendings returns how many spendings the user recorded within [from, to) in any ledger,
+// by when they were recorded rather than their possibly backdated date.
+func (s *Spending) CountUserSpendings(userID int64, from, to time.Time) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM spendings WHERE user_id = ? AND created_at >= ? AND created_at < ?`
+	if err := s.db.Get(&count, query, userID, from, to); err != nil {
+		return 0, fmt.Errorf("failed to count spendings of user_id: %d: %w", userID, err)
+	}
+
+	return count, nil
+}
+
+// TotalsByDay sums spendings of a ledger per day within [from, to), days without spendings are omitted.
+// A non-zero userID limits the totals to spendings recorded by that member.
+func (s *Spending) TotalsByDay(ledgerID, userID int64, from, to time.Time) ([]PeriodTotal, error) {
+	return s.totalsByPeriod(len("2006-01-02"), ledgerID, userID, from, to)
+}
+
+// TotalsByMonth sums spendings of a ledger per month within [from, to), months without spendings are omitted.
+// A non-zero userID limits the totals to spendings recorded by that member.
+func (s *Spending) TotalsByMonth(ledgerID, userID int64, from, to time.Time) ([]PeriodTotal, error) {
+	return s.totalsByPeriod(len("2006-01"), ledgerID, userID, from, to)
+}
+
+// totalsByPeriod groups spendings by the leading part of their timestamp of the given length.
+func (s *Spending) totalsByPeriod(length int, ledgerID, userID int64, from, to time.Time) ([]PeriodTotal, error) {
+	var totals []PeriodTotal
+	query := `SELECT substr(timestamp, 1, ?) AS period, SUM(amount) AS total FROM spendings
+		WHERE ledger_id = ? AND (? = 0 OR user_id = ?) AND timestamp >= ? AND timestamp < ?
+		GROUP BY period ORDER BY period ASC`
+	if err := s.db.Select(&totals, query, length, ledgerID, userID, userID, from, to); err != nil {
+		return nil, fmt.Errorf("failed to sum spendings by period for ledger_id: %d: %w", ledgerID, err)
+	}
+
+	return totals, nil
+}
+
+// SplitBalances returns the net position of every member of a ledger from split spendings:
+// what they paid for split spendings minus their own shares. Positive balances are owed to the member.
+func (s *Spending) SplitBalances(ledgerID int64) (map[int64]float64, error) {
+	var rows []struct {
+		UserID int64   `db:"user_id"`
+		Amount float64 `db:"amount"`
+	}
+	query := `SELECT payer_id AS user_id, SUM(amount) AS amount FROM spendings
+			WHERE ledger_id = ? AND id IN (SELECT spending_id FROM spending_shares) GROUP BY payer_id
+		UNION ALL
+		SELECT sh.user_id, -SUM(sh.amount) FROM spending_shares sh JOIN spendings s ON s.id = sh.spending_id
+			WHERE s.ledger_id = ? GROUP BY sh.user_id`
+	if err := s.db.Select(&rows, query, ledgerID, ledgerID); err != nil {
+		return nil, fmt.Errorf("failed to compute split balances for ledger_id: %d: %w", ledgerID, err)
+	}
+
+	balances := make(map[int64]float64)
+	for _, row := range rows {
+		balances[row.UserID] += row.Amount
+	}
+	return balances, nil
+}
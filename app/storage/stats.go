@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Stats represents usage statistics across the tables of the other storages, along with when users were last seen.
+type Stats struct {
+	db *sqlx.DB
+}
+
+// UsageInfo is how much the bot is used, as shown to admins.
+type UsageInfo struct {
+	Users        int   `db:"users"`         // Users who ever talked to the bot
+	ActiveDay    int   `db:"active_day"`    // Users seen in the last day
+	ActiveWeek   int   `db:"active_week"`   // Users seen in the last 7 days
+	ActiveMonth  int   `db:"active_month"`  // Users seen in the last 30 days
+	Ledgers      int   `db:"ledgers"`       // Personal and shared ledgers
+	Categories   int   `db:"categories"`    // Categories of all ledgers
+	Spendings    int   `db:"spendings"`     // Spendings of all ledgers
+	DatabaseSize int64 `db:"database_size"` // Size of the database file in bytes
+}
+
+// NewStats creates a new Stats storage, the other tables it reads are created by the other storages.
+func NewStats(db *sqlx.DB) (*Stats, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS user_activity (
+		user_id INTEGER PRIMARY KEY,
+		last_seen DATETIME NOT NULL
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user_activity table: %w", err)
+	}
+
+	if _, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_user_activity_last_seen ON user_activity(last_seen)`); err != nil {
+		return nil, fmt.Errorf("failed to create index on last_seen: %w", err)
+	}
+
+	return &Stats{db: db}, nil
+}
+
+// Seen records the time the user last used the bot, reading or recording alike.
+func (s *Stats) Seen(userID int64, at time.Time) error {
+	query := `INSERT INTO user_activity (user_id, last_seen) VALUES (?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET last_seen = excluded.last_seen`
+	if _, err := s.db.Exec(query, userID, at); err != nil {
+		return fmt.Errorf("failed to record activity of user_id: %d: %w", userID, err)
+	}
+
+	return nil
+}
+
+// Usage counts users, active users and records as of now.
+func (s *Stats) Usage(now time.Time) (UsageInfo, error) {
+	var usage UsageInfo
+	query := `SELECT
+			(SELECT COUNT(*) FROM user_settings) AS users,
+			(SELECT COUNT(*) FROM user_activity WHERE last_seen >= ?) AS active_day,
+			(SELECT COUNT(*) FROM user_activity WHERE last_seen >= ?) AS active_week,
+			(SELECT COUNT(*) FROM user_activity WHERE last_seen >= ?) AS active_month,
+			(SELECT COUNT(*) FROM ledgers) AS ledgers,
+			(SELECT COUNT(*) FROM categories) AS categories,
+			(SELECT COUNT(*) FROM spendings) AS spendings,
+			(SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size()) AS database_size`
+	err := s.db.Get(&usage, query, now.AddDate(0, 0, -1), now.AddDate(0, 0, -7), now.AddDate(0, 0, -30))
+	if err != nil {
+		return usage, fmt.Errorf("failed to collect usage statistics: %w", err)
+	}
+
+	return usage, nil
+}
+
+// ListUserIDs returns every user who ever talked to the bot, except users whose access was revoked.
+func (s *Stats) ListUserIDs() ([]int64, error) {
+	var userIDs []int64
+	query := `SELECT user_id FROM user_settings
+		WHERE user_id NOT IN (SELECT user_id FROM user_access WHERE status = ?)
+		ORDER BY user_id ASC`
+	if err := s.db.Select(&userIDs, query, AccessRevoked); err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	return userIDs, nil
+}
@@ -1,6 +1,9 @@
 package storage
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/jmoiron/sqlx"
 	_ "modernc.org/sqlite" // sqlite driver loaded here
 )
@@ -9,3 +12,56 @@ import (
 func NewSqliteDB(file string) (*sqlx.DB, error) {
 	return sqlx.Connect("sqlite", file)
 }
+
+// addColumnIfMissing adds a column to an existing table created by an older version of the bot
+func addColumnIfMissing(db *sqlx.DB, table, column, definition string) error {
+	var count int
+	query := `SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`
+	if err := db.Get(&count, query, table, column); err != nil {
+		return fmt.Errorf("failed to inspect %s table: %w", table, err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("failed to add %s column to %s table: %w", column, table, err)
+	}
+	return nil
+}
+
+// rebuildLegacyTable recreates a table whose stored definition still contains legacyFragment,
+// copying the given columns into the table created by createQuery. SQLite can't drop constraints
+// in place, so the table is copied following the documented create-copy-drop-rename procedure,
+// which keeps references from other tables intact.
+func rebuildLegacyTable(db *sqlx.DB, table, legacyFragment, createQuery string, columns []string) error {
+	var definition string
+	if err := db.Get(&definition, `SELECT COALESCE(MAX(sql), '') FROM sqlite_master WHERE type = 'table' AND name = ?`, table); err != nil {
+		return fmt.Errorf("failed to inspect %s table: %w", table, err)
+	}
+	// tables created from schema.sql are indented differently, so compare with collapsed whitespace
+	if !strings.Contains(strings.Join(strings.Fields(definition), " "), legacyFragment) {
+		return nil
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to start %s migration: %w", table, err)
+	}
+	defer tx.Rollback() //nolint:errcheck // rollback after commit is a no-op
+
+	cols := strings.Join(columns, ", ")
+	queries := []string{
+		strings.Replace(createQuery, "IF NOT EXISTS "+table, table+"_new", 1),
+		fmt.Sprintf("INSERT INTO %s_new (%s) SELECT %s FROM %s", table, cols, cols, table),
+		fmt.Sprintf("DROP TABLE %s", table),
+		fmt.Sprintf("ALTER TABLE %s_new RENAME TO %s", table, table),
+	}
+	for _, query := range queries {
+		if _, err = tx.Exec(query); err != nil {
+			return fmt.Errorf("failed to migrate %s table: %w", table, err)
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// UserSettings represents per-user preferences storage.
+type UserSettings struct {
+	db *sqlx.DB
+}
+
+// UserSettingsInfo represents the structure of a user's preferences.
+type UserSettingsInfo struct {
+	UserID    int64     `db:"user_id"`
+	Language  string    `db:"language"`
+	LedgerID  int64     `db:"ledger_id"` // Active ledger new records go to
+	Timestamp time.Time `db:"timestamp"`
+
+	WeeklyDigest  bool `db:"weekly_digest"`
+	MonthlyDigest bool `db:"monthly_digest"`
+
+	Timezone     string `db:"timezone"`      // IANA name or UTC offset, server time zone if empty
+	ReminderTime string `db:"reminder_time"` // Daily logging reminder in 15:04 format, off if empty
+	QuietStart   string `db:"quiet_start"`   // Quiet hours in 15:04 format, none if empty
+	QuietEnd     string `db:"quiet_end"`
+}
+
+// NewUserSettings creates a new UserSettings storage
+func NewUserSettings(db *sqlx.DB) (*UserSettings, error) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS user_settings (
+		user_id INTEGER PRIMARY KEY,
+		language TEXT NOT NULL DEFAULT '',
+		ledger_id INTEGER NOT NULL DEFAULT 0,
+		timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+		weekly_digest INTEGER NOT NULL DEFAULT 0,
+		monthly_digest INTEGER NOT NULL DEFAULT 0,
+		timezone TEXT NOT NULL DEFAULT '',
+		reminder_time TEXT NOT NULL DEFAULT '',
+		quiet_start TEXT NOT NULL DEFAULT '',
+		quiet_end TEXT NOT NULL DEFAULT ''
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user_settings table: %w", err)
+	}
+
+	for _, column := range []string{"ledger_id", "weekly_digest", "monthly_digest"} {
+		if err = addColumnIfMissing(db, "user_settings", column, "INTEGER NOT NULL DEFAULT 0"); err != nil {
+			return nil, err
+		}
+	}
+	for _, column := range []string{"timezone", "reminder_time", "quiet_start", "quiet_end"} {
+		if err = addColumnIfMissing(db, "user_settings", column, "TEXT NOT NULL DEFAULT ''"); err != nil {
+			return nil, err
+		}
+	}
+
+	return &UserSettings{db: db}, nil
+}
+
+// Write adds or updates a user's settings entry
+func (us *UserSettings) Write(entry UserSettingsInfo) error {
+	query := `INSERT INTO user_settings (user_id, language, ledger_id, weekly_digest, monthly_digest, timezone, reminder_time, quiet_start, quiet_end)
+		VALUES (:user_id, :language, :ledger_id, :weekly_digest, :monthly_digest, :timezone, :reminder_time, :quiet_start, :quiet_end)
+		ON CONFLICT(user_id) DO UPDATE SET language = excluded.language, ledger_id = excluded.ledger_id,
+			weekly_digest = excluded.weekly_digest, monthly_digest = excluded.monthly_digest, timezone = excluded.timezone,
+			reminder_time = excluded.reminder_time, quiet_start = excluded.quiet_start, quiet_end = excluded.quiet_end,
+			timestamp = CURRENT_TIMESTAMP`
+	if _, err := us.db.NamedExec(query, entry); err != nil {
+		return fmt.Errorf("failed to insert or update user settings entry: %w", err)
+	}
+
+	log.Printf("[info] User settings updated for user_id: %d, language: %s, ledger_id: %d", entry.UserID, entry.Language, entry.LedgerID)
+	return nil
+}
+
+// Read returns the settings entry for a given user ID
+func (us *UserSettings) Read(userID int64) (*UserSettingsInfo, error) {
+	var entry UserSettingsInfo
+	if err := us.db.Get(&entry, "SELECT * FROM user_settings WHERE user_id = ?", userID); err != nil {
+		return nil, fmt.Errorf("failed to get user settings entry: %w", err)
+	}
+
+	return &entry, nil
+}
+
+// ListDigestSubscribers returns settings of users who opted in to any digest
+func (us *UserSettings) ListDigestSubscribers() ([]UserSettingsInfo, error) {
+	var entries []UserSettingsInfo
+	if err := us.db.Select(&entries, "SELECT * FROM user_settings WHERE weekly_digest != 0 OR monthly_digest != 0"); err != nil {
+		return nil, fmt.Errorf("failed to list digest subscribers: %w", err)
+	}
+
+	return entries, nil
+}
+
+// ListReminderSubscribers returns settings of users with a daily logging reminder
+func (us *UserSettings) ListReminderSubscribers() ([]UserSettingsInfo, error) {
+	var entries []UserSettingsInfo
+	if err := us.db.Select(&entries, "SELECT * FROM user_settings WHERE reminder_time != ''"); err != nil {
+		return nil, fmt.Errorf("failed to list reminder subscribers: %w", err)
+	}
+
+	return entries, nil
+}
@@ -16,6 +16,7 @@ type UserState struct {
 // UserStateInfo represents the structure of a user's state information.
 type UserStateInfo struct {
 	ID        int64                  `db:"id"`
+	ChatID    int64                  `db:"chat_id"` // Same as UserID in private chats
 	UserID    int64                  `db:"user_id"`
 	State     string                 `db:"state"`
 	DataJSON  string                 `db:"data"` // Store as JSON
@@ -23,22 +24,29 @@ type UserStateInfo struct {
 	Timestamp time.Time              `db:"timestamp"`
 }
 
-// NewUserState creates a new UserState storage
-func NewUserState(db *sqlx.DB) (*UserState, error) {
-	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS user_states (
+const userStatesTable = `CREATE TABLE IF NOT EXISTS user_states (
 		id INTEGER PRIMARY KEY,
-		user_id INTEGER UNIQUE,
+		chat_id INTEGER NOT NULL DEFAULT 0,
+		user_id INTEGER,
 		state TEXT,
 		data TEXT,
-		timestamp DATETIME DEFAULT CURRENT_TIMESTAMP
-	)`)
-	if err != nil {
+		timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(chat_id, user_id)
+	)`
+
+// NewUserState creates a new UserState storage
+func NewUserState(db *sqlx.DB) (*UserState, error) {
+	if _, err := db.Exec(userStatesTable); err != nil {
 		return nil, fmt.Errorf("failed to create user_states table: %w", err)
 	}
 
-	// Add index on user_id for faster lookup
-	if _, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_user_states_user_id ON user_states(user_id)`); err != nil {
-		return nil, fmt.Errorf("failed to create index on user_id: %w", err)
+	// older versions kept a single state per user, states are per chat now
+	legacyColumns := []string{"id", "user_id", "state", "data", "timestamp"}
+	if err := rebuildLegacyTable(db, "user_states", "user_id INTEGER UNIQUE", userStatesTable, legacyColumns); err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(`UPDATE user_states SET chat_id = user_id WHERE chat_id = 0`); err != nil {
+		return nil, fmt.Errorf("failed to migrate user_states chat_id: %w", err)
 	}
 
 	return &UserState{db: db}, nil
@@ -46,19 +54,20 @@ func NewUserState(db *sqlx.DB) (*UserState, error) {
 
 // Write adds or updates a user's state entry
 func (us *UserState) Write(entry UserStateInfo) error {
-	query := `INSERT INTO user_states (user_id, state, data) VALUES (?, ?, ?) ON CONFLICT(user_id) DO UPDATE SET state = excluded.state, data = excluded.data`
-	if _, err := us.db.Exec(query, entry.UserID, entry.State, entry.DataJSON); err != nil {
+	query := `INSERT INTO user_states (chat_id, user_id, state, data) VALUES (?, ?, ?, ?)
+		ON CONFLICT(chat_id, user_id) DO UPDATE SET state = excluded.state, data = excluded.data`
+	if _, err := us.db.Exec(query, entry.ChatID, entry.UserID, entry.State, entry.DataJSON); err != nil {
 		return fmt.Errorf("failed to insert or update user state entry: %w", err)
 	}
 
-	log.Printf("[info] User state updated for user_id: %d, state: %s", entry.UserID, entry.State)
+	log.Printf("[info] User state updated for user_id: %d, chat_id: %d, state: %s", entry.UserID, entry.ChatID, entry.State)
 	return nil
 }
 
-// Read returns the latest state entry for a given user ID
-func (us *UserState) Read(userID int64) (*UserStateInfo, error) {
+// Read returns the latest state entry for a given user in a chat
+func (us *UserState) Read(chatID, userID int64) (*UserStateInfo, error) {
 	var entry UserStateInfo
-	err := us.db.Get(&entry, "SELECT * FROM user_states WHERE user_id = ? ORDER BY timestamp DESC LIMIT 1", userID)
+	err := us.db.Get(&entry, "SELECT * FROM user_states WHERE chat_id = ? AND user_id = ? ORDER BY timestamp DESC LIMIT 1", chatID, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user state entry: %w", err)
 	}
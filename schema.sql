@@ -1,29 +1,283 @@
 CREATE TABLE IF NOT EXISTS user_states
 (
     id        INTEGER PRIMARY KEY,
-    user_id   INTEGER UNIQUE,
+    chat_id   INTEGER NOT NULL DEFAULT 0,
+    user_id   INTEGER,
     state     TEXT,
     data      TEXT,
-    timestamp DATETIME DEFAULT CURRENT_TIMESTAMP
+    timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (chat_id, user_id)
 );
 
 CREATE TABLE IF NOT EXISTS spendings
 (
     id          INTEGER PRIMARY KEY,
-    user_id     INTEGER UNIQUE,
+    user_id     INTEGER,
+    payer_id    INTEGER NOT NULL DEFAULT 0,
+    ledger_id   INTEGER NOT NULL DEFAULT 0,
+    account_id  INTEGER NOT NULL DEFAULT 0,
     category_id INTEGER,
     amount      REAL NOT NULL,
     description TEXT,
     timestamp   DATETIME DEFAULT CURRENT_TIMESTAMP,
-    FOREIGN KEY (user_id) REFERENCES user_states (user_id),
+    created_at  DATETIME,
+    FOREIGN KEY (ledger_id) REFERENCES ledgers (id),
     FOREIGN KEY (category_id) REFERENCES categories (id)
 );
 
+CREATE INDEX IF NOT EXISTS idx_spendings_ledger_id_timestamp ON spendings (ledger_id, timestamp);
+
+CREATE TABLE IF NOT EXISTS spending_shares
+(
+    spending_id INTEGER NOT NULL,
+    user_id     INTEGER NOT NULL,
+    amount      REAL    NOT NULL,
+    PRIMARY KEY (spending_id, user_id),
+    FOREIGN KEY (spending_id) REFERENCES spendings (id)
+);
+
+CREATE TABLE IF NOT EXISTS spending_tags
+(
+    spending_id INTEGER NOT NULL,
+    tag         TEXT    NOT NULL,
+    PRIMARY KEY (spending_id, tag),
+    FOREIGN KEY (spending_id) REFERENCES spendings (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_spending_tags_tag ON spending_tags (tag);
+
 CREATE TABLE IF NOT EXISTS categories
 (
-    id      INTEGER PRIMARY KEY,
-    user_id INTEGER UNIQUE,
-    name    TEXT,
-    emoji   TEXT,
-    UNIQUE (user_id, name) ON CONFLICT REPLACE
-);
\ No newline at end of file
+    id        INTEGER PRIMARY KEY,
+    user_id   INTEGER,
+    ledger_id INTEGER NOT NULL DEFAULT 0,
+    name      TEXT,
+    emoji     TEXT,
+    parent_id INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_categories_ledger_id ON categories (ledger_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_categories_ledger_name ON categories (ledger_id, name) WHERE ledger_id != 0;
+
+CREATE TABLE IF NOT EXISTS category_favorites
+(
+    user_id     INTEGER NOT NULL,
+    category_id INTEGER NOT NULL,
+    PRIMARY KEY (user_id, category_id),
+    FOREIGN KEY (category_id) REFERENCES categories (id)
+);
+
+CREATE TABLE IF NOT EXISTS user_settings
+(
+    user_id        INTEGER PRIMARY KEY,
+    language       TEXT    NOT NULL DEFAULT '',
+    ledger_id      INTEGER NOT NULL DEFAULT 0,
+    timestamp      DATETIME DEFAULT CURRENT_TIMESTAMP,
+    weekly_digest  INTEGER NOT NULL DEFAULT 0,
+    monthly_digest INTEGER NOT NULL DEFAULT 0,
+    timezone       TEXT    NOT NULL DEFAULT '',
+    reminder_time  TEXT    NOT NULL DEFAULT '',
+    quiet_start    TEXT    NOT NULL DEFAULT '',
+    quiet_end      TEXT    NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS ledgers
+(
+    id        INTEGER PRIMARY KEY,
+    name      TEXT    NOT NULL,
+    owner_id  INTEGER NOT NULL,
+    personal  INTEGER NOT NULL DEFAULT 0,
+    chat_id   INTEGER NOT NULL DEFAULT 0,
+    timestamp DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledgers_chat_id ON ledgers (chat_id);
+
+CREATE TABLE IF NOT EXISTS ledger_members
+(
+    ledger_id INTEGER NOT NULL,
+    user_id   INTEGER NOT NULL,
+    role      TEXT    NOT NULL,
+    name      TEXT    NOT NULL DEFAULT '',
+    timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ledger_id, user_id),
+    FOREIGN KEY (ledger_id) REFERENCES ledgers (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_members_user_id ON ledger_members (user_id);
+
+CREATE TABLE IF NOT EXISTS ledger_invites
+(
+    code       TEXT PRIMARY KEY,
+    ledger_id  INTEGER NOT NULL,
+    role       TEXT    NOT NULL,
+    created_by INTEGER NOT NULL,
+    used_by    INTEGER NOT NULL DEFAULT 0,
+    expires_at DATETIME NOT NULL,
+    timestamp  DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (ledger_id) REFERENCES ledgers (id)
+);
+
+CREATE TABLE IF NOT EXISTS settlements
+(
+    id           INTEGER PRIMARY KEY,
+    ledger_id    INTEGER NOT NULL,
+    from_user_id INTEGER NOT NULL,
+    to_user_id   INTEGER NOT NULL,
+    amount       REAL    NOT NULL,
+    created_by   INTEGER NOT NULL,
+    timestamp    DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (ledger_id) REFERENCES ledgers (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_settlements_ledger_id ON settlements (ledger_id);
+
+CREATE TABLE IF NOT EXISTS budgets
+(
+    ledger_id   INTEGER NOT NULL,
+    category_id INTEGER NOT NULL DEFAULT 0,
+    amount      REAL    NOT NULL,
+    rollover    INTEGER NOT NULL DEFAULT 0,
+    timestamp   DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (ledger_id, category_id),
+    FOREIGN KEY (ledger_id) REFERENCES ledgers (id)
+);
+
+CREATE TABLE IF NOT EXISTS budget_envelopes
+(
+    ledger_id   INTEGER NOT NULL,
+    category_id INTEGER NOT NULL DEFAULT 0,
+    month       TEXT    NOT NULL,
+    allocated   REAL    NOT NULL,
+    carried     REAL    NOT NULL DEFAULT 0,
+    moved       REAL    NOT NULL DEFAULT 0,
+    PRIMARY KEY (ledger_id, category_id, month),
+    FOREIGN KEY (ledger_id) REFERENCES ledgers (id)
+);
+
+CREATE TABLE IF NOT EXISTS digests
+(
+    user_id INTEGER NOT NULL,
+    kind    TEXT    NOT NULL,
+    sent_at DATETIME NOT NULL,
+    PRIMARY KEY (user_id, kind)
+);
+
+CREATE TABLE IF NOT EXISTS reminders
+(
+    user_id       INTEGER PRIMARY KEY,
+    sent_at       DATETIME NOT NULL,
+    snoozed_until DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS category_rules
+(
+    id          INTEGER PRIMARY KEY,
+    ledger_id   INTEGER NOT NULL,
+    keyword     TEXT    NOT NULL,
+    category_id INTEGER NOT NULL,
+    created_by  INTEGER NOT NULL,
+    timestamp   DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (ledger_id, keyword),
+    FOREIGN KEY (ledger_id) REFERENCES ledgers (id),
+    FOREIGN KEY (category_id) REFERENCES categories (id)
+);
+
+CREATE TABLE IF NOT EXISTS receipts
+(
+    id          INTEGER PRIMARY KEY,
+    spending_id INTEGER NOT NULL,
+    kind        TEXT    NOT NULL,
+    file_id     TEXT    NOT NULL,
+    local_path  TEXT    NOT NULL DEFAULT '',
+    timestamp   DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (spending_id) REFERENCES spendings (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_receipts_spending_id ON receipts (spending_id);
+
+CREATE TABLE IF NOT EXISTS spending_messages
+(
+    chat_id     INTEGER NOT NULL,
+    message_id  INTEGER NOT NULL,
+    spending_id INTEGER NOT NULL,
+    PRIMARY KEY (chat_id, message_id),
+    FOREIGN KEY (spending_id) REFERENCES spendings (id)
+);
+
+CREATE TABLE IF NOT EXISTS goals
+(
+    id         INTEGER PRIMARY KEY,
+    ledger_id  INTEGER NOT NULL,
+    name       TEXT    NOT NULL,
+    target     REAL    NOT NULL,
+    deadline   DATETIME NOT NULL,
+    created_by INTEGER NOT NULL,
+    closed     INTEGER NOT NULL DEFAULT 0,
+    timestamp  DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (ledger_id) REFERENCES ledgers (id)
+);
+
+CREATE TABLE IF NOT EXISTS goal_contributions
+(
+    id        INTEGER PRIMARY KEY,
+    goal_id   INTEGER NOT NULL,
+    user_id   INTEGER NOT NULL,
+    amount    REAL    NOT NULL,
+    timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (goal_id) REFERENCES goals (id)
+);
+
+CREATE TABLE IF NOT EXISTS accounts
+(
+    id              INTEGER PRIMARY KEY,
+    ledger_id       INTEGER NOT NULL,
+    name            TEXT    NOT NULL,
+    kind            TEXT    NOT NULL,
+    opening_balance REAL    NOT NULL DEFAULT 0,
+    created_by      INTEGER NOT NULL,
+    timestamp       DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (ledger_id, name),
+    FOREIGN KEY (ledger_id) REFERENCES ledgers (id)
+);
+
+CREATE TABLE IF NOT EXISTS account_transfers
+(
+    id              INTEGER PRIMARY KEY,
+    ledger_id       INTEGER NOT NULL,
+    from_account_id INTEGER NOT NULL,
+    to_account_id   INTEGER NOT NULL,
+    amount          REAL    NOT NULL,
+    user_id         INTEGER NOT NULL,
+    timestamp       DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (ledger_id) REFERENCES ledgers (id),
+    FOREIGN KEY (from_account_id) REFERENCES accounts (id),
+    FOREIGN KEY (to_account_id) REFERENCES accounts (id)
+);
+
+CREATE TABLE IF NOT EXISTS user_access
+(
+    user_id   INTEGER PRIMARY KEY,
+    username  TEXT NOT NULL DEFAULT '',
+    name      TEXT NOT NULL DEFAULT '',
+    language  TEXT NOT NULL DEFAULT '',
+    status    TEXT NOT NULL,
+    timestamp DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS access_invites
+(
+    code       TEXT PRIMARY KEY,
+    created_by INTEGER  NOT NULL,
+    used_by    INTEGER  NOT NULL DEFAULT 0,
+    expires_at DATETIME NOT NULL,
+    timestamp  DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS user_activity
+(
+    user_id   INTEGER PRIMARY KEY,
+    last_seen DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_activity_last_seen ON user_activity (last_seen);